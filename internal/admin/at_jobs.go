@@ -0,0 +1,81 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type atRequest struct {
+	// RunAt is a RFC3339 timestamp, e.g. 2026-11-01T09:00:00+07:00
+	RunAt   string            `json:"run_at"`
+	Handler string            `json:"handler"`
+	Params  map[string]string `json:"params"`
+}
+
+// GET /at-jobs
+// POST /at-jobs
+func (s *Server) atJobs(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		jobs, err := s.cron.AtJobs(r.Context())
+		if err != nil {
+			writeError(w, statusOf(err), err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, jobs)
+		return
+	}
+
+	var req atRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	runAt, err := time.Parse(time.RFC3339, req.RunAt)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid run_at: %w", err))
+		return
+	}
+
+	aj, err := s.cron.At(r.Context(), runAt, req.Handler, req.Params)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, aj)
+}
+
+// GET /at-jobs/{id}
+// DELETE /at-jobs/{id}
+func (s *Server) atJob(w http.ResponseWriter, r *http.Request) {
+	parts := splitPath(r.URL.Path, "/at-jobs/")
+	if len(parts) != 1 {
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	if !allowMethod(w, r, http.MethodGet, http.MethodDelete) {
+		return
+	}
+
+	get := s.cron.AtJob
+	if r.Method == http.MethodDelete {
+		get = s.cron.CancelAt
+	}
+
+	aj, err := get(r.Context(), parts[0])
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, aj)
+}
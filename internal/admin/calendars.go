@@ -0,0 +1,85 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	cron_jobs "github.com/vldcreation/sample-cron-go/internal/cron-jobs"
+)
+
+type calendarResponse struct {
+	Name     string              `json:"name"`
+	Holidays []cron_jobs.Holiday `json:"holidays"`
+}
+
+type businessDayResponse struct {
+	Name            string    `json:"name"`
+	At              time.Time `json:"at"`
+	BusinessDay     bool      `json:"business_day"`
+	NextBusinessDay time.Time `json:"next_business_day"`
+}
+
+// GET /calendars
+func (s *Server) calendars(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	resp := make([]calendarResponse, 0)
+	for _, name := range s.cron.Calendars() {
+		cal, err := s.cron.Calendar(name)
+		if err != nil {
+			continue
+		}
+		resp = append(resp, calendarResponse{Name: name, Holidays: cal.Holidays()})
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GET /calendars/{name}?at=2023-06-19T09:00:00+07:00
+// POST /calendars/reload
+func (s *Server) calendar(w http.ResponseWriter, r *http.Request) {
+	parts := splitPath(r.URL.Path, "/calendars/")
+	if len(parts) != 1 {
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	if r.Method == http.MethodPost && parts[0] == "reload" {
+		if err := s.cron.ReloadCalendars(); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{"calendars": s.cron.Calendars()})
+		return
+	}
+
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	cal, err := s.cron.Calendar(parts[0])
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+
+	at := time.Now()
+	if v := r.URL.Query().Get("at"); v != "" {
+		if at, err = time.Parse(time.RFC3339, v); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid at: %w", err))
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, businessDayResponse{
+		Name:            cal.Name(),
+		At:              at,
+		BusinessDay:     cal.IsBusinessDay(at),
+		NextBusinessDay: cal.NextBusinessDay(at),
+	})
+}
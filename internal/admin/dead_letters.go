@@ -0,0 +1,43 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+)
+
+// GET /dead-letters/{run_id}
+// POST /dead-letters/{run_id}/replay
+func (s *Server) deadLetter(w http.ResponseWriter, r *http.Request) {
+	parts := splitPath(r.URL.Path, "/dead-letters/")
+	if len(parts) == 0 || len(parts) > 2 || (len(parts) == 2 && parts[1] != "replay") {
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	if len(parts) == 1 {
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+
+		dl, err := s.cron.DeadLetter(r.Context(), parts[0])
+		if err != nil {
+			writeError(w, statusOf(err), err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, dl)
+		return
+	}
+
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	runID, err := s.cron.Replay(r.Context(), parts[0])
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"replay_of": parts[0], "run_id": runID})
+}
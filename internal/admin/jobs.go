@@ -0,0 +1,198 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	cron_jobs "github.com/vldcreation/sample-cron-go/internal/cron-jobs"
+)
+
+const (
+	// DefaultNextRuns is how many upcoming runs are previewed by GET /jobs/{name}
+	DefaultNextRuns = 5
+	// MaxNextRuns is the most upcoming runs GET /jobs/{name} can preview
+	MaxNextRuns = 100
+)
+
+type jobResponse struct {
+	cron_jobs.JobInfo
+	NextRuns []time.Time `json:"next_runs,omitempty"`
+}
+
+type triggerRequest struct {
+	Params map[string]string `json:"params"`
+}
+
+type rescheduleRequest struct {
+	Schedule string `json:"schedule"`
+	Timezone string `json:"timezone"`
+}
+
+// GET /jobs
+func (s *Server) jobs(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.cron.List())
+}
+
+// GET /jobs/{name}?next=5
+// POST /jobs/{name}/trigger|pause|resume|stop|reschedule
+func (s *Server) job(w http.ResponseWriter, r *http.Request) {
+	parts := splitPath(r.URL.Path, "/jobs/")
+	if len(parts) == 0 || len(parts) > 2 {
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	j, err := s.cron.Job(parts[0])
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+
+	if len(parts) == 1 {
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+
+		n := DefaultNextRuns
+		if v := r.URL.Query().Get("next"); v != "" {
+			if n, err = strconv.Atoi(v); err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid next: %w", err))
+				return
+			}
+			if n < 0 || n > MaxNextRuns {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid next %d, must be within 0 and %d", n, MaxNextRuns))
+				return
+			}
+		}
+
+		info := j.Info()
+		resp := jobResponse{JobInfo: info}
+		if !info.Retired {
+			resp.NextRuns = j.NextRuns(n)
+		}
+
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	resp := map[string]string{"job": j.Name()}
+
+	switch action := parts[1]; action {
+	case cron_jobs.ActionTrigger:
+		var req triggerRequest
+		if err := decodeJSON(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		resp["run_id"], err = s.cron.Trigger(j.Name(), req.Params)
+	case cron_jobs.ActionPause:
+		err = s.cron.Pause(j.Name())
+	case cron_jobs.ActionResume:
+		err = s.cron.Resume(j.Name())
+	case cron_jobs.ActionStop:
+		err = s.cron.StopJob(j.Name())
+	case cron_jobs.ActionReschedule:
+		var req rescheduleRequest
+		if err := decodeJSON(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		err = s.cron.UpdateSchedule(j.Name(), req.Schedule, cron_jobs.WithTimezone(req.Timezone))
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("%w: %q", cron_jobs.ErrUnknownAction, action))
+		return
+	}
+
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GET /history?job=&run_id=&status=&from=&to=&limit=
+// from and to are RFC3339 timestamps
+func (s *Server) history(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	store := s.cron.History()
+	if store == nil {
+		writeError(w, http.StatusNotFound, errors.New("history is disabled"))
+		return
+	}
+
+	q := r.URL.Query()
+	query := cron_jobs.HistoryQuery{
+		Job:    q.Get("job"),
+		RunID:  q.Get("run_id"),
+		Status: cron_jobs.RunStatus(q.Get("status")),
+	}
+
+	var err error
+	if v := q.Get("from"); v != "" {
+		if query.From, err = time.Parse(time.RFC3339, v); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid from: %w", err))
+			return
+		}
+	}
+	if v := q.Get("to"); v != "" {
+		if query.To, err = time.Parse(time.RFC3339, v); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid to: %w", err))
+			return
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if query.Limit, err = strconv.Atoi(v); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit: %w", err))
+			return
+		}
+	}
+
+	records, err := store.Query(r.Context(), query)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, records)
+}
+
+// GET /graphs/{run_id}
+func (s *Server) graphRun(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	parts := splitPath(r.URL.Path, "/graphs/")
+	if len(parts) != 1 {
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	if s.cron.History() == nil {
+		writeError(w, http.StatusNotFound, errors.New("history is disabled"))
+		return
+	}
+
+	run, err := s.cron.GraphRun(r.Context(), parts[0])
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, run)
+}
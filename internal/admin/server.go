@@ -0,0 +1,206 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/vldcreation/sample-cron-go/internal/clock"
+	cron_jobs "github.com/vldcreation/sample-cron-go/internal/cron-jobs"
+	"github.com/vldcreation/sample-cron-go/internal/pubsubs"
+	"github.com/vldcreation/sample-cron-go/internal/storage"
+)
+
+// DefaultShutdownTimeout is how long in flight requests are waited on shutdown
+var DefaultShutdownTimeout = time.Second * 10
+
+// Server expose the admin JSON API
+type Server struct {
+	cron      *cron_jobs.Cron
+	storage   storage.Storage
+	publisher pubsubs.Publisher
+
+	// bucket is the default bucket of storage endpoints
+	bucket string
+	// topic is the default topic of the publish endpoint
+	topic string
+
+	shutdownTimeout time.Duration
+	mux             *http.ServeMux
+	clock           clock.Clock
+}
+
+// Option configure the Server
+type Option func(s *Server)
+
+// WithBucket set the default bucket of storage endpoints
+func WithBucket(bucket string) Option {
+	return func(s *Server) {
+		s.bucket = bucket
+	}
+}
+
+// WithTopic set the default topic of the publish endpoint
+func WithTopic(topic string) Option {
+	return func(s *Server) {
+		s.topic = topic
+	}
+}
+
+// WithShutdownTimeout set how long in flight requests are waited on shutdown
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.shutdownTimeout = d
+	}
+}
+
+// WithClock set the clock telling when presigned urls expire, default to the system clock
+func WithClock(clk clock.Clock) Option {
+	return func(s *Server) {
+		s.clock = clk
+	}
+}
+
+// NewServer create admin server
+func NewServer(c *cron_jobs.Cron, st storage.Storage, pub pubsubs.Publisher, opts ...Option) *Server {
+	s := &Server{
+		cron:            c,
+		storage:         st,
+		publisher:       pub,
+		shutdownTimeout: DefaultShutdownTimeout,
+		mux:             http.NewServeMux(),
+		clock:           clock.New(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.mux.HandleFunc("/healthz", s.health)
+	s.mux.HandleFunc("/jobs", s.jobs)
+	s.mux.HandleFunc("/jobs/", s.job)
+	s.mux.HandleFunc("/history", s.history)
+	s.mux.HandleFunc("/graphs/", s.graphRun)
+	s.mux.HandleFunc("/dead-letters/", s.deadLetter)
+	s.mux.HandleFunc("/at-jobs", s.atJobs)
+	s.mux.HandleFunc("/at-jobs/", s.atJob)
+	s.mux.HandleFunc("/calendars", s.calendars)
+	s.mux.HandleFunc("/calendars/", s.calendar)
+	s.mux.HandleFunc("/storage/objects/", s.object)
+	s.mux.HandleFunc("/storage/presign", s.presign)
+	s.mux.HandleFunc("/pubsub/publish", s.publish)
+
+	return s
+}
+
+// Handle register additional handler, e.g. metrics
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Run listen on addr until ctx is done, then shutdown gracefully
+func (s *Server) Run(ctx context.Context, addr string) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s,
+		ReadHeaderTimeout: time.Second * 10,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		log.Printf("admin: listening on %s\n", addr)
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+
+	log.Println("admin: server stopped")
+	return nil
+}
+
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"running": s.cron.IsRunning()})
+}
+
+// splitPath returns the path segments after prefix
+func splitPath(path, prefix string) []string {
+	path = strings.Trim(strings.TrimPrefix(path, prefix), "/")
+	if path == "" {
+		return nil
+	}
+
+	return strings.Split(path, "/")
+}
+
+func allowMethod(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m {
+			return true
+		}
+	}
+
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("admin: failed to write response: %v\n", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// statusOf map domain error into http status
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, cron_jobs.ErrJobNotFound), errors.Is(err, cron_jobs.ErrCalendarNotFound), errors.Is(err, cron_jobs.ErrDeadLetterNotFound),
+		errors.Is(err, cron_jobs.ErrAtJobNotFound), errors.Is(err, storage.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, cron_jobs.ErrJobRetired), errors.Is(err, cron_jobs.ErrCronStopped), errors.Is(err, cron_jobs.ErrAtJobDone):
+		return http.StatusConflict
+	case errors.Is(err, cron_jobs.ErrInvalidSpec), errors.Is(err, cron_jobs.ErrInvalidInterval), errors.Is(err, cron_jobs.ErrHandlerNotFound):
+		return http.StatusBadRequest
+	case errors.Is(err, cron_jobs.ErrAtDisabled):
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func decodeJSON(r *http.Request, v any) error {
+	if r.ContentLength == 0 {
+		return nil
+	}
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	return dec.Decode(v)
+}
@@ -0,0 +1,302 @@
+package admin_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vldcreation/sample-cron-go/internal/admin"
+	"github.com/vldcreation/sample-cron-go/internal/clock"
+	cron_jobs "github.com/vldcreation/sample-cron-go/internal/cron-jobs"
+	"github.com/vldcreation/sample-cron-go/internal/pubsubs"
+	"github.com/vldcreation/sample-cron-go/internal/storage"
+)
+
+// fakeStorage keep objects in memory, only the methods used by the api are implemented
+type fakeStorage struct {
+	storage.Storage
+
+	mu      sync.Mutex
+	objects map[string]*storage.ObjectInfo
+}
+
+func (f *fakeStorage) Put(ctx context.Context, parent, name string, contents []byte, cacheAble bool, contentType string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.objects[parent+"/"+name] = &storage.ObjectInfo{Name: name, Size: int64(len(contents)), ContentType: contentType}
+	return nil
+}
+
+func (f *fakeStorage) Stat(ctx context.Context, parent, name string) (*storage.ObjectInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	info, ok := f.objects[parent+"/"+name]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return info, nil
+}
+
+func (f *fakeStorage) Delete(ctx context.Context, parent, name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.objects, parent+"/"+name)
+	return nil
+}
+
+func (f *fakeStorage) PresignURL(ctx context.Context, parent, name string, expires time.Duration) (string, error) {
+	return fmt.Sprintf("https://storage.test/%s/%s?expires=%d", parent, name, int(expires.Seconds())), nil
+}
+
+type fakePublisher struct {
+	mu       sync.Mutex
+	messages []*pubsubs.Message
+}
+
+func (f *fakePublisher) Publish(ctx context.Context, msg *pubsubs.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.messages = append(f.messages, msg)
+	return nil
+}
+
+func TestServer(t *testing.T) {
+	c := cron_jobs.NewCron(cron_jobs.WithAtStore(cron_jobs.NewFileAtStore(filepath.Join(t.TempDir(), "at_jobs.json"))))
+	defer c.Stop()
+
+	ran := make(chan string, 1)
+	if _, err := c.AddJobWithCron("0 0 1 1 *", func(ctx context.Context) error {
+		ran <- cron_jobs.Param(ctx, "object", "")
+		return nil
+	}, cron_jobs.WithName("resign")); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	if err := c.RegisterCalendar(cron_jobs.NewCalendar("id")); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if err := c.RegisterHandler("delete-object", func(ctx context.Context) error { return nil }); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	st := &fakeStorage{objects: make(map[string]*storage.ObjectInfo)}
+	pub := &fakePublisher{}
+	clk := clock.NewFake(time.Date(2023, 6, 19, 9, 0, 0, 0, time.UTC))
+	srv := httptest.NewServer(admin.NewServer(c, st, pub, admin.WithBucket("bucket"), admin.WithTopic("cron"), admin.WithClock(clk)))
+	defer srv.Close()
+
+	do := func(t *testing.T, method, path, body string, out any) int {
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		defer resp.Body.Close()
+
+		if out != nil {
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+		}
+
+		return resp.StatusCode
+	}
+
+	t.Run("should list jobs with next runs", func(t *testing.T) {
+		var jobs []cron_jobs.JobInfo
+		if status := do(t, http.MethodGet, "/jobs", "", &jobs); status != http.StatusOK {
+			t.Fatalf("expected 200, got %d", status)
+		}
+		if len(jobs) != 1 || jobs[0].Name != "resign" {
+			t.Errorf("expected [resign], got %+v", jobs)
+		}
+
+		var job struct {
+			Name     string      `json:"name"`
+			NextRuns []time.Time `json:"next_runs"`
+		}
+		if status := do(t, http.MethodGet, "/jobs/resign?next=3", "", &job); status != http.StatusOK {
+			t.Fatalf("expected 200, got %d", status)
+		}
+		if len(job.NextRuns) != 3 {
+			t.Errorf("expected 3 next runs, got %v", job.NextRuns)
+		}
+		for _, next := range []string{"-1", "101", "1000000000000"} {
+			if status := do(t, http.MethodGet, "/jobs/resign?next="+next, "", nil); status != http.StatusBadRequest {
+				t.Errorf("next=%s: expected 400, got %d", next, status)
+			}
+		}
+
+		if status := do(t, http.MethodGet, "/jobs/unknown", "", nil); status != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", status)
+		}
+	})
+
+	t.Run("should trigger job and read its history", func(t *testing.T) {
+		var resp map[string]string
+		if status := do(t, http.MethodPost, "/jobs/resign/trigger", `{"params": {"object": "a.jpeg"}}`, &resp); status != http.StatusOK {
+			t.Fatalf("expected 200, got %d", status)
+		}
+
+		select {
+		case object := <-ran:
+			if object != "a.jpeg" {
+				t.Errorf("expected a.jpeg, got %s", object)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected job to run")
+		}
+
+		var records []cron_jobs.RunRecord
+		deadline := time.Now().Add(time.Second)
+		for len(records) == 0 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+			do(t, http.MethodGet, "/history?job=resign&run_id="+resp["run_id"], "", &records)
+		}
+		if len(records) != 1 || records[0].Status != cron_jobs.RunSuccess {
+			t.Errorf("expected 1 successful record, got %+v", records)
+		}
+	})
+
+	t.Run("should return 404 when graph run is unknown", func(t *testing.T) {
+		if status := do(t, http.MethodGet, "/graphs/unknown", "", nil); status != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", status)
+		}
+	})
+
+	t.Run("should return 404 when dead letter is unknown", func(t *testing.T) {
+		if status := do(t, http.MethodGet, "/dead-letters/unknown", "", nil); status != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", status)
+		}
+		if status := do(t, http.MethodPost, "/dead-letters/unknown/replay", "", nil); status != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", status)
+		}
+	})
+
+	t.Run("should schedule, list and cancel at job", func(t *testing.T) {
+		var aj cron_jobs.AtJob
+		body := `{"run_at": "2099-11-01T09:00:00+07:00", "handler": "delete-object", "params": {"object": "a.jpeg"}}`
+		if status := do(t, http.MethodPost, "/at-jobs", body, &aj); status != http.StatusCreated {
+			t.Fatalf("expected 201, got %d", status)
+		}
+
+		var jobs []cron_jobs.AtJob
+		if status := do(t, http.MethodGet, "/at-jobs", "", &jobs); status != http.StatusOK {
+			t.Fatalf("expected 200, got %d", status)
+		}
+		if len(jobs) != 1 || jobs[0].ID != aj.ID || jobs[0].Status != cron_jobs.AtPending || jobs[0].Params["object"] != "a.jpeg" {
+			t.Errorf("expected pending job %s, got %+v", aj.ID, jobs)
+		}
+
+		if status := do(t, http.MethodDelete, "/at-jobs/"+aj.ID, "", &aj); status != http.StatusOK || aj.Status != cron_jobs.AtCancelled {
+			t.Errorf("expected 200 with cancelled job, got %d %+v", status, aj)
+		}
+		if status := do(t, http.MethodDelete, "/at-jobs/"+aj.ID, "", nil); status != http.StatusConflict {
+			t.Errorf("expected 409, got %d", status)
+		}
+		if status := do(t, http.MethodGet, "/at-jobs/unknown", "", nil); status != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", status)
+		}
+		if status := do(t, http.MethodPost, "/at-jobs", `{"run_at": "2099-11-01T09:00:00+07:00", "handler": "unknown"}`, nil); status != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", status)
+		}
+	})
+
+	t.Run("should tell business day of calendar", func(t *testing.T) {
+		var resp struct {
+			BusinessDay     bool      `json:"business_day"`
+			NextBusinessDay time.Time `json:"next_business_day"`
+		}
+		if status := do(t, http.MethodGet, "/calendars/id?at=2023-06-24T09:00:00Z", "", &resp); status != http.StatusOK {
+			t.Fatalf("expected 200, got %d", status)
+		}
+
+		expected := time.Date(2023, 6, 26, 9, 0, 0, 0, time.UTC)
+		if resp.BusinessDay || !resp.NextBusinessDay.Equal(expected) {
+			t.Errorf("expected saturday off and next business day %s, got %+v", expected, resp)
+		}
+
+		if status := do(t, http.MethodGet, "/calendars/unknown", "", nil); status != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", status)
+		}
+	})
+
+	t.Run("should pause job", func(t *testing.T) {
+		if status := do(t, http.MethodPost, "/jobs/resign/pause", "", nil); status != http.StatusOK {
+			t.Fatalf("expected 200, got %d", status)
+		}
+
+		if j, _ := c.Job("resign"); !j.Paused() {
+			t.Errorf("expected job to be paused")
+		}
+
+		if status := do(t, http.MethodGet, "/jobs/resign/pause", "", nil); status != http.StatusMethodNotAllowed {
+			t.Errorf("expected 405, got %d", status)
+		}
+	})
+
+	t.Run("should upload, stat and delete object", func(t *testing.T) {
+		var info storage.ObjectInfo
+		if status := do(t, http.MethodPut, "/storage/objects/dir/a.txt", "hello", &info); status != http.StatusCreated {
+			t.Fatalf("expected 201, got %d", status)
+		}
+		if info.Name != "dir/a.txt" || info.Size != 5 {
+			t.Errorf("expected dir/a.txt of 5 bytes, got %+v", info)
+		}
+
+		if status := do(t, http.MethodGet, "/storage/objects/dir/a.txt", "", &info); status != http.StatusOK {
+			t.Errorf("expected 200, got %d", status)
+		}
+
+		if status := do(t, http.MethodDelete, "/storage/objects/dir/a.txt", "", nil); status != http.StatusNoContent {
+			t.Errorf("expected 204, got %d", status)
+		}
+
+		if status := do(t, http.MethodGet, "/storage/objects/dir/a.txt", "", nil); status != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", status)
+		}
+	})
+
+	t.Run("should presign url", func(t *testing.T) {
+		var resp map[string]string
+		if status := do(t, http.MethodPost, "/storage/presign", `{"object": "a.jpeg", "expires": "1h"}`, &resp); status != http.StatusOK {
+			t.Fatalf("expected 200, got %d", status)
+		}
+		if resp["url"] != "https://storage.test/bucket/a.jpeg?expires=3600" {
+			t.Errorf("expected presigned url, got %s", resp["url"])
+		}
+		if expected := clk.Now().Add(time.Hour).Format(time.RFC3339); resp["expires_at"] != expected {
+			t.Errorf("expected expiry %s, got %s", expected, resp["expires_at"])
+		}
+
+		if status := do(t, http.MethodPost, "/storage/presign", `{"object": "a.jpeg", "expires": "720h"}`, nil); status != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", status)
+		}
+	})
+
+	t.Run("should publish test message", func(t *testing.T) {
+		if status := do(t, http.MethodPost, "/pubsub/publish", `{"data": "ping"}`, nil); status != http.StatusOK {
+			t.Fatalf("expected 200, got %d", status)
+		}
+
+		if len(pub.messages) != 1 || pub.messages[0].Topic != "cron" || !bytes.Equal(pub.messages[0].Data, []byte("ping")) {
+			t.Errorf("expected ping on cron, got %+v", pub.messages)
+		}
+	})
+}
@@ -0,0 +1,160 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/vldcreation/sample-cron-go/internal/pubsubs"
+	"github.com/vldcreation/sample-cron-go/internal/storage"
+)
+
+// MaxUploadSize limit the body of an upload
+var MaxUploadSize int64 = 32 << 20
+
+type presignRequest struct {
+	Bucket string `json:"bucket"`
+	Object string `json:"object"`
+	// Expires is a duration, e.g. "1h", default to storage.DefaultDuration
+	Expires string `json:"expires"`
+}
+
+type presignResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type publishRequest struct {
+	Topic      string            `json:"topic"`
+	Data       string            `json:"data"`
+	Attributes map[string]string `json:"attributes"`
+}
+
+// PUT|GET|DELETE /storage/objects/{name}?bucket=
+// PUT upload the body using the request Content-Type, GET returns the object info
+func (s *Server) object(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/storage/objects/")
+	if name == "" {
+		writeError(w, http.StatusNotFound, errors.New("missing object name"))
+		return
+	}
+
+	bucket := r.URL.Query().Get("bucket")
+	if bucket == "" {
+		bucket = s.bucket
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxUploadSize))
+		if err != nil {
+			writeError(w, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+
+		cacheAble := r.URL.Query().Get("cache") == "true"
+		if err := s.storage.Put(r.Context(), bucket, name, body, cacheAble, r.Header.Get("Content-Type")); err != nil {
+			writeError(w, statusOf(err), err)
+			return
+		}
+
+		info, err := s.storage.Stat(r.Context(), bucket, name)
+		if err != nil {
+			writeError(w, statusOf(err), err)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, info)
+	case http.MethodGet:
+		info, err := s.storage.Stat(r.Context(), bucket, name)
+		if err != nil {
+			writeError(w, statusOf(err), err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, info)
+	case http.MethodDelete:
+		if err := s.storage.Delete(r.Context(), bucket, name); err != nil {
+			writeError(w, statusOf(err), err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		allowMethod(w, r, http.MethodPut, http.MethodGet, http.MethodDelete)
+	}
+}
+
+// POST /storage/presign
+func (s *Server) presign(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var req presignRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.Object == "" {
+		writeError(w, http.StatusBadRequest, errors.New("missing object"))
+		return
+	}
+	if req.Bucket == "" {
+		req.Bucket = s.bucket
+	}
+
+	expires := storage.DefaultDuration
+	if req.Expires != "" {
+		d, err := time.ParseDuration(req.Expires)
+		if err != nil || d <= 0 || d > storage.MaxDuration {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid expires %q, must be within %v", req.Expires, storage.MaxDuration))
+			return
+		}
+		expires = d
+	}
+
+	url, err := s.storage.PresignURL(r.Context(), req.Bucket, req.Object, expires)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, presignResponse{URL: url, ExpiresAt: s.clock.Now().Add(expires)})
+}
+
+// POST /pubsub/publish
+func (s *Server) publish(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var req publishRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.Topic == "" {
+		req.Topic = s.topic
+	}
+	if req.Topic == "" {
+		writeError(w, http.StatusBadRequest, errors.New("missing topic"))
+		return
+	}
+
+	if err := s.publisher.Publish(r.Context(), &pubsubs.Message{
+		Topic:     req.Topic,
+		Data:      []byte(req.Data),
+		Attribute: req.Attributes,
+	}); err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"topic": req.Topic})
+}
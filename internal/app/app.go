@@ -3,11 +3,42 @@ package app
 import (
 	"context"
 	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/vldcreation/sample-cron-go/internal/admin"
+	"github.com/vldcreation/sample-cron-go/internal/clock"
 	"github.com/vldcreation/sample-cron-go/internal/config"
 	cron_jobs "github.com/vldcreation/sample-cron-go/internal/cron-jobs"
+	"github.com/vldcreation/sample-cron-go/internal/metrics"
 	"github.com/vldcreation/sample-cron-go/internal/pubsubs"
 	"github.com/vldcreation/sample-cron-go/internal/storage"
+	"github.com/vldcreation/sample-cron-go/internal/tracing"
+)
+
+var (
+	// DrainTimeout is how long running jobs are waited on shutdown
+	DrainTimeout = time.Second * 30
+	// StatePrefix is where the last successful run of each job is stored in the storage bucket,
+	// shared by the replicas to catch up missed runs
+	StatePrefix = "cron-state"
+	// LockPrefix is where job leases are stored in the storage bucket
+	LockPrefix = "cron-locks"
+	// CommandLogPrefix is where the output of command jobs is stored in the storage bucket
+	CommandLogPrefix = "cron-logs"
+	// AtJobsObject keep the one-shot jobs in the storage bucket, shared by the replicas
+	AtJobsObject = "cron-at-jobs.json"
+	// HTTPResponsePrefix is where the responses of http jobs are archived in the storage bucket
+	HTTPResponsePrefix = "cron-responses"
+	// CrontabReload is how often the crontab file is checked for changes by default
+	CrontabReload = time.Minute
+	// DeadLetterPrefix is where runs failing after their retries are stored in the dead letter bucket
+	DeadLetterPrefix = "cron-dead-letters"
 )
 
 type App struct {
@@ -16,6 +47,17 @@ type App struct {
 	Storage      storage.Storage
 	Publisherer  pubsubs.Publisher
 	Subscriberer pubsubs.Subscriberer
+	Admin        *admin.Server
+	Metrics      *metrics.Metrics
+	// Clock is shared by the cron and the storage, default to the system clock
+	Clock clock.Clock
+	// Crontab imports the crontab file declared in config, nil when there is none
+	Crontab *cron_jobs.CrontabImporter
+
+	// wg tracks background services stopped when the run context is done
+	wg sync.WaitGroup
+	// closers release resources once the services are stopped, e.g. flush pending spans
+	closers []func(context.Context) error
 }
 
 func Run(ctx context.Context, app *App) {
@@ -25,17 +67,30 @@ func Run(ctx context.Context, app *App) {
 
 	log.Printf("app config: %+v\n", app.Config.Storage)
 
-	// init cron
-	app.Cron = cron_jobs.NewCron()
+	if app.Clock == nil {
+		app.Clock = clock.New()
+	}
+
+	// init metrics and tracing, every client below is wrapped to be measured and traced
+	app.Metrics = metrics.New()
+	shutdownTracing, err := tracing.Setup(conf.Tracing)
+	if err != nil {
+		log.Fatalf("error init tracing: %v\n", err)
+		panic(err)
+	}
+	app.closers = append(app.closers, shutdownTracing)
 
 	// init pubsub
 	initPubsubs := pubsubs.NewPubSubs(ctx, conf)
-	app.Publisherer = pubsubs.NewGPublisher(initPubsubs)
-	app.Subscriberer = pubsubs.NewGSubscriber(initPubsubs)
+	app.closers = append(app.closers, func(context.Context) error {
+		return initPubsubs.Close()
+	})
+	app.Publisherer = tracing.InstrumentPublisher(app.Metrics.InstrumentPublisher(pubsubs.NewGPublisher(initPubsubs)))
+	app.Subscriberer = tracing.InstrumentSubscriber(app.Metrics.InstrumentSubscriber(pubsubs.NewGSubscriber(initPubsubs)))
 
 	// init storage
 	// default storage
-	defaultStorage, err := storage.NewMinio()
+	defaultStorage, err := storage.NewMinio(storage.WithClock(app.Clock))
 	if err != nil {
 		log.Fatalf("error init minio: %v\n", err)
 		panic(err)
@@ -43,19 +98,180 @@ func Run(ctx context.Context, app *App) {
 	// switcher
 	switch conf.App.APP_ENV {
 	case "dev":
-		app.Storage = defaultStorage
+		app.Storage = instrumentStorage(app, defaultStorage, "minio")
 		break
 	case "prod":
-		app.Storage, err = storage.NewGCS(ctx, conf.GCS.AccountPath)
+		gcs, err := storage.NewGCS(ctx, conf.GCS.AccountPath, storage.WithClock(app.Clock))
 		if err != nil {
 			log.Fatalf("error init gcs: %v\n", err)
 			panic(err)
 		}
+		app.Storage = instrumentStorage(app, gcs, "gcs")
 		break
 	default:
-		app.Storage = defaultStorage
+		app.Storage = instrumentStorage(app, defaultStorage, "minio")
 		break
 	}
 
+	// init cron
+	// replicas share the storage bucket, so a scheduled run is executed only once
+	deadLetterBucket := conf.Storage.DeadLetterBucket
+	if deadLetterBucket == "" {
+		deadLetterBucket = conf.Storage.Bucket
+	}
+	retention := cron_jobs.HistoryRetention{MaxRecords: conf.History.MaxRecords, MaxAge: conf.History.MaxAge}
+	if retention.MaxRecords <= 0 {
+		retention.MaxRecords = cron_jobs.DefaultHistoryRecords
+	}
+	historyClock := cron_jobs.WithHistoryClock(app.Clock)
+	var history cron_jobs.HistoryStore = cron_jobs.NewMemoryHistory(retention, historyClock)
+	if conf.History.File != "" {
+		fileHistory, err := cron_jobs.NewFileHistory(conf.History.File, retention, historyClock)
+		if err != nil {
+			log.Fatalf("error init cron history: %v\n", err)
+			panic(err)
+		}
+		history = fileHistory
+		app.closers = append(app.closers, func(context.Context) error {
+			return fileHistory.Close()
+		})
+	}
+	cronOpts := []cron_jobs.Option{
+		cron_jobs.WithClock(app.Clock),
+		cron_jobs.WithDrainTimeout(DrainTimeout),
+		cron_jobs.WithHistory(app.Metrics.InstrumentHistory(history)),
+		cron_jobs.WithMiddleware(tracing.JobMiddleware()),
+		cron_jobs.WithState(cron_jobs.NewStorageState(app.Storage, conf.Storage.Bucket, StatePrefix)),
+		cron_jobs.WithLocker(cron_jobs.NewStorageLocker(app.Storage, conf.Storage.Bucket, LockPrefix, cron_jobs.InstanceID(),
+			cron_jobs.WithLockerClock(app.Clock),
+		), cron_jobs.DefaultLockTTL),
+		cron_jobs.WithDeadLetters(cron_jobs.NewStorageDeadLetters(app.Storage, deadLetterBucket, DeadLetterPrefix,
+			cron_jobs.WithDeadLetterTopic(app.Publisherer, conf.PubSub.DeadLetterTopic),
+		)),
+		cron_jobs.WithAtStore(cron_jobs.NewStorageAtStore(app.Storage, conf.Storage.Bucket, AtJobsObject)),
+	}
+	if conf.PubSub.EventTopic != "" {
+		events := cron_jobs.NewEventPublisher(app.Publisherer, conf.PubSub.EventTopic)
+		cronOpts = append(cronOpts, cron_jobs.WithListener(events.Handle))
+		// publish the last events before tracing is shut down
+		app.closers = append([]func(context.Context) error{events.Close}, app.closers...)
+	}
+	app.Cron = cron_jobs.NewCron(cronOpts...)
+
+	// build the schedule declared in config
+	if err := registerHandlers(app); err != nil {
+		log.Fatalf("error register job handlers: %v\n", err)
+		panic(err)
+	}
+	if err := buildCalendars(app.Cron, conf.Calendars); err != nil {
+		log.Fatalf("error build calendars: %v\n", err)
+		panic(err)
+	}
+	commandOpts := []cron_jobs.CommandOption{
+		cron_jobs.WithCommandLogs(app.Storage, conf.Storage.Bucket, CommandLogPrefix),
+		cron_jobs.WithCommandClock(app.Clock),
+	}
+	if err := buildJobs(app.Cron, conf.Jobs, jobKinds{
+		command: commandOpts,
+		http:    []cron_jobs.HTTPRequestOption{cron_jobs.WithHTTPClient(tracing.InstrumentHTTPClient(http.DefaultClient))},
+		archive: cron_jobs.WithHTTPResponseArchive(app.Storage, conf.Storage.Bucket, HTTPResponsePrefix),
+		storage: app.Storage,
+		bucket:  conf.Storage.Bucket,
+		batch:   []cron_jobs.BatchOption{cron_jobs.WithBatchClock(app.Clock)},
+	}); err != nil {
+		log.Fatalf("error build jobs: %v\n", err)
+		panic(err)
+	}
+	if conf.Crontab.File != "" {
+		app.Crontab = cron_jobs.NewCrontabImporter(app.Cron, conf.Crontab.File,
+			cron_jobs.WithCrontabCommandOptions(commandOpts...),
+		)
+		if err := app.Crontab.Load(); err != nil {
+			log.Fatalf("error import crontab: %v\n", err)
+			panic(err)
+		}
+
+		reload := conf.Crontab.Reload
+		if reload <= 0 {
+			reload = CrontabReload
+		}
+		app.wg.Add(1)
+		go func() {
+			defer app.wg.Done()
+			app.Crontab.Watch(ctx, reload)
+		}()
+	}
+
+	// init admin api, stopped when ctx is done
+	app.Admin = admin.NewServer(app.Cron, app.Storage, app.Publisherer,
+		admin.WithBucket(conf.Storage.Bucket),
+		admin.WithTopic(conf.PubSub.Topic),
+		admin.WithClock(app.Clock),
+	)
+	app.Admin.Handle("/metrics", app.Metrics.Handler())
+	addr := net.JoinHostPort(conf.App.APP_HOST, conf.App.APP_PORT)
+	app.wg.Add(1)
+	go func() {
+		defer app.wg.Done()
+		if err := app.Admin.Run(ctx, addr); err != nil {
+			log.Printf("error admin server: %v\n", err)
+		}
+	}()
+
+	// reload calendar and crontab files without a restart
+	app.wg.Add(1)
+	go func() {
+		defer app.wg.Done()
+		app.reloadOnHangup(ctx)
+	}()
+
 	log.Println("app initialized successfully")
 }
+
+// reloadOnHangup reload calendars and crontab every time the process receive SIGHUP, until ctx is done
+func (app *App) reloadOnHangup(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			if err := app.Cron.ReloadCalendars(); err != nil {
+				log.Printf("error reload calendars: %v\n", err)
+			} else {
+				log.Println("calendars reloaded")
+			}
+
+			if app.Crontab == nil {
+				continue
+			}
+			if err := app.Crontab.Load(); err != nil {
+				log.Printf("error reload crontab: %v\n", err)
+				continue
+			}
+			log.Println("crontab reloaded")
+		}
+	}
+}
+
+func instrumentStorage(app *App, st storage.Storage, backend string) storage.Storage {
+	return tracing.InstrumentStorage(app.Metrics.InstrumentStorage(st, backend), backend)
+}
+
+// Wait for background services to stop then release resources,
+// call it once the run context is done and the cron is drained
+func (app *App) Wait() {
+	app.wg.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), DrainTimeout)
+	defer cancel()
+
+	for _, closer := range app.closers {
+		if err := closer(ctx); err != nil {
+			log.Printf("error close app: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,377 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/vldcreation/sample-cron-go/internal/config"
+	cron_jobs "github.com/vldcreation/sample-cron-go/internal/cron-jobs"
+	"github.com/vldcreation/sample-cron-go/internal/storage"
+)
+
+// registerHandlers register the handlers jobs in config can refer to
+func registerHandlers(app *App) error {
+	handlers := map[string]cron_jobs.JobFunc{
+		"resign-url": resignURLHandler(app),
+	}
+
+	for name, fn := range handlers {
+		if err := app.Cron.RegisterHandler(name, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// buildCalendars register the calendars declared in config
+func buildCalendars(c *cron_jobs.Cron, calendars []config.CalendarConfig) error {
+	for _, cc := range calendars {
+		var opts []cron_jobs.CalendarOption
+
+		if cc.Timezone != "" {
+			loc, err := time.LoadLocation(cc.Timezone)
+			if err != nil {
+				return fmt.Errorf("calendar %s: %w", cc.Name, err)
+			}
+			opts = append(opts, cron_jobs.WithCalendarLocation(loc))
+		}
+
+		if cc.Weekend != nil {
+			days := make([]time.Weekday, 0, len(cc.Weekend))
+			for _, s := range cc.Weekend {
+				d, err := cron_jobs.ParseWeekday(s)
+				if err != nil {
+					return fmt.Errorf("calendar %s: %w", cc.Name, err)
+				}
+				days = append(days, d)
+			}
+			opts = append(opts, cron_jobs.WithWeekend(days...))
+		}
+
+		for _, s := range cc.Holidays {
+			h, err := cron_jobs.ParseHoliday(s, "")
+			if err != nil {
+				return fmt.Errorf("calendar %s: %w", cc.Name, err)
+			}
+			opts = append(opts, cron_jobs.WithHolidays(h))
+		}
+
+		cal := cron_jobs.NewCalendar(cc.Name, opts...)
+		if cc.File != "" {
+			loaded, err := cron_jobs.LoadCalendar(cc.Name, cc.File, opts...)
+			if err != nil {
+				return err
+			}
+			cal = loaded
+		}
+
+		if err := c.RegisterCalendar(cal); err != nil {
+			return err
+		}
+		log.Printf("calendar %s registered with %d holidays\n", cal.Name(), len(cal.Holidays()))
+	}
+
+	return nil
+}
+
+// jobKinds are the options of the built-in job kinds declared in config
+type jobKinds struct {
+	command []cron_jobs.CommandOption
+	http    []cron_jobs.HTTPRequestOption
+	// archive is added to the http jobs archiving their response
+	archive cron_jobs.HTTPRequestOption
+	// storage list the objects of batch jobs, in bucket unless the job set one
+	storage storage.Storage
+	bucket  string
+	batch   []cron_jobs.BatchOption
+}
+
+// buildJobs schedule every enabled job declared in config
+func buildJobs(c *cron_jobs.Cron, jobs []config.JobConfig, kinds jobKinds) error {
+	for _, jc := range jobs {
+		if !jc.IsEnabled() {
+			log.Printf("job %s is disabled\n", jc.Name)
+			continue
+		}
+
+		opts, err := jobOptions(jc)
+		if err != nil {
+			return fmt.Errorf("job %s: %w", jc.Name, err)
+		}
+
+		var j *cron_jobs.Job
+		if len(jc.Steps) > 0 {
+			g, gerr := buildGraph(c, jc.Steps)
+			if gerr != nil {
+				return fmt.Errorf("job %s: %w", jc.Name, gerr)
+			}
+			j, err = c.AddGraph(jc.Schedule, g, opts...)
+		} else if jc.Command != nil {
+			j, err = c.AddJobWithSpec(jc.Schedule, cron_jobs.NewCommandJob(commandSpec(jc.Command), kinds.command...), opts...)
+		} else if jc.HTTP != nil {
+			httpOpts := append([]cron_jobs.HTTPRequestOption{}, kinds.http...)
+			if jc.HTTP.Archive && kinds.archive != nil {
+				httpOpts = append(httpOpts, kinds.archive)
+			}
+
+			fn, ferr := cron_jobs.NewHTTPRequestJob(httpSpec(jc.HTTP), httpOpts...)
+			if ferr != nil {
+				return fmt.Errorf("job %s: %w", jc.Name, ferr)
+			}
+			j, err = c.AddJobWithSpec(jc.Schedule, fn, opts...)
+		} else if jc.Batch != nil {
+			fn, ferr := c.Handler(jc.Handler)
+			if ferr != nil {
+				return fmt.Errorf("job %s: %w", jc.Name, ferr)
+			}
+			j, err = c.AddJobWithSpec(jc.Schedule, cron_jobs.NewBatchJob(kinds.storage, batchSpec(jc.Batch, kinds.bucket), fn, kinds.batch...), opts...)
+		} else {
+			j, err = c.AddJobWithHandler(jc.Handler, jc.Schedule, opts...)
+		}
+		if err != nil {
+			return fmt.Errorf("job %s: %w", jc.Name, err)
+		}
+
+		log.Printf("job %s scheduled: %s\n", j.Name(), j.Info().Schedule)
+	}
+
+	return nil
+}
+
+func jobOptions(jc config.JobConfig) ([]cron_jobs.JobOption, error) {
+	opts := []cron_jobs.JobOption{
+		cron_jobs.WithName(jc.Name),
+		cron_jobs.WithTimezone(jc.Timezone),
+		cron_jobs.WithTags(jc.Tags...),
+		cron_jobs.WithParams(jc.Params),
+	}
+
+	if jc.Retry != nil {
+		opts = append(opts, cron_jobs.WithRetry(retryPolicy(jc.Retry)))
+	}
+
+	if jc.Breaker != nil {
+		opts = append(opts, cron_jobs.WithBreaker(cron_jobs.BreakerPolicy{
+			Threshold: jc.Breaker.Threshold,
+			Cooldown:  jc.Breaker.Cooldown,
+		}))
+	}
+
+	if jc.Overlap != "" {
+		policy, err := cron_jobs.ParseOverlapPolicy(jc.Overlap)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, cron_jobs.WithOverlap(policy))
+	}
+
+	if jc.Calendar != "" {
+		policy, err := cron_jobs.ParseCalendarPolicy(jc.CalendarPolicy)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, cron_jobs.WithCalendar(jc.Calendar, policy))
+	}
+
+	if jc.Misfire != "" {
+		policy, err := cron_jobs.ParseMisfirePolicy(jc.Misfire)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, cron_jobs.WithMisfire(policy, jc.MisfireLimit))
+	}
+
+	limits, err := jobLimits(jc)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(opts, limits...), nil
+}
+
+// buildGraph build the graph of handlers declared by steps
+func buildGraph(c *cron_jobs.Cron, steps []config.StepConfig) (*cron_jobs.Graph, error) {
+	g := cron_jobs.NewGraph()
+
+	for _, sc := range steps {
+		fn, err := c.Handler(sc.Handler)
+		if err != nil {
+			return nil, fmt.Errorf("step %s: %w", sc.Name, err)
+		}
+
+		opts := []cron_jobs.NodeOption{cron_jobs.WithNodeParams(sc.Params)}
+		if sc.Retry != nil {
+			opts = append(opts, cron_jobs.WithNodeRetry(retryPolicy(sc.Retry)))
+		}
+
+		if err := g.AddNode(sc.Name, fn, opts...); err != nil {
+			return nil, err
+		}
+	}
+
+	// edges need every step to be added first
+	for _, sc := range steps {
+		for from, cond := range sc.After {
+			ec, err := cron_jobs.ParseEdgeCondition(cond)
+			if err != nil {
+				return nil, fmt.Errorf("step %s: %w", sc.Name, err)
+			}
+
+			if err := g.AddEdge(from, sc.Name, ec); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return g, nil
+}
+
+func commandSpec(cc *config.CommandConfig) cron_jobs.CommandSpec {
+	return cron_jobs.CommandSpec{
+		Path:      cc.Path,
+		Args:      cc.Args,
+		Env:       cc.Env,
+		Dir:       cc.Dir,
+		Timeout:   cc.Timeout,
+		KillGrace: cc.KillGrace,
+	}
+}
+
+func httpSpec(hc *config.HTTPConfig) cron_jobs.HTTPRequestSpec {
+	spec := cron_jobs.HTTPRequestSpec{
+		Method:       hc.Method,
+		URL:          hc.URL,
+		Header:       make(http.Header, len(hc.Headers)),
+		Body:         hc.Body,
+		Timeout:      hc.Timeout,
+		ExpectStatus: hc.ExpectStatus,
+	}
+	for k, v := range hc.Headers {
+		spec.Header.Set(k, v)
+	}
+	if hc.Assert != nil {
+		spec.Assert = &cron_jobs.JSONAssertion{Path: hc.Assert.Path, Equals: hc.Assert.Equals}
+	}
+
+	return spec
+}
+
+func batchSpec(bc *config.BatchConfig, bucket string) cron_jobs.BatchSpec {
+	spec := cron_jobs.BatchSpec{
+		Bucket:   bc.Bucket,
+		Prefix:   bc.Prefix,
+		Workers:  bc.Workers,
+		PageSize: bc.PageSize,
+	}
+	if spec.Bucket == "" {
+		spec.Bucket = bucket
+	}
+	if bc.Retry != nil {
+		spec.Retry = retryPolicy(bc.Retry)
+	}
+
+	return spec
+}
+
+func retryPolicy(rc *config.RetryConfig) cron_jobs.RetryPolicy {
+	policy := cron_jobs.DefaultRetryPolicy()
+	if rc.MaxAttempts > 0 {
+		policy.MaxAttempts = rc.MaxAttempts
+	}
+	if rc.InitialBackoff > 0 {
+		policy.InitialBackoff = rc.InitialBackoff
+	}
+	if rc.MaxBackoff > 0 {
+		policy.MaxBackoff = rc.MaxBackoff
+	}
+	if rc.Multiplier > 0 {
+		policy.Multiplier = rc.Multiplier
+	}
+	if rc.Jitter > 0 {
+		policy.Jitter = rc.Jitter
+	}
+
+	return policy
+}
+
+func jobLimits(jc config.JobConfig) ([]cron_jobs.JobOption, error) {
+	var opts []cron_jobs.JobOption
+
+	if jc.MaxRuns > 0 {
+		opts = append(opts, cron_jobs.WithMaxRuns(jc.MaxRuns))
+	}
+
+	if jc.StartAfter != "" {
+		t, err := time.Parse(time.RFC3339, jc.StartAfter)
+		if err != nil {
+			return nil, fmt.Errorf("start_after: %w", err)
+		}
+		opts = append(opts, cron_jobs.WithStartAfter(t))
+	}
+
+	if jc.StopAfter != "" {
+		t, err := time.Parse(time.RFC3339, jc.StopAfter)
+		if err != nil {
+			return nil, fmt.Errorf("stop_after: %w", err)
+		}
+		opts = append(opts, cron_jobs.WithStopAfter(t))
+	}
+
+	for _, s := range jc.Windows {
+		w, err := cron_jobs.ParseWindow(s)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, cron_jobs.WithWindows(w))
+	}
+
+	for _, s := range jc.Blackouts {
+		w, err := cron_jobs.ParseWindow(s)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, cron_jobs.WithBlackouts(w))
+	}
+
+	return opts, nil
+}
+
+// resignURLHandler re-sign the url of an object, so it never stays expired.
+// params: object (required), bucket (default to storage bucket)
+func resignURLHandler(app *App) cron_jobs.JobFunc {
+	// last generated url per object
+	var urls sync.Map
+
+	return func(ctx context.Context) error {
+		bucket := cron_jobs.Param(ctx, "bucket", app.Config.Storage.Bucket)
+		object := cron_jobs.Param(ctx, "object", "")
+		if object == "" {
+			return cron_jobs.Permanent(fmt.Errorf("resign-url: missing object param"))
+		}
+
+		existing, ok := urls.Load(bucket + "/" + object)
+		if !ok {
+			bt, err := app.Storage.Get(ctx, bucket, object)
+			if err != nil {
+				return fmt.Errorf("get url: %w", err)
+			}
+			existing = string(bt)
+		}
+
+		log.Println("reSigned url for file ", object)
+		url, err := app.Storage.ReSignedURL(ctx, bucket, object, existing.(string))
+		if err != nil {
+			return fmt.Errorf("reSigned url: %w", err)
+		}
+
+		urls.Store(bucket+"/"+object, url)
+		log.Printf("new reSigned url: %v\n", url)
+
+		return nil
+	}
+}
@@ -0,0 +1,184 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/vldcreation/sample-cron-go/internal/config"
+	cron_jobs "github.com/vldcreation/sample-cron-go/internal/cron-jobs"
+)
+
+// newTestCron returns cron with the resign-url handler and the id calendar jobs can refer to
+func newTestCron(t *testing.T) *cron_jobs.Cron {
+	t.Helper()
+
+	c := cron_jobs.NewCron()
+	if err := c.RegisterHandler("resign-url", func(ctx context.Context) error { return nil }); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if err := buildCalendars(c, []config.CalendarConfig{{Name: "id", Weekend: []string{"saturday", "sunday"}}}); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	return c
+}
+
+func TestBuildJobs(t *testing.T) {
+	disabled := false
+
+	t.Run("should build every job kind", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			job      config.JobConfig
+			schedule string
+		}{
+			{
+				name:     "handler",
+				job:      config.JobConfig{Name: "resign", Handler: "resign-url", Schedule: "10s", Tags: []string{"storage"}},
+				schedule: "@every 10s",
+			},
+			{
+				name:     "handler in time zone",
+				job:      config.JobConfig{Name: "resign-daily", Handler: "resign-url", Schedule: "0 9 * * 1-5", Timezone: "Asia/Jakarta", Overlap: "skip", Misfire: "run_once"},
+				schedule: "CRON_TZ=Asia/Jakarta 0 9 * * 1-5",
+			},
+			{
+				name:     "handler on business days",
+				job:      config.JobConfig{Name: "report", Handler: "resign-url", Schedule: "0 9 * * *", Timezone: "UTC", Calendar: "id", CalendarPolicy: "shift", Windows: []string{"08:00-20:00"}, StartAfter: "2023-06-19T00:00:00+07:00"},
+				schedule: "CRON_TZ=UTC 0 9 * * *",
+			},
+			{
+				name:     "command",
+				job:      config.JobConfig{Name: "cleanup", Schedule: "@hourly", Command: &config.CommandConfig{Path: "/bin/true"}},
+				schedule: "CRON_TZ=Local @hourly",
+			},
+			{
+				name:     "http",
+				job:      config.JobConfig{Name: "ping", Schedule: "1m", HTTP: &config.HTTPConfig{URL: "http://localhost/health", Assert: &config.AssertConfig{Path: "$.status", Equals: "ok"}}},
+				schedule: "@every 1m0s",
+			},
+			{
+				name:     "batch",
+				job:      config.JobConfig{Name: "resign-images", Handler: "resign-url", Schedule: "1h", Batch: &config.BatchConfig{Prefix: "images/", Retry: &config.RetryConfig{MaxAttempts: 2}}},
+				schedule: "@every 1h0m0s",
+			},
+			{
+				name: "graph",
+				job: config.JobConfig{Name: "sync", Schedule: "5m", Steps: []config.StepConfig{
+					{Name: "resign", Handler: "resign-url"},
+					{Name: "notify", Handler: "resign-url", After: map[string]string{"resign": "always"}},
+				}},
+				schedule: "@every 5m0s",
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				c := newTestCron(t)
+
+				if err := buildJobs(c, []config.JobConfig{tt.job}, jobKinds{}); err != nil {
+					t.Fatalf("expected nil, got %v", err)
+				}
+
+				j, err := c.Job(tt.job.Name)
+				if err != nil {
+					t.Fatalf("expected nil, got %v", err)
+				}
+				info := j.Info()
+				if info.Schedule != tt.schedule || info.Calendar != tt.job.Calendar || strings.Join(info.Tags, ",") != strings.Join(tt.job.Tags, ",") {
+					t.Errorf("expected %s with calendar %q and tags %v, got %+v", tt.schedule, tt.job.Calendar, tt.job.Tags, info)
+				}
+			})
+		}
+	})
+
+	t.Run("should not schedule disabled job", func(t *testing.T) {
+		c := newTestCron(t)
+
+		if err := buildJobs(c, []config.JobConfig{{Name: "resign", Handler: "resign-url", Schedule: "10s", Enabled: &disabled}}, jobKinds{}); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if jobs := c.Jobs(); len(jobs) != 0 {
+			t.Errorf("expected no job, got %d", len(jobs))
+		}
+	})
+
+	t.Run("should reject invalid job", func(t *testing.T) {
+		valid := func(fn func(jc *config.JobConfig)) config.JobConfig {
+			jc := config.JobConfig{Name: "resign", Handler: "resign-url", Schedule: "10s"}
+			fn(&jc)
+			return jc
+		}
+
+		tests := []struct {
+			name     string
+			job      config.JobConfig
+			expected error
+			contains string
+		}{
+			{name: "unknown handler", job: valid(func(jc *config.JobConfig) { jc.Handler = "unknown" }), expected: cron_jobs.ErrHandlerNotFound},
+			{name: "invalid schedule", job: valid(func(jc *config.JobConfig) { jc.Schedule = "every day" }), expected: cron_jobs.ErrInvalidSpec},
+			{name: "unknown time zone", job: valid(func(jc *config.JobConfig) { jc.Schedule, jc.Timezone = "0 9 * * *", "Mars/Olympus" }), expected: cron_jobs.ErrInvalidSpec},
+			{name: "unknown overlap policy", job: valid(func(jc *config.JobConfig) { jc.Overlap = "sometimes" }), contains: "unknown overlap policy"},
+			{name: "unknown misfire policy", job: valid(func(jc *config.JobConfig) { jc.Misfire = "later" }), contains: "unknown misfire policy"},
+			{name: "unknown calendar", job: valid(func(jc *config.JobConfig) { jc.Calendar = "us" }), expected: cron_jobs.ErrCalendarNotFound},
+			{name: "unknown calendar policy", job: valid(func(jc *config.JobConfig) { jc.Calendar, jc.CalendarPolicy = "id", "never" }), contains: "unknown calendar policy"},
+			{name: "invalid start after", job: valid(func(jc *config.JobConfig) { jc.StartAfter = "tomorrow" }), contains: "start_after"},
+			{name: "invalid stop after", job: valid(func(jc *config.JobConfig) { jc.StopAfter = "2023-06-19" }), contains: "stop_after"},
+			{name: "invalid window", job: valid(func(jc *config.JobConfig) { jc.Windows = []string{"8-20"} }), expected: cron_jobs.ErrInvalidWindow},
+			{name: "invalid blackout", job: valid(func(jc *config.JobConfig) { jc.Blackouts = []string{"12:00-12:00"} }), expected: cron_jobs.ErrInvalidWindow},
+			{name: "invalid http url", job: valid(func(jc *config.JobConfig) { jc.HTTP = &config.HTTPConfig{URL: "ftp://localhost"} }), expected: cron_jobs.ErrInvalidHTTPSpec},
+			{name: "invalid http body", job: valid(func(jc *config.JobConfig) { jc.HTTP = &config.HTTPConfig{URL: "http://localhost", Body: "{{.Params"} }), expected: cron_jobs.ErrInvalidHTTPSpec},
+			{name: "unknown batch handler", job: valid(func(jc *config.JobConfig) { jc.Handler, jc.Batch = "unknown", &config.BatchConfig{} }), expected: cron_jobs.ErrHandlerNotFound},
+			{name: "unknown step handler", job: valid(func(jc *config.JobConfig) { jc.Steps = []config.StepConfig{{Name: "resign", Handler: "unknown"}} }), expected: cron_jobs.ErrHandlerNotFound},
+			{name: "unknown upstream step", job: valid(func(jc *config.JobConfig) {
+				jc.Steps = []config.StepConfig{{Name: "notify", Handler: "resign-url", After: map[string]string{"resign": "always"}}}
+			}), expected: cron_jobs.ErrInvalidGraph},
+			{name: "unknown edge condition", job: valid(func(jc *config.JobConfig) {
+				jc.Steps = []config.StepConfig{{Name: "resign", Handler: "resign-url"}, {Name: "notify", Handler: "resign-url", After: map[string]string{"resign": "sometimes"}}}
+			}), contains: "unknown edge condition"},
+			{name: "cyclic steps", job: valid(func(jc *config.JobConfig) {
+				jc.Steps = []config.StepConfig{
+					{Name: "resign", Handler: "resign-url", After: map[string]string{"notify": ""}},
+					{Name: "notify", Handler: "resign-url", After: map[string]string{"resign": ""}},
+				}
+			}), expected: cron_jobs.ErrInvalidGraph},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				err := buildJobs(newTestCron(t), []config.JobConfig{tt.job}, jobKinds{})
+				if err == nil || !strings.HasPrefix(err.Error(), "job resign: ") {
+					t.Fatalf("expected error of job resign, got %v", err)
+				}
+				if tt.expected != nil && !errors.Is(err, tt.expected) {
+					t.Errorf("expected %v, got %v", tt.expected, err)
+				}
+				if tt.contains != "" && !strings.Contains(err.Error(), tt.contains) {
+					t.Errorf("expected error about %s, got %v", tt.contains, err)
+				}
+			})
+		}
+	})
+
+	t.Run("should reject duplicate job name", func(t *testing.T) {
+		jc := config.JobConfig{Name: "resign", Handler: "resign-url", Schedule: "10s"}
+
+		if err := buildJobs(newTestCron(t), []config.JobConfig{jc, jc}, jobKinds{}); !errors.Is(err, cron_jobs.ErrJobExists) {
+			t.Errorf("expected %v, got %v", cron_jobs.ErrJobExists, err)
+		}
+	})
+}
+
+func TestRetryPolicy(t *testing.T) {
+	t.Run("should fall back to the default policy", func(t *testing.T) {
+		defaults := cron_jobs.DefaultRetryPolicy()
+
+		policy := retryPolicy(&config.RetryConfig{MaxAttempts: 5})
+		if policy.MaxAttempts != 5 || policy.InitialBackoff != defaults.InitialBackoff || policy.MaxBackoff != defaults.MaxBackoff {
+			t.Errorf("expected 5 attempts with default backoff, got %+v", policy)
+		}
+	})
+}
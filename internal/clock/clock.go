@@ -0,0 +1,86 @@
+package clock
+
+import (
+	"time"
+)
+
+// Clock tell the time and wait, so code depending on time can be tested without real waiting
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	Until(t time.Time) time.Duration
+	Sleep(d time.Duration)
+	// After wait for the duration then send the current time on the returned channel
+	After(d time.Duration) <-chan time.Time
+	// NewTimer create timer sending the current time on its channel after d
+	NewTimer(d time.Duration) Timer
+	// AfterFunc wait for the duration then call f in its own goroutine.
+	// The returned timer cancel the call when stopped
+	AfterFunc(d time.Duration, f func()) *time.Timer
+}
+
+// Timer is a single event timer, see time.Timer
+type Timer interface {
+	C() <-chan time.Time
+	// Stop prevent the timer from firing, returns false if it already fired or was stopped
+	Stop() bool
+}
+
+// Real is the clock of the system
+type Real struct{}
+
+var _ Clock = Real{}
+
+// New returns the system clock
+func New() Clock {
+	return Real{}
+}
+
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+func (Real) Since(t time.Time) time.Duration {
+	return time.Since(t)
+}
+
+func (Real) Until(t time.Time) time.Duration {
+	return time.Until(t)
+}
+
+func (Real) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+func (Real) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (Real) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (Real) AfterFunc(d time.Duration, f func()) *time.Timer {
+	return time.AfterFunc(d, f)
+}
+
+type realTimer struct {
+	t *time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.t.C
+}
+
+func (t realTimer) Stop() bool {
+	return t.t.Stop()
+}
+
+// OrReal returns c, or the system clock when c is nil
+func OrReal(c Clock) Clock {
+	if c == nil {
+		return Real{}
+	}
+
+	return c
+}
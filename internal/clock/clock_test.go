@@ -0,0 +1,122 @@
+package clock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vldcreation/sample-cron-go/internal/clock"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Date(2023, 6, 19, 9, 0, 0, 0, time.UTC)
+
+	t.Run("should fire timer only once advanced past its deadline", func(t *testing.T) {
+		clk := clock.NewFake(start)
+		ch := clk.After(10 * time.Second)
+
+		clk.Advance(9 * time.Second)
+		select {
+		case <-ch:
+			t.Fatalf("expected timer not to fire yet")
+		default:
+		}
+
+		clk.Advance(time.Second)
+		select {
+		case at := <-ch:
+			if expected := start.Add(10 * time.Second); !at.Equal(expected) {
+				t.Errorf("expected %s, got %s", expected, at)
+			}
+		default:
+			t.Fatalf("expected timer to fire")
+		}
+	})
+
+	t.Run("should not fire stopped timer", func(t *testing.T) {
+		clk := clock.NewFake(start)
+		timer := clk.NewTimer(time.Second)
+
+		if !timer.Stop() {
+			t.Errorf("expected pending timer to be stopped")
+		}
+
+		clk.Advance(time.Minute)
+		select {
+		case <-timer.C():
+			t.Errorf("expected stopped timer not to fire")
+		default:
+		}
+	})
+
+	t.Run("should call func unless its timer is stopped", func(t *testing.T) {
+		clk := clock.NewFake(start)
+		called := make(chan string, 2)
+
+		clk.AfterFunc(time.Second, func() { called <- "kept" })
+		clk.AfterFunc(time.Second, func() { called <- "stopped" }).Stop()
+
+		clk.Advance(time.Second)
+		select {
+		case name := <-called:
+			if name != "kept" {
+				t.Errorf("expected kept, got %s", name)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected func to be called")
+		}
+
+		select {
+		case name := <-called:
+			t.Errorf("expected stopped func not to be called, got %s", name)
+		case <-time.After(20 * time.Millisecond):
+		}
+	})
+
+	t.Run("should remove func timer once stopped", func(t *testing.T) {
+		clk := clock.NewFake(start)
+
+		kept := clk.AfterFunc(time.Second, func() {})
+		stopped := clk.AfterFunc(time.Second, func() {})
+		if n := clk.Waiters(); n != 2 {
+			t.Fatalf("expected 2 pending timers, got %d", n)
+		}
+
+		if !stopped.Stop() {
+			t.Errorf("expected pending timer to be stopped")
+		}
+		if n := clk.Waiters(); n != 1 {
+			t.Errorf("expected 1 pending timer, got %d", n)
+		}
+		if stopped.Stop() {
+			t.Errorf("expected stopped timer to stay stopped")
+		}
+
+		kept.Stop()
+		if n := clk.Waiters(); n != 0 {
+			t.Errorf("expected no pending timer, got %d", n)
+		}
+	})
+
+	t.Run("should block until timers are pending", func(t *testing.T) {
+		clk := clock.NewFake(start)
+		done := make(chan struct{})
+
+		go func() {
+			clk.Sleep(time.Minute)
+			close(done)
+		}()
+
+		clk.BlockUntil(1)
+		clk.Advance(time.Minute)
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("expected sleep to return")
+		}
+
+		if got := clk.Since(start); got != time.Minute {
+			t.Errorf("expected 1m0s, got %s", got)
+		}
+	})
+}
@@ -0,0 +1,204 @@
+package clock
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Fake is a clock only moving when told to, timers fire as Advance or Set pass their deadline
+type Fake struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*waiter
+}
+
+var _ Clock = (*Fake)(nil)
+
+type waiter struct {
+	at time.Time
+	ch chan time.Time
+	// fn is called instead of sending on ch, guarded by the placeholder timer
+	fn          func()
+	placeholder *time.Timer
+}
+
+// NewFake create fake clock starting at now
+func NewFake(now time.Time) *Fake {
+	f := &Fake{now: now}
+	f.cond = sync.NewCond(&f.mu)
+
+	return f
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.now
+}
+
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+func (f *Fake) Until(t time.Time) time.Duration {
+	return t.Sub(f.Now())
+}
+
+// Sleep block until the clock is advanced by d
+func (f *Fake) Sleep(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+
+	<-f.After(d)
+}
+
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	return f.NewTimer(d).C()
+}
+
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	w := &waiter{ch: make(chan time.Time, 1)}
+	f.add(w, d)
+
+	return &fakeTimer{f: f, w: w}
+}
+
+// AfterFunc call f once the clock is advanced by d. The returned timer never fires by itself,
+// stopping it cancel the call so it can be handed to code expecting a *time.Timer.
+// A stopped timer is no longer pending once the clock looks at its timers again, see prune
+func (f *Fake) AfterFunc(d time.Duration, fn func()) *time.Timer {
+	placeholder := time.AfterFunc(math.MaxInt64, func() {})
+	f.add(&waiter{fn: fn, placeholder: placeholder}, d)
+
+	return placeholder
+}
+
+// Advance move the clock forward and fire the timers due by then, in deadline order.
+// Timers created by the fired callbacks are due from the new time and fired by the next call
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	f.now = f.now.Add(d)
+	f.fire()
+	f.mu.Unlock()
+}
+
+// Set move the clock to t, it never goes backward
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	if t.After(f.now) {
+		f.now = t
+	}
+	f.fire()
+	f.mu.Unlock()
+}
+
+// Waiters returns how many timers are pending
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.prune()
+	return len(f.waiters)
+}
+
+// BlockUntil wait until at least n timers are pending, e.g. until a job is scheduled again
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for f.prune(); len(f.waiters) < n; f.prune() {
+		f.cond.Wait()
+	}
+}
+
+func (f *Fake) add(w *waiter, d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w.at = f.now.Add(d)
+	if d <= 0 {
+		f.notify(w)
+		return
+	}
+
+	f.prune()
+	f.waiters = append(f.waiters, w)
+	f.cond.Broadcast()
+}
+
+// prune remove the waiters of AfterFunc timers stopped by their owner, caller must hold f.mu.
+// A *time.Timer does not tell the clock it was stopped, so its placeholder is probed instead:
+// reset reports whether it was still pending, a stopped one is stopped again.
+func (f *Fake) prune() {
+	kept := f.waiters[:0]
+	for _, w := range f.waiters {
+		if w.fn != nil && !w.placeholder.Reset(math.MaxInt64) {
+			w.placeholder.Stop()
+			continue
+		}
+		kept = append(kept, w)
+	}
+
+	for i := len(kept); i < len(f.waiters); i++ {
+		f.waiters[i] = nil
+	}
+	f.waiters = kept
+}
+
+// fire notify due waiters, caller must hold f.mu
+func (f *Fake) fire() {
+	sort.SliceStable(f.waiters, func(a, b int) bool {
+		return f.waiters[a].at.Before(f.waiters[b].at)
+	})
+
+	i := 0
+	for ; i < len(f.waiters) && !f.waiters[i].at.After(f.now); i++ {
+		f.notify(f.waiters[i])
+	}
+	f.waiters = f.waiters[i:]
+}
+
+// notify fire a waiter, caller must hold f.mu
+func (f *Fake) notify(w *waiter) {
+	if w.fn == nil {
+		w.ch <- f.now
+		return
+	}
+
+	// the placeholder is stopped when the timer was cancelled
+	if w.placeholder.Stop() {
+		go w.fn()
+	}
+}
+
+func (f *Fake) remove(w *waiter) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i, other := range f.waiters {
+		if other == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+type fakeTimer struct {
+	f *Fake
+	w *waiter
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.w.ch
+}
+
+func (t *fakeTimer) Stop() bool {
+	return t.f.remove(t.w)
+}
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"fmt"
+	"io"
 	"log"
 	"os"
 	"time"
@@ -9,11 +11,16 @@ import (
 )
 
 type Config struct {
-	App     AppConfig     `mapstructure:"app" yaml:"app,omitempty"`
-	Minio   MinioConfig   `mapstructure:"minio" yaml:"minio,omitempty"`
-	GCS     GCSConfig     `mapstructure:"gcs" yaml:"gcs,omitempty"`
-	Storage StorageConfig `mapstructure:"storage" yaml:"storage,omitempty"`
-	PubSub  PubSubConfig  `mapstructure:"pubsub" yaml:"pubsub,omitempty"`
+	App       AppConfig        `mapstructure:"app" yaml:"app,omitempty"`
+	Minio     MinioConfig      `mapstructure:"minio" yaml:"minio,omitempty"`
+	GCS       GCSConfig        `mapstructure:"gcs" yaml:"gcs,omitempty"`
+	Storage   StorageConfig    `mapstructure:"storage" yaml:"storage,omitempty"`
+	PubSub    PubSubConfig     `mapstructure:"pubsub" yaml:"pubsub,omitempty"`
+	Tracing   TracingConfig    `mapstructure:"tracing" yaml:"tracing,omitempty"`
+	Calendars []CalendarConfig `mapstructure:"calendars" yaml:"calendars,omitempty"`
+	Jobs      []JobConfig      `mapstructure:"jobs" yaml:"jobs,omitempty"`
+	Crontab   CrontabConfig    `mapstructure:"crontab" yaml:"crontab,omitempty"`
+	History   HistoryConfig    `mapstructure:"history" yaml:"history,omitempty"`
 }
 
 func NewAppConfig() *Config {
@@ -65,6 +72,23 @@ func NewAppConfig() *Config {
 
 }
 
+// Parse decode YAML config in the format of the config file
+func Parse(r io.Reader) (*Config, error) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+
+	if err := v.ReadConfig(r); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+
+	var config Config
+	if err := v.Unmarshal(&config); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+
+	return &config, nil
+}
+
 type AppConfig struct {
 	APP_ENV  string `mapstructure:"app_env" yaml:"app_env,omitempty"`
 	APP_PORT string `mapstructure:"app_port" yaml:"app_port,omitempty"`
@@ -96,10 +120,176 @@ type PubSubConfig struct {
 	ProjectID    string `mapstructure:"pubsub_project_id" yaml:"pubsub_project_id" json:"pubsub_project_id"`
 	Topic        string `mapstructure:"pubsub_topic" yaml:"pubsub_topic" json:"pubsub_topic"`
 	Subscription string `mapstructure:"pubsub_subscription" yaml:"pubsub_subscription" json:"pubsub_subscription"`
+	// ControlTopic receive cron commands, empty disable them. AckTopic receive their acknowledgements
+	ControlTopic string `mapstructure:"pubsub_control_topic" yaml:"pubsub_control_topic" json:"pubsub_control_topic"`
+	AckTopic     string `mapstructure:"pubsub_ack_topic" yaml:"pubsub_ack_topic" json:"pubsub_ack_topic"`
+	// EventTopic receive job lifecycle events, empty disable them
+	EventTopic string `mapstructure:"pubsub_event_topic" yaml:"pubsub_event_topic" json:"pubsub_event_topic"`
+	// DeadLetterTopic receive runs failing after their retries, empty only store them in the bucket
+	DeadLetterTopic string `mapstructure:"pubsub_dead_letter_topic" yaml:"pubsub_dead_letter_topic" json:"pubsub_dead_letter_topic"`
 }
 
 // GeneralConfig fields for storage for switcher purpose
 type StorageConfig struct {
 	Bucket string `yaml:"storage_bucket" json:"storage_bucket"`
 	Prefix string `yaml:"storage_prefix" json:"storage_prefix"`
+	// DeadLetterBucket store runs failing after their retries, default to Bucket
+	DeadLetterBucket string `yaml:"storage_dead_letter_bucket" json:"storage_dead_letter_bucket"`
+}
+
+// TracingConfig of the OpenTelemetry exporter
+type TracingConfig struct {
+	Enabled bool `mapstructure:"enabled" yaml:"enabled" json:"enabled"`
+	// Exporter is stdout or file, file write one JSON span per line to File
+	Exporter    string  `mapstructure:"exporter" yaml:"exporter" json:"exporter"`
+	File        string  `mapstructure:"file" yaml:"file,omitempty" json:"file,omitempty"`
+	ServiceName string  `mapstructure:"service_name" yaml:"service_name,omitempty" json:"service_name,omitempty"`
+	SampleRatio float64 `mapstructure:"sample_ratio" yaml:"sample_ratio,omitempty" json:"sample_ratio,omitempty"`
+}
+
+// JobConfig declare a scheduled job running a registered handler
+type JobConfig struct {
+	Name    string `mapstructure:"name" yaml:"name" json:"name"`
+	Handler string `mapstructure:"handler" yaml:"handler" json:"handler"`
+	// Schedule is either a duration ("10s") or a cron expression ("*/5 * * * *")
+	Schedule string `mapstructure:"schedule" yaml:"schedule" json:"schedule"`
+	Timezone string `mapstructure:"timezone" yaml:"timezone,omitempty" json:"timezone,omitempty"`
+	// Params are passed to the handler, keys are case insensitive and read in lower case
+	Params  map[string]string `mapstructure:"params" yaml:"params,omitempty" json:"params,omitempty"`
+	Tags    []string          `mapstructure:"tags" yaml:"tags,omitempty" json:"tags,omitempty"`
+	Retry   *RetryConfig      `mapstructure:"retry" yaml:"retry,omitempty" json:"retry,omitempty"`
+	Breaker *BreakerConfig    `mapstructure:"breaker" yaml:"breaker,omitempty" json:"breaker,omitempty"`
+	// Command run an external command instead of a handler
+	Command *CommandConfig `mapstructure:"command" yaml:"command,omitempty" json:"command,omitempty"`
+	// HTTP send a request instead of running a handler
+	HTTP *HTTPConfig `mapstructure:"http" yaml:"http,omitempty" json:"http,omitempty"`
+	// Batch run the handler for every object under a prefix of the bucket
+	Batch        *BatchConfig `mapstructure:"batch" yaml:"batch,omitempty" json:"batch,omitempty"`
+	Overlap      string       `mapstructure:"overlap" yaml:"overlap,omitempty" json:"overlap,omitempty"`
+	Misfire      string       `mapstructure:"misfire" yaml:"misfire,omitempty" json:"misfire,omitempty"`
+	MisfireLimit int          `mapstructure:"misfire_limit" yaml:"misfire_limit,omitempty" json:"misfire_limit,omitempty"`
+	// MaxRuns retire the job after the given number of runs, zero means no limit
+	MaxRuns int `mapstructure:"max_runs" yaml:"max_runs,omitempty" json:"max_runs,omitempty"`
+	// StartAfter and StopAfter are RFC3339 timestamps bounding when the job runs
+	StartAfter string `mapstructure:"start_after" yaml:"start_after,omitempty" json:"start_after,omitempty"`
+	StopAfter  string `mapstructure:"stop_after" yaml:"stop_after,omitempty" json:"stop_after,omitempty"`
+	// Windows and Blackouts are daily periods in form "HH:MM-HH:MM" in the job time zone
+	Windows   []string `mapstructure:"windows" yaml:"windows,omitempty" json:"windows,omitempty"`
+	Blackouts []string `mapstructure:"blackouts" yaml:"blackouts,omitempty" json:"blackouts,omitempty"`
+	// Calendar is the name of a calendar the job only runs on business days of
+	Calendar string `mapstructure:"calendar" yaml:"calendar,omitempty" json:"calendar,omitempty"`
+	// CalendarPolicy is skip (default) or next_business_day
+	CalendarPolicy string `mapstructure:"calendar_policy" yaml:"calendar_policy,omitempty" json:"calendar_policy,omitempty"`
+	// Steps turn the job into a graph of handlers, Handler is then ignored
+	Steps []StepConfig `mapstructure:"steps" yaml:"steps,omitempty" json:"steps,omitempty"`
+	// Enabled default to true when omitted
+	Enabled *bool `mapstructure:"enabled" yaml:"enabled,omitempty" json:"enabled,omitempty"`
+}
+
+// CalendarConfig declare a named business day calendar shared by jobs
+type CalendarConfig struct {
+	Name string `mapstructure:"name" yaml:"name" json:"name"`
+	// File is an iCalendar (.ics) or YAML (.yaml) holiday list, reloaded on SIGHUP
+	File string `mapstructure:"file" yaml:"file,omitempty" json:"file,omitempty"`
+	// Timezone the dates are evaluated in, empty means the job time zone
+	Timezone string `mapstructure:"timezone" yaml:"timezone,omitempty" json:"timezone,omitempty"`
+	// Weekend default to saturday and sunday, the file may override it
+	Weekend []string `mapstructure:"weekend" yaml:"weekend,omitempty" json:"weekend,omitempty"`
+	// Holidays are dates in form YYYY-MM-DD, or MM-DD for every year
+	Holidays []string `mapstructure:"holidays" yaml:"holidays,omitempty" json:"holidays,omitempty"`
+}
+
+// HistoryConfig of the run history, records are kept in memory when File is empty
+type HistoryConfig struct {
+	// File keep the records in a JSON lines file so they survive restarts
+	File string `mapstructure:"file" yaml:"file,omitempty" json:"file,omitempty"`
+	// MaxRecords keep only the newest records, default to the cron default
+	MaxRecords int `mapstructure:"max_records" yaml:"max_records,omitempty" json:"max_records,omitempty"`
+	// MaxAge drop records older than it, zero means no limit
+	MaxAge time.Duration `mapstructure:"max_age" yaml:"max_age,omitempty" json:"max_age,omitempty"`
+}
+
+// CrontabConfig import the entries of a crontab file as command jobs
+type CrontabConfig struct {
+	// File is a Vixie cron style crontab, empty means none
+	File string `mapstructure:"file" yaml:"file,omitempty" json:"file,omitempty"`
+	// Reload is how often the file is checked for changes, it is also reloaded on SIGHUP
+	Reload time.Duration `mapstructure:"reload" yaml:"reload,omitempty" json:"reload,omitempty"`
+}
+
+// StepConfig is a step of a job graph
+type StepConfig struct {
+	Name    string            `mapstructure:"name" yaml:"name" json:"name"`
+	Handler string            `mapstructure:"handler" yaml:"handler" json:"handler"`
+	Params  map[string]string `mapstructure:"params" yaml:"params,omitempty" json:"params,omitempty"`
+	// After map upstream steps to the edge condition: on_success (default), on_failure or always
+	After map[string]string `mapstructure:"after" yaml:"after,omitempty" json:"after,omitempty"`
+	Retry *RetryConfig      `mapstructure:"retry" yaml:"retry,omitempty" json:"retry,omitempty"`
+}
+
+// IsEnabled reports whether the job should be scheduled
+func (j JobConfig) IsEnabled() bool {
+	return j.Enabled == nil || *j.Enabled
+}
+
+// CommandConfig of a job running an external command, its output is uploaded to the storage bucket
+type CommandConfig struct {
+	Path string   `mapstructure:"path" yaml:"path" json:"path"`
+	Args []string `mapstructure:"args" yaml:"args,omitempty" json:"args,omitempty"`
+	// Env are KEY=VALUE pairs, a list as config keys are read in lower case
+	Env []string `mapstructure:"env" yaml:"env,omitempty" json:"env,omitempty"`
+	Dir string   `mapstructure:"dir" yaml:"dir,omitempty" json:"dir,omitempty"`
+	// Timeout stop the command, KillGrace is how long it has to exit before it is killed
+	Timeout   time.Duration `mapstructure:"timeout" yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	KillGrace time.Duration `mapstructure:"kill_grace" yaml:"kill_grace,omitempty" json:"kill_grace,omitempty"`
+}
+
+// HTTPConfig of a job sending a request, the run fails when the response does not pass the checks
+type HTTPConfig struct {
+	Method string `mapstructure:"method" yaml:"method,omitempty" json:"method,omitempty"`
+	URL    string `mapstructure:"url" yaml:"url" json:"url"`
+	// Headers names are case insensitive, so reading them in lower case is fine
+	Headers map[string]string `mapstructure:"headers" yaml:"headers,omitempty" json:"headers,omitempty"`
+	// Body is a template, e.g. {"object": "{{.Params.object}}"}
+	Body    string        `mapstructure:"body" yaml:"body,omitempty" json:"body,omitempty"`
+	Timeout time.Duration `mapstructure:"timeout" yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	// ExpectStatus are the accepted status codes, empty means any 2xx
+	ExpectStatus []int `mapstructure:"expect_status" yaml:"expect_status,omitempty" json:"expect_status,omitempty"`
+	// Assert check the value at a JSON path of the response, e.g. $.data.status
+	Assert *AssertConfig `mapstructure:"assert" yaml:"assert,omitempty" json:"assert,omitempty"`
+	// Archive upload the response of every attempt to the storage bucket
+	Archive bool `mapstructure:"archive" yaml:"archive,omitempty" json:"archive,omitempty"`
+}
+
+// AssertConfig check the value at Path of a JSON response, an empty Equals only requires it to exist
+type AssertConfig struct {
+	Path   string `mapstructure:"path" yaml:"path" json:"path"`
+	Equals string `mapstructure:"equals" yaml:"equals,omitempty" json:"equals,omitempty"`
+}
+
+// BatchConfig of a job running its handler for every object under Prefix, with the "bucket" and "object" params
+type BatchConfig struct {
+	// Bucket default to the storage bucket
+	Bucket  string `mapstructure:"bucket" yaml:"bucket,omitempty" json:"bucket,omitempty"`
+	Prefix  string `mapstructure:"prefix" yaml:"prefix,omitempty" json:"prefix,omitempty"`
+	Workers int    `mapstructure:"workers" yaml:"workers,omitempty" json:"workers,omitempty"`
+	// PageSize is how many objects are listed and checkpointed at once
+	PageSize int `mapstructure:"page_size" yaml:"page_size,omitempty" json:"page_size,omitempty"`
+	// Retry every object, the retry of the job resumes the whole batch from its checkpoint
+	Retry *RetryConfig `mapstructure:"retry" yaml:"retry,omitempty" json:"retry,omitempty"`
+}
+
+// BreakerConfig suspend a job after Threshold consecutive failed runs for Cooldown
+type BreakerConfig struct {
+	Threshold int           `mapstructure:"threshold" yaml:"threshold" json:"threshold"`
+	Cooldown  time.Duration `mapstructure:"cooldown" yaml:"cooldown,omitempty" json:"cooldown,omitempty"`
+}
+
+// RetryConfig of a job, zero values fall back to the default retry policy
+type RetryConfig struct {
+	MaxAttempts    int           `mapstructure:"max_attempts" yaml:"max_attempts,omitempty" json:"max_attempts,omitempty"`
+	InitialBackoff time.Duration `mapstructure:"initial_backoff" yaml:"initial_backoff,omitempty" json:"initial_backoff,omitempty"`
+	MaxBackoff     time.Duration `mapstructure:"max_backoff" yaml:"max_backoff,omitempty" json:"max_backoff,omitempty"`
+	Multiplier     float64       `mapstructure:"multiplier" yaml:"multiplier,omitempty" json:"multiplier,omitempty"`
+	Jitter         float64       `mapstructure:"jitter" yaml:"jitter,omitempty" json:"jitter,omitempty"`
 }
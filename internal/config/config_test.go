@@ -0,0 +1,79 @@
+package config_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vldcreation/sample-cron-go/internal/config"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("should parse the sample config", func(t *testing.T) {
+		f, err := os.Open("../../config.yaml.dist")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		defer f.Close()
+
+		conf, err := config.Parse(f)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		jobs := make(map[string]config.JobConfig)
+		for _, jc := range conf.Jobs {
+			jobs[jc.Name] = jc
+		}
+
+		resign := jobs["resign-url"]
+		if resign.Schedule != "10s" || resign.Params["object"] != "sample.jpeg" || !resign.IsEnabled() {
+			t.Errorf("expected enabled resign-url every 10s, got %+v", resign)
+		}
+		if resign.Retry == nil || resign.Retry.MaxAttempts != 3 || resign.Retry.MaxBackoff != 30*time.Second {
+			t.Errorf("expected 3 attempts up to 30s backoff, got %+v", resign.Retry)
+		}
+		if resign.Breaker == nil || resign.Breaker.Cooldown != 5*time.Minute {
+			t.Errorf("expected breaker cooling down 5m, got %+v", resign.Breaker)
+		}
+
+		if batch := jobs["resign-images"]; batch.Batch == nil || batch.Batch.PageSize != 1000 || batch.IsEnabled() {
+			t.Errorf("expected disabled batch of 1000 objects per page, got %+v", batch)
+		}
+		if conf.History.MaxRecords != 1000 || conf.History.File == "" {
+			t.Errorf("expected file history of 1000 records, got %+v", conf.History)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		yaml     string
+		expected string
+	}{
+		{
+			name:     "should reject malformed yaml",
+			yaml:     "jobs: [",
+			expected: "config:",
+		},
+		{
+			name:     "should reject invalid duration",
+			yaml:     "jobs:\n  - name: resign\n    retry:\n      initial_backoff: soon\n",
+			expected: "initial_backoff",
+		},
+		{
+			name:     "should reject list instead of object",
+			yaml:     "history: [a, b]\n",
+			expected: "history",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := config.Parse(strings.NewReader(tt.yaml))
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected error about %s, got %v", tt.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,424 @@
+package cron_jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrAtDisabled    = errors.New("cron: at jobs are disabled")
+	ErrAtJobNotFound = errors.New("cron: at job not found")
+	ErrAtJobDone     = errors.New("cron: at job is no longer pending")
+)
+
+var (
+	// DefaultAtPollInterval is how often the store is checked for jobs added by other instances
+	DefaultAtPollInterval = time.Second * 10
+	// DefaultAtRetention is how long finished at jobs are kept for listing
+	DefaultAtRetention = time.Hour * 24 * 7
+)
+
+// AtStatus is the state of a one-shot job
+type AtStatus string
+
+const (
+	AtPending   AtStatus = "pending"
+	AtRunning   AtStatus = "running"
+	AtSucceeded AtStatus = "succeeded"
+	AtFailed    AtStatus = "failed"
+	AtCancelled AtStatus = "cancelled"
+)
+
+// AtJob is a handler run once at an absolute time.
+// The instance claiming the job renews its claim every third of the lock ttl while it runs,
+// a claim not renewed by then, e.g. the owner died, goes back to pending and the job runs again.
+type AtJob struct {
+	ID      string            `json:"id"`
+	Handler string            `json:"handler"`
+	Params  map[string]string `json:"params,omitempty"`
+	RunAt   time.Time         `json:"run_at"`
+	Status  AtStatus          `json:"status"`
+	// Owner is the instance which claimed the job
+	Owner string `json:"owner,omitempty"`
+	RunID string `json:"run_id,omitempty"`
+	// ClaimedUntil is when the claim of the owner expires unless renewed
+	ClaimedUntil time.Time `json:"claimed_until,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	StartedAt    time.Time `json:"started_at"`
+	FinishedAt   time.Time `json:"finished_at"`
+	Error        string    `json:"error,omitempty"`
+}
+
+// name is the job name of the run in history, events and metrics,
+// it is shared by the jobs of a handler so the run id tells them apart
+func (aj AtJob) name() string {
+	return "at-" + aj.Handler
+}
+
+// claimExpired reports whether the job is running and its owner did not renew the claim in time
+func (aj AtJob) claimExpired(now time.Time) bool {
+	return aj.Status == AtRunning && aj.ClaimedUntil.Before(now)
+}
+
+// AtStore persist at jobs, instances sharing the store run every job once
+type AtStore interface {
+	// Load returns every job keyed by id
+	Load(ctx context.Context) (map[string]*AtJob, error)
+	// Update atomically apply fn to the jobs keyed by id, fn may add, change or delete jobs.
+	// fn may be called again when the jobs were changed concurrently, the error of fn is returned
+	Update(ctx context.Context, fn func(jobs map[string]*AtJob) error) error
+}
+
+// atRunner wake the loop running due at jobs
+type atRunner struct {
+	owner string
+	once  sync.Once
+	wake  chan struct{}
+}
+
+// At schedule handler to run once at runAt with params, a time in the past runs as soon as possible.
+// The job is persisted and survives restarts
+func (c *Cron) At(ctx context.Context, runAt time.Time, handler string, params map[string]string) (AtJob, error) {
+	if c.opts.AtStore == nil {
+		return AtJob{}, ErrAtDisabled
+	}
+	if runAt.IsZero() {
+		return AtJob{}, fmt.Errorf("%w: missing run time", ErrInvalidSpec)
+	}
+	if _, err := c.Handler(handler); err != nil {
+		return AtJob{}, err
+	}
+
+	aj := AtJob{
+		ID:        uuid.NewString(),
+		Handler:   handler,
+		Params:    params,
+		RunAt:     runAt,
+		Status:    AtPending,
+		CreatedAt: c.clock.Now(),
+	}
+
+	if err := c.opts.AtStore.Update(ctx, func(jobs map[string]*AtJob) error {
+		job := aj
+		jobs[aj.ID] = &job
+		return nil
+	}); err != nil {
+		return AtJob{}, err
+	}
+
+	log.Printf("cron: at job %s of handler %s scheduled at %s\n", aj.ID, handler, runAt.Format(time.RFC3339))
+	c.wakeAt()
+
+	return aj, nil
+}
+
+// AtJobs returns every at job kept by the store, ordered by run time
+func (c *Cron) AtJobs(ctx context.Context) ([]AtJob, error) {
+	if c.opts.AtStore == nil {
+		return nil, ErrAtDisabled
+	}
+
+	jobs, err := c.opts.AtStore.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]AtJob, 0, len(jobs))
+	for _, aj := range jobs {
+		list = append(list, *aj)
+	}
+	sort.Slice(list, func(a, b int) bool {
+		if !list[a].RunAt.Equal(list[b].RunAt) {
+			return list[a].RunAt.Before(list[b].RunAt)
+		}
+		return list[a].ID < list[b].ID
+	})
+
+	return list, nil
+}
+
+// AtJob returns the at job with the given id
+func (c *Cron) AtJob(ctx context.Context, id string) (AtJob, error) {
+	if c.opts.AtStore == nil {
+		return AtJob{}, ErrAtDisabled
+	}
+
+	jobs, err := c.opts.AtStore.Load(ctx)
+	if err != nil {
+		return AtJob{}, err
+	}
+
+	aj, ok := jobs[id]
+	if !ok {
+		return AtJob{}, fmt.Errorf("%w: %s", ErrAtJobNotFound, id)
+	}
+
+	return *aj, nil
+}
+
+// CancelAt cancel a pending at job, returns ErrAtJobDone once it was claimed by an instance
+func (c *Cron) CancelAt(ctx context.Context, id string) (AtJob, error) {
+	if c.opts.AtStore == nil {
+		return AtJob{}, ErrAtDisabled
+	}
+
+	var cancelled AtJob
+	err := c.opts.AtStore.Update(ctx, func(jobs map[string]*AtJob) error {
+		aj, ok := jobs[id]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrAtJobNotFound, id)
+		}
+		if aj.Status != AtPending {
+			return fmt.Errorf("%w: %s is %s", ErrAtJobDone, id, aj.Status)
+		}
+
+		aj.Status = AtCancelled
+		aj.FinishedAt = c.clock.Now()
+		cancelled = *aj
+		return nil
+	})
+	if err != nil {
+		return AtJob{}, err
+	}
+
+	log.Printf("cron: at job %s cancelled\n", id)
+
+	return cancelled, nil
+}
+
+// startAt start the loop running due at jobs, until the scheduler stops
+func (c *Cron) startAt() {
+	if c.opts.AtStore == nil {
+		return
+	}
+
+	c.at.once.Do(func() {
+		go c.watchAt()
+	})
+}
+
+// wakeAt make the loop check the store now, e.g. after a job was added
+func (c *Cron) wakeAt() {
+	select {
+	case c.at.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (c *Cron) watchAt() {
+	interval := c.opts.AtPollInterval
+	if interval <= 0 {
+		interval = DefaultAtPollInterval
+	}
+
+	for {
+		wait := interval
+		next, err := c.runDueAt()
+		if err != nil {
+			log.Printf("cron: failed to run at jobs: %v\n", err)
+		} else if !next.IsZero() {
+			if d := next.Sub(c.clock.Now()); d < wait {
+				wait = d
+			}
+		}
+
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-c.at.wake:
+		case <-c.clock.After(wait):
+		}
+	}
+}
+
+// runDueAt claim the due pending jobs, including the ones whose claim expired, and run them
+// in background, returns the run time of the next pending job not due yet
+func (c *Cron) runDueAt() (time.Time, error) {
+	var (
+		claimed   []AtJob
+		reclaimed []AtJob
+		next      time.Time
+	)
+
+	err := c.opts.AtStore.Update(c.ctx, func(jobs map[string]*AtJob) error {
+		claimed, reclaimed, next = nil, nil, time.Time{}
+		now := c.clock.Now()
+		changed := false
+
+		for id, aj := range jobs {
+			if aj.claimExpired(now) {
+				reclaimed = append(reclaimed, *aj)
+				aj.Status = AtPending
+				aj.Owner, aj.RunID, aj.ClaimedUntil = "", "", time.Time{}
+				changed = true
+			}
+
+			switch {
+			case aj.Status == AtPending && !aj.RunAt.After(now):
+				aj.Status = AtRunning
+				aj.Owner = c.at.owner
+				aj.RunID = uuid.NewString()
+				aj.StartedAt = now
+				aj.ClaimedUntil = now.Add(c.lockTTL())
+				claimed = append(claimed, *aj)
+				changed = true
+			case aj.Status == AtPending:
+				if next.IsZero() || aj.RunAt.Before(next) {
+					next = aj.RunAt
+				}
+			case aj.Status != AtRunning && now.Sub(aj.FinishedAt) > DefaultAtRetention:
+				delete(jobs, id)
+				changed = true
+			}
+		}
+
+		if !changed {
+			return errAtUnchanged
+		}
+		return nil
+	})
+	if errors.Is(err, errAtUnchanged) {
+		err = nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	for _, aj := range reclaimed {
+		log.Printf("cron: at job %s claimed by %s expired at %s, running it again\n", aj.ID, aj.Owner, aj.ClaimedUntil.Format(time.RFC3339))
+	}
+
+	sort.Slice(claimed, func(a, b int) bool {
+		return claimed[a].RunAt.Before(claimed[b].RunAt)
+	})
+	for _, aj := range claimed {
+		go c.runAtJob(aj)
+	}
+
+	return next, nil
+}
+
+// errAtUnchanged tell the store there is nothing to write
+var errAtUnchanged = errors.New("cron: at jobs unchanged")
+
+// runAtJob execute the claimed job through the run pipeline then store its result.
+// A job which did not start, e.g. the scheduler is stopping, is released to run later
+func (c *Cron) runAtJob(aj AtJob) {
+	var (
+		started bool
+		runErr  error
+	)
+
+	// shutdown waits for the result to be stored
+	tracked := c.track()
+	if tracked {
+		defer c.wg.Done()
+	}
+
+	fn, err := c.Handler(aj.Handler)
+	switch {
+	case !tracked:
+	case err == nil:
+		cmd := func(ctx context.Context) error {
+			started = true
+			runErr = invoke(ctx, fn)
+			return runErr
+		}
+
+		j := c.handlerJob(aj.name(), aj.Handler, cmd)
+		stop := c.renewAtClaim(aj)
+		c.execute(j, runRequest{ID: aj.RunID, ScheduledAt: aj.RunAt, Params: aj.Params, Manual: true, Claimed: true})
+		stop()
+	default:
+		started, runErr = true, err
+	}
+
+	// the scheduler context may already be cancelled, the result should still be stored
+	err = c.opts.AtStore.Update(context.Background(), func(jobs map[string]*AtJob) error {
+		cur, ok := jobs[aj.ID]
+		if !ok || cur.Owner != c.at.owner || cur.RunID != aj.RunID {
+			return fmt.Errorf("%w: %s", ErrAtJobNotFound, aj.ID)
+		}
+
+		cur.ClaimedUntil = time.Time{}
+		if !started {
+			cur.Status = AtPending
+			cur.Owner, cur.RunID, cur.StartedAt = "", "", time.Time{}
+			return nil
+		}
+
+		cur.Status = AtSucceeded
+		cur.FinishedAt = c.clock.Now()
+		if runErr != nil {
+			cur.Status = AtFailed
+			cur.Error = runErr.Error()
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("cron: failed to save result of at job %s: %v\n", aj.ID, err)
+	}
+}
+
+// handlerJob returns the unscheduled job running cmd of handler once, e.g. an at job
+func (c *Cron) handlerJob(name, handler string, cmd JobFunc) *Job {
+	return &Job{
+		name:    name,
+		handler: handler,
+		cmd:     cmd,
+		cfg:     newJobConfig(WithName(name)),
+		overlap: newOverlapGuard(OverlapAllow),
+		loc:     c.opts.Location,
+		clock:   c.clock,
+	}
+}
+
+// renewAtClaim extend the claim of the running job every third of the lock ttl,
+// the returned stop function ends the renewal
+func (c *Cron) renewAtClaim(aj AtJob) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		for {
+			timer := c.clock.NewTimer(c.lockTTL() / 3)
+			select {
+			case <-done:
+				timer.Stop()
+				return
+			case <-timer.C():
+			}
+
+			err := c.opts.AtStore.Update(context.Background(), func(jobs map[string]*AtJob) error {
+				cur, ok := jobs[aj.ID]
+				if !ok || cur.Owner != c.at.owner || cur.RunID != aj.RunID {
+					return fmt.Errorf("%w: %s", ErrAtJobNotFound, aj.ID)
+				}
+
+				cur.ClaimedUntil = c.clock.Now().Add(c.lockTTL())
+				return nil
+			})
+			if errors.Is(err, ErrAtJobNotFound) {
+				log.Printf("cron: at job %s lost its claim of run %s\n", aj.ID, aj.RunID)
+				return
+			}
+			if err != nil {
+				log.Printf("cron: failed to renew claim of at job %s: %v\n", aj.ID, err)
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
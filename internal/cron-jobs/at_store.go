@@ -0,0 +1,164 @@
+package cron_jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/vldcreation/sample-cron-go/internal/storage"
+)
+
+// compile-time interface check
+var (
+	_ AtStore = (*FileAtStore)(nil)
+	_ AtStore = (*StorageAtStore)(nil)
+)
+
+// atCASRetries is how many times a conflicting write is retried, every instance polls the same object
+const atCASRetries = 10
+
+// atJobsData is the persisted list of at jobs
+type atJobsData struct {
+	Jobs map[string]*AtJob `json:"jobs"`
+}
+
+func decodeAtJobs(b []byte) (map[string]*AtJob, error) {
+	var data atJobsData
+	if len(b) > 0 {
+		if err := json.Unmarshal(b, &data); err != nil {
+			return nil, err
+		}
+	}
+	if data.Jobs == nil {
+		data.Jobs = make(map[string]*AtJob)
+	}
+
+	return data.Jobs, nil
+}
+
+// FileAtStore keep at jobs in a local JSON file, for a single instance
+type FileAtStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileAtStore returns store of the at jobs in the file at path, created on first write
+func NewFileAtStore(path string) *FileAtStore {
+	return &FileAtStore{path: path}
+}
+
+func (s *FileAtStore) Load(ctx context.Context) (map[string]*AtJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.load()
+}
+
+func (s *FileAtStore) Update(ctx context.Context, fn func(jobs map[string]*AtJob) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(jobs); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(atJobsData{Jobs: jobs}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("at jobs: %w", err)
+	}
+	if err := writeFileAtomic(s.path, b); err != nil {
+		return fmt.Errorf("at jobs: %w", err)
+	}
+
+	return nil
+}
+
+// load read the file, caller must hold s.mu
+func (s *FileAtStore) load() (map[string]*AtJob, error) {
+	b, err := os.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("at jobs: %w", err)
+	}
+
+	jobs, err := decodeAtJobs(b)
+	if err != nil {
+		return nil, fmt.Errorf("at jobs: %s: %w", s.path, err)
+	}
+
+	return jobs, nil
+}
+
+// StorageAtStore keep at jobs in a single JSON object updated with conditional writes,
+// so instances sharing the bucket claim every job once
+type StorageAtStore struct {
+	st     storage.Storage
+	bucket string
+	name   string
+}
+
+// NewStorageAtStore returns store of the at jobs in the object name of bucket
+func NewStorageAtStore(st storage.Storage, bucket, name string) *StorageAtStore {
+	return &StorageAtStore{st: st, bucket: bucket, name: name}
+}
+
+func (s *StorageAtStore) Load(ctx context.Context) (map[string]*AtJob, error) {
+	jobs, _, err := s.read(ctx)
+	return jobs, err
+}
+
+func (s *StorageAtStore) Update(ctx context.Context, fn func(jobs map[string]*AtJob) error) error {
+	for i := 0; i < atCASRetries; i++ {
+		jobs, version, err := s.read(ctx)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(jobs); err != nil {
+			return err
+		}
+
+		b, err := json.Marshal(atJobsData{Jobs: jobs})
+		if err != nil {
+			return fmt.Errorf("at jobs: %w", err)
+		}
+
+		_, err = s.st.PutIfMatch(ctx, s.bucket, s.name, b, "application/json", version)
+		if errors.Is(err, storage.ErrPreconditionFailed) {
+			// changed by other instance, evaluate again
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("at jobs: %w", err)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("at jobs: too many concurrent updates of %s", s.name)
+}
+
+// read returns the jobs and the version of the object, empty when it does not exist yet
+func (s *StorageAtStore) read(ctx context.Context) (map[string]*AtJob, string, error) {
+	data, info, err := s.st.Read(ctx, s.bucket, s.name)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return make(map[string]*AtJob), "", nil
+	case err != nil:
+		return nil, "", fmt.Errorf("at jobs: %w", err)
+	}
+
+	jobs, err := decodeAtJobs(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("at jobs: %s: %w", s.name, err)
+	}
+
+	return jobs, info.Version, nil
+}
@@ -0,0 +1,208 @@
+package cron_jobs_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	cron_jobs "github.com/vldcreation/sample-cron-go/internal/cron-jobs"
+)
+
+// waitAtJob returns the at job once it has the expected status
+func waitAtJob(t *testing.T, c *cron_jobs.Cron, id string, status cron_jobs.AtStatus) cron_jobs.AtJob {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		aj, err := c.AtJob(context.Background(), id)
+		if err == nil && aj.Status == status {
+			return aj
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected at job %s %s, got %+v (%v)", id, status, aj, err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestAtJobs(t *testing.T) {
+	t.Run("should run job once at its time across instances", func(t *testing.T) {
+		st := &memStorage{objects: make(map[string][]byte)}
+
+		var (
+			mu      sync.Mutex
+			objects []string
+		)
+		replica := func() *cron_jobs.Cron {
+			c := cron_jobs.NewCron(
+				cron_jobs.WithAtStore(cron_jobs.NewStorageAtStore(st, "bucket", "cron-at-jobs.json")),
+				cron_jobs.WithAtPollInterval(10*time.Millisecond),
+			)
+			if err := c.RegisterHandler("delete-object", func(ctx context.Context) error {
+				mu.Lock()
+				defer mu.Unlock()
+				objects = append(objects, cron_jobs.Param(ctx, "object", ""))
+				return nil
+			}); err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+			return c
+		}
+
+		replicas := []*cron_jobs.Cron{replica(), replica(), replica()}
+		for _, c := range replicas {
+			c.StartAsync()
+			defer c.Stop()
+		}
+
+		runAt := time.Now().Add(100 * time.Millisecond)
+		aj, err := replicas[0].At(context.Background(), runAt, "delete-object", map[string]string{"object": "a.jpeg"})
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		// listed by every replica
+		if got, err := replicas[2].AtJob(context.Background(), aj.ID); err != nil || got.Status != cron_jobs.AtPending {
+			t.Errorf("expected pending at job, got %+v (%v)", got, err)
+		}
+
+		done := waitAtJob(t, replicas[1], aj.ID, cron_jobs.AtSucceeded)
+		if done.StartedAt.Before(runAt) {
+			t.Errorf("expected job started after %s, got %s", runAt, done.StartedAt)
+		}
+
+		// let the other replicas poll again
+		time.Sleep(50 * time.Millisecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if len(objects) != 1 || objects[0] != "a.jpeg" {
+			t.Errorf("expected a.jpeg deleted once, got %v", objects)
+		}
+
+		var records []cron_jobs.RunRecord
+		for _, c := range replicas {
+			recs, _ := c.History().Query(context.Background(), cron_jobs.HistoryQuery{RunID: done.RunID})
+			records = append(records, recs...)
+		}
+		if len(records) != 1 || records[0].Job != "at-delete-object" || records[0].Status != cron_jobs.RunSuccess {
+			t.Errorf("expected 1 successful record, got %+v", records)
+		}
+	})
+
+	t.Run("should keep pending job across restarts until cancelled", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "at_jobs.json")
+		newCron := func() *cron_jobs.Cron {
+			c := cron_jobs.NewCron(cron_jobs.WithAtStore(cron_jobs.NewFileAtStore(path)))
+			c.RegisterHandler("delete-object", func(ctx context.Context) error { return nil })
+			return c
+		}
+
+		wib := time.FixedZone("WIB", 7*60*60)
+		runAt := time.Date(2026, 11, 1, 9, 0, 0, 0, wib)
+
+		c := newCron()
+		c.StartAsync()
+		aj, err := c.At(context.Background(), runAt, "delete-object", nil)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		c.Stop()
+
+		c = newCron()
+		c.StartAsync()
+		defer c.Stop()
+
+		jobs, err := c.AtJobs(context.Background())
+		if err != nil || len(jobs) != 1 || jobs[0].ID != aj.ID || jobs[0].Status != cron_jobs.AtPending || !jobs[0].RunAt.Equal(runAt) {
+			t.Fatalf("expected pending job %s at %s, got %+v (%v)", aj.ID, runAt, jobs, err)
+		}
+
+		cancelled, err := c.CancelAt(context.Background(), aj.ID)
+		if err != nil || cancelled.Status != cron_jobs.AtCancelled {
+			t.Fatalf("expected cancelled job, got %+v (%v)", cancelled, err)
+		}
+
+		if _, err := c.CancelAt(context.Background(), aj.ID); !errors.Is(err, cron_jobs.ErrAtJobDone) {
+			t.Errorf("expected %v, got %v", cron_jobs.ErrAtJobDone, err)
+		}
+		if _, err := c.CancelAt(context.Background(), "unknown"); !errors.Is(err, cron_jobs.ErrAtJobNotFound) {
+			t.Errorf("expected %v, got %v", cron_jobs.ErrAtJobNotFound, err)
+		}
+	})
+
+	t.Run("should record failure of the handler", func(t *testing.T) {
+		c := cron_jobs.NewCron(
+			cron_jobs.WithAtStore(cron_jobs.NewFileAtStore(filepath.Join(t.TempDir(), "at_jobs.json"))),
+		)
+		c.RegisterHandler("delete-object", func(ctx context.Context) error { return errors.New("object locked") })
+		c.StartAsync()
+		defer c.Stop()
+
+		aj, err := c.At(context.Background(), time.Now(), "delete-object", nil)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		if failed := waitAtJob(t, c, aj.ID, cron_jobs.AtFailed); failed.Error != "object locked" {
+			t.Errorf("expected object locked, got %q", failed.Error)
+		}
+	})
+
+	t.Run("should run again job claimed by a dead instance", func(t *testing.T) {
+		ctx := context.Background()
+		store := cron_jobs.NewFileAtStore(filepath.Join(t.TempDir(), "at_jobs.json"))
+		now := time.Now()
+
+		// dead stopped renewing its claim, alive is still running its job
+		if err := store.Update(ctx, func(jobs map[string]*cron_jobs.AtJob) error {
+			jobs["dead"] = &cron_jobs.AtJob{ID: "dead", Handler: "delete-object", RunAt: now.Add(-time.Hour), Status: cron_jobs.AtRunning,
+				Owner: "replica-dead", RunID: "run-dead", StartedAt: now.Add(-time.Hour), ClaimedUntil: now.Add(-time.Minute)}
+			jobs["alive"] = &cron_jobs.AtJob{ID: "alive", Handler: "delete-object", RunAt: now.Add(-time.Hour), Status: cron_jobs.AtRunning,
+				Owner: "replica-alive", RunID: "run-alive", StartedAt: now.Add(-time.Hour), ClaimedUntil: now.Add(time.Minute)}
+			return nil
+		}); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		ran := make(chan string, 2)
+		c := cron_jobs.NewCron(cron_jobs.WithAtStore(store), cron_jobs.WithAtPollInterval(10*time.Millisecond))
+		c.RegisterHandler("delete-object", func(ctx context.Context) error {
+			run, _ := cron_jobs.RunFromContext(ctx)
+			ran <- run.ID
+			return nil
+		})
+		c.StartAsync()
+		defer c.Stop()
+
+		done := waitAtJob(t, c, "dead", cron_jobs.AtSucceeded)
+		if done.RunID == "run-dead" || done.Owner == "replica-dead" {
+			t.Errorf("expected job claimed again, got %+v", done)
+		}
+		if got := waitString(t, ran); got != done.RunID {
+			t.Errorf("expected run %s, got %s", done.RunID, got)
+		}
+		expectNothing(t, ran)
+
+		if alive, _ := c.AtJob(ctx, "alive"); alive.Status != cron_jobs.AtRunning || alive.RunID != "run-alive" {
+			t.Errorf("expected job of the live instance left running, got %+v", alive)
+		}
+	})
+
+	t.Run("should reject invalid job", func(t *testing.T) {
+		if _, err := cron_jobs.NewCron().At(context.Background(), time.Now(), "delete-object", nil); !errors.Is(err, cron_jobs.ErrAtDisabled) {
+			t.Errorf("expected %v, got %v", cron_jobs.ErrAtDisabled, err)
+		}
+
+		c := cron_jobs.NewCron(cron_jobs.WithAtStore(cron_jobs.NewFileAtStore(filepath.Join(t.TempDir(), "at_jobs.json"))))
+		if _, err := c.At(context.Background(), time.Now(), "unknown", nil); !errors.Is(err, cron_jobs.ErrHandlerNotFound) {
+			t.Errorf("expected %v, got %v", cron_jobs.ErrHandlerNotFound, err)
+		}
+		if _, err := c.At(context.Background(), time.Time{}, "unknown", nil); !errors.Is(err, cron_jobs.ErrInvalidSpec) {
+			t.Errorf("expected %v, got %v", cron_jobs.ErrInvalidSpec, err)
+		}
+	})
+}
@@ -0,0 +1,342 @@
+package cron_jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vldcreation/sample-cron-go/internal/clock"
+	"github.com/vldcreation/sample-cron-go/internal/storage"
+)
+
+var ErrBatchFailed = errors.New("cron: batch failed")
+
+var (
+	// DefaultBatchWorkers is how many objects are processed concurrently
+	DefaultBatchWorkers = 8
+	// DefaultBatchPageSize is how many objects are listed at once, progress is checkpointed after every page
+	DefaultBatchPageSize = 1000
+	// DefaultBatchPrefix is where checkpoints and reports are stored
+	DefaultBatchPrefix = "cron-batches"
+	// MaxBatchFailures is how many failed objects are listed in the report, the others are only counted
+	MaxBatchFailures = 1000
+)
+
+// BatchSpec describe the objects processed by a batch job
+type BatchSpec struct {
+	Bucket string
+	Prefix string
+	// Workers is how many objects are processed concurrently, default to DefaultBatchWorkers
+	Workers int
+	// PageSize is how many objects are listed and checkpointed at once, default to DefaultBatchPageSize
+	PageSize int
+	// Retry every object, the zero value means no retry
+	Retry RetryPolicy
+}
+
+// BatchFailure is an object which still failed after its retries
+type BatchFailure struct {
+	Object   string `json:"object"`
+	Attempts int    `json:"attempts"`
+	Error    string `json:"error"`
+}
+
+// BatchProgress is the state of a batch, checkpointed after every page
+type BatchProgress struct {
+	Job    string `json:"job"`
+	RunID  string `json:"run_id"`
+	Bucket string `json:"bucket"`
+	Prefix string `json:"prefix"`
+	// After is the last object of the last completed page, an interrupted batch resumes after it
+	After     string `json:"after"`
+	Processed int    `json:"processed"`
+	Succeeded int    `json:"succeeded"`
+	Failed    int    `json:"failed"`
+	// Failures are the first MaxBatchFailures failed objects
+	Failures []BatchFailure `json:"failures,omitempty"`
+	// ResumedBy are the runs which resumed the batch after it was interrupted
+	ResumedBy []string  `json:"resumed_by,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// BatchReport is written once every object was processed
+type BatchReport struct {
+	BatchProgress
+	FinishedAt time.Time     `json:"finished_at"`
+	Duration   time.Duration `json:"duration"`
+}
+
+// BatchOption configure a batch job
+type BatchOption func(bj *batchJob)
+
+// WithBatchState store checkpoints and reports under prefix/job/ in bucket,
+// default to DefaultBatchPrefix in the bucket of the batch
+func WithBatchState(bucket, prefix string) BatchOption {
+	return func(bj *batchJob) {
+		bj.stateBucket = bucket
+		bj.statePrefix = prefix
+	}
+}
+
+// WithBatchClock set the clock telling the time of progress and reports and measuring the backoff
+// of the object retries, default to the system clock
+func WithBatchClock(clk clock.Clock) BatchOption {
+	return func(bj *batchJob) {
+		bj.clock = clk
+	}
+}
+
+// WithBatchProgress call fn with the progress after every page
+func WithBatchProgress(fn func(BatchProgress)) BatchOption {
+	return func(bj *batchJob) {
+		bj.progress = fn
+	}
+}
+
+type batchJob struct {
+	st   storage.Storage
+	spec BatchSpec
+	fn   JobFunc
+
+	stateBucket string
+	statePrefix string
+	progress    func(BatchProgress)
+	clock       clock.Clock
+}
+
+type batchObjectKey struct{}
+
+// BatchObject returns the object processed by the batch item
+func BatchObject(ctx context.Context) (*storage.ObjectInfo, bool) {
+	obj, ok := ctx.Value(batchObjectKey{}).(*storage.ObjectInfo)
+	return obj, ok
+}
+
+// NewBatchJob returns job function calling fn for every object under the prefix of the bucket,
+// with the bucket and object name in the "bucket" and "object" params. A run interrupted before
+// the end is resumed by the next one from its last checkpoint, a page may then be processed twice.
+// Once done a BatchReport is written, the run fails with ErrBatchFailed when an object failed
+func NewBatchJob(st storage.Storage, spec BatchSpec, fn JobFunc, opts ...BatchOption) JobFunc {
+	bj := &batchJob{st: st, spec: spec, fn: fn, statePrefix: DefaultBatchPrefix, clock: clock.New()}
+	for _, opt := range opts {
+		opt(bj)
+	}
+
+	if bj.spec.Workers <= 0 {
+		bj.spec.Workers = DefaultBatchWorkers
+	}
+	if bj.spec.PageSize <= 0 {
+		bj.spec.PageSize = DefaultBatchPageSize
+	}
+	if bj.stateBucket == "" {
+		bj.stateBucket = bj.spec.Bucket
+	}
+	if bj.spec.Retry.Clock == nil {
+		bj.spec.Retry.Clock = bj.clock
+	}
+
+	return bj.run
+}
+
+func (bj *batchJob) run(ctx context.Context) error {
+	run, _ := RunFromContext(ctx)
+
+	prog, err := bj.resume(ctx, run)
+	if err != nil {
+		return err
+	}
+
+	for {
+		page, err := bj.st.List(ctx, bj.spec.Bucket, bj.spec.Prefix, prog.After, bj.spec.PageSize)
+		if err != nil {
+			return fmt.Errorf("batch %s: list: %w", run.Job, err)
+		}
+		if len(page) == 0 {
+			break
+		}
+
+		bj.processPage(ctx, page, prog)
+		if err := ctx.Err(); err != nil {
+			// the page is processed again on resume
+			return fmt.Errorf("batch %s interrupted after %d objects: %w", run.Job, prog.Processed, err)
+		}
+
+		prog.After = page[len(page)-1].Name
+		prog.UpdatedAt = bj.clock.Now()
+		if err := bj.save(ctx, bj.checkpointName(run.Job), prog); err != nil {
+			return fmt.Errorf("batch %s: checkpoint: %w", run.Job, err)
+		}
+
+		log.Printf("cron: batch %s: %d object(s) processed, %d failed\n", run.Job, prog.Processed, prog.Failed)
+		if bj.progress != nil {
+			bj.progress(*prog)
+		}
+
+		if len(page) < bj.spec.PageSize {
+			break
+		}
+	}
+
+	return bj.finish(run, prog)
+}
+
+// resume load the checkpoint of an interrupted batch, or start a new one
+func (bj *batchJob) resume(ctx context.Context, run RunInfo) (*BatchProgress, error) {
+	now := bj.clock.Now()
+	prog := &BatchProgress{
+		Job:       run.Job,
+		RunID:     run.ID,
+		Bucket:    bj.spec.Bucket,
+		Prefix:    bj.spec.Prefix,
+		StartedAt: now,
+		UpdatedAt: now,
+	}
+
+	data, _, err := bj.st.Read(ctx, bj.stateBucket, bj.checkpointName(run.Job))
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return prog, nil
+	case err != nil:
+		return nil, fmt.Errorf("batch %s: checkpoint: %w", run.Job, err)
+	}
+
+	var cp BatchProgress
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("batch %s: checkpoint: %w", run.Job, err)
+	}
+
+	if cp.Bucket != bj.spec.Bucket || cp.Prefix != bj.spec.Prefix {
+		log.Printf("cron: batch %s: checkpoint of %s/%s ignored, starting over\n", run.Job, cp.Bucket, cp.Prefix)
+		return prog, nil
+	}
+
+	log.Printf("cron: batch %s: resuming run %s after %q, %d object(s) processed\n", run.Job, cp.RunID, cp.After, cp.Processed)
+	if cp.RunID != run.ID {
+		cp.ResumedBy = append(cp.ResumedBy, run.ID)
+	}
+
+	return &cp, nil
+}
+
+// processPage feed the objects of the page to the workers and wait for them
+func (bj *batchJob) processPage(ctx context.Context, page []*storage.ObjectInfo, prog *BatchProgress) {
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+
+	objects := make(chan *storage.ObjectInfo)
+	for i := 0; i < bj.spec.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for obj := range objects {
+				attempts, err := bj.process(ctx, obj)
+				if ctx.Err() != nil {
+					// interrupted, not a failure of the object
+					continue
+				}
+
+				mu.Lock()
+				prog.Processed++
+				if err == nil {
+					prog.Succeeded++
+				} else {
+					prog.Failed++
+					if len(prog.Failures) < MaxBatchFailures {
+						prog.Failures = append(prog.Failures, BatchFailure{Object: obj.Name, Attempts: attempts, Error: err.Error()})
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for _, obj := range page {
+		if bj.own(obj.Name) {
+			continue
+		}
+
+		select {
+		case objects <- obj:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(objects)
+
+	wg.Wait()
+}
+
+// process call the job function with the object, retried by the item policy
+func (bj *batchJob) process(ctx context.Context, obj *storage.ObjectInfo) (int, error) {
+	ctx = withParams(ctx, mergeParams(Params(ctx), map[string]string{
+		"bucket": bj.spec.Bucket,
+		"object": obj.Name,
+	}))
+	ctx = context.WithValue(ctx, batchObjectKey{}, obj)
+
+	attempts := 0
+	err := bj.spec.Retry.Do(ctx, func(ctx context.Context) error {
+		attempts++
+		return bj.fn(ctx)
+	}, nil)
+
+	return attempts, err
+}
+
+// finish write the report and remove the checkpoint, the batch is done even if objects failed
+func (bj *batchJob) finish(run RunInfo, prog *BatchProgress) error {
+	// job context may be cancelled right after the last page, the report should still be written
+	ctx := context.Background()
+
+	report := BatchReport{BatchProgress: *prog, FinishedAt: bj.clock.Now()}
+	report.Duration = report.FinishedAt.Sub(report.StartedAt)
+
+	if err := bj.save(ctx, bj.reportName(run.Job, prog.RunID), report); err != nil {
+		return fmt.Errorf("batch %s: report: %w", run.Job, err)
+	}
+	if err := bj.st.Delete(ctx, bj.stateBucket, bj.checkpointName(run.Job)); err != nil {
+		log.Printf("cron: batch %s: failed to delete checkpoint: %v\n", run.Job, err)
+	}
+
+	log.Printf("cron: batch %s done: %d object(s) processed, %d failed\n", run.Job, prog.Processed, prog.Failed)
+
+	// every object was already retried by the item policy, retrying the run would start the batch over
+	if prog.Failed > 0 {
+		return Permanent(fmt.Errorf("%w: %d of %d object(s) failed", ErrBatchFailed, prog.Failed, prog.Processed))
+	}
+
+	return nil
+}
+
+func (bj *batchJob) save(ctx context.Context, name string, v any) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return bj.st.Put(ctx, bj.stateBucket, name, b, false, "application/json")
+}
+
+func (bj *batchJob) checkpointName(job string) string {
+	return path.Join(bj.statePrefix, job, "checkpoint.json")
+}
+
+func (bj *batchJob) reportName(job, runID string) string {
+	return path.Join(bj.statePrefix, job, "reports", runID+".json")
+}
+
+// own reports whether the object is a checkpoint or report of a batch, listed when they share the bucket
+func (bj *batchJob) own(name string) bool {
+	return bj.stateBucket == bj.spec.Bucket && strings.HasPrefix(name, bj.statePrefix+"/")
+}
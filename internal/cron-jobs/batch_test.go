@@ -0,0 +1,302 @@
+package cron_jobs_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/vldcreation/sample-cron-go/internal/clock"
+	cron_jobs "github.com/vldcreation/sample-cron-go/internal/cron-jobs"
+)
+
+// waitRun returns the record of the run once it finished
+func waitRun(t *testing.T, c *cron_jobs.Cron, runID string) cron_jobs.RunRecord {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		records, _ := c.History().Query(context.Background(), cron_jobs.HistoryQuery{RunID: runID})
+		if len(records) > 0 {
+			return records[0]
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected run %s to finish, got %+v", runID, records)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func readReport(t *testing.T, st *memStorage, job, runID string) cron_jobs.BatchReport {
+	t.Helper()
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var report cron_jobs.BatchReport
+	data, ok := st.objects["bucket/cron-batches/"+job+"/reports/"+runID+".json"]
+	if !ok {
+		t.Fatalf("expected report of run %s", runID)
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	return report
+}
+
+func TestBatchJob(t *testing.T) {
+	t.Run("should process every object with bounded workers and item retries", func(t *testing.T) {
+		st := &memStorage{objects: make(map[string][]byte)}
+		for i := 0; i < 25; i++ {
+			st.objects[fmt.Sprintf("bucket/images/%03d.jpeg", i)] = []byte("jpeg")
+		}
+		st.objects["bucket/videos/001.mp4"] = []byte("mp4")
+
+		var (
+			running, maxRunning int32
+			mu                  sync.Mutex
+			attempts            = make(map[string]int)
+		)
+		fn := func(ctx context.Context) error {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				max := atomic.LoadInt32(&maxRunning)
+				if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+					break
+				}
+			}
+			time.Sleep(2 * time.Millisecond)
+
+			object := cron_jobs.Param(ctx, "object", "")
+			if obj, ok := cron_jobs.BatchObject(ctx); !ok || obj.Name != object || cron_jobs.Param(ctx, "bucket", "") != "bucket" {
+				return fmt.Errorf("expected object %s of bucket, got %+v", object, obj)
+			}
+
+			mu.Lock()
+			attempts[object]++
+			tries := attempts[object]
+			mu.Unlock()
+
+			switch {
+			case object == "images/003.jpeg" && tries == 1:
+				return errors.New("timeout")
+			case object == "images/007.jpeg":
+				return cron_jobs.Permanent(errors.New("corrupted"))
+			}
+			return nil
+		}
+
+		var pages int32
+		c := cron_jobs.NewCron()
+		defer c.Stop()
+		if _, err := c.AddJobWithCron("0 0 1 1 *", cron_jobs.NewBatchJob(st, cron_jobs.BatchSpec{
+			Bucket:   "bucket",
+			Prefix:   "images/",
+			Workers:  3,
+			PageSize: 10,
+			Retry:    cron_jobs.RetryPolicy{MaxAttempts: 2},
+		}, fn, cron_jobs.WithBatchProgress(func(cron_jobs.BatchProgress) {
+			atomic.AddInt32(&pages, 1)
+		})), cron_jobs.WithName("rehash")); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		runID, err := c.Trigger("rehash", nil)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		rec := waitRun(t, c, runID)
+		if rec.Status != cron_jobs.RunFailed || rec.Error != "cron: batch failed: 1 of 25 object(s) failed" {
+			t.Errorf("expected batch failed with 1 object, got %s %q", rec.Status, rec.Error)
+		}
+
+		if len(attempts) != 25 || attempts["images/003.jpeg"] != 2 || attempts["images/007.jpeg"] != 1 {
+			t.Errorf("expected every image processed once and 003 retried, got %v", attempts)
+		}
+		if max := atomic.LoadInt32(&maxRunning); max > 3 {
+			t.Errorf("expected at most 3 concurrent objects, got %d", max)
+		}
+		if n := atomic.LoadInt32(&pages); n != 3 {
+			t.Errorf("expected progress of 3 pages, got %d", n)
+		}
+
+		report := readReport(t, st, "rehash", runID)
+		expected := []cron_jobs.BatchFailure{{Object: "images/007.jpeg", Attempts: 1, Error: "corrupted"}}
+		if report.Processed != 25 || report.Succeeded != 24 || report.Failed != 1 || fmt.Sprint(report.Failures) != fmt.Sprint(expected) {
+			t.Errorf("expected 24 succeeded and 007 failed, got %+v", report)
+		}
+
+		st.mu.Lock()
+		defer st.mu.Unlock()
+		if _, ok := st.objects["bucket/cron-batches/rehash/checkpoint.json"]; ok {
+			t.Errorf("expected checkpoint deleted once done")
+		}
+	})
+
+	t.Run("should not retry run with failed objects and date report by its clock", func(t *testing.T) {
+		st := &memStorage{objects: make(map[string][]byte)}
+		st.objects["bucket/images/001.jpeg"] = []byte("jpeg")
+		st.objects["bucket/images/002.jpeg"] = []byte("jpeg")
+
+		var calls int32
+		fn := func(ctx context.Context) error {
+			atomic.AddInt32(&calls, 1)
+			if cron_jobs.Param(ctx, "object", "") == "images/002.jpeg" {
+				return errors.New("corrupted")
+			}
+			return nil
+		}
+
+		failed := make(chan cron_jobs.Event, 1)
+		c := cron_jobs.NewCron(cron_jobs.WithListener(func(ev cron_jobs.Event) {
+			failed <- ev
+		}, cron_jobs.EventFailed))
+		defer c.Stop()
+
+		clk := clock.NewFake(time.Date(2023, 6, 19, 9, 0, 0, 0, time.UTC))
+		if _, err := c.AddJobWithCron("0 0 1 1 *", cron_jobs.NewBatchJob(st, cron_jobs.BatchSpec{Bucket: "bucket", Prefix: "images/"}, fn,
+			cron_jobs.WithBatchClock(clk),
+		), cron_jobs.WithName("rehash"), cron_jobs.WithRetry(cron_jobs.RetryPolicy{MaxAttempts: 3})); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		runID, _ := c.Trigger("rehash", nil)
+		if ev := waitEvent(t, failed); ev.Attempt != 1 || !strings.HasPrefix(ev.Error, cron_jobs.ErrBatchFailed.Error()) {
+			t.Errorf("expected batch failed on the first attempt, got %+v", ev)
+		}
+		if n := atomic.LoadInt32(&calls); n != 2 {
+			t.Errorf("expected every object processed once, got %d calls", n)
+		}
+
+		report := readReport(t, st, "rehash", runID)
+		if !report.StartedAt.Equal(clk.Now()) || !report.FinishedAt.Equal(clk.Now()) || report.Duration != 0 {
+			t.Errorf("expected report dated %v, got %+v", clk.Now(), report)
+		}
+	})
+
+	t.Run("should back off object retries by its clock", func(t *testing.T) {
+		st := &memStorage{objects: make(map[string][]byte)}
+		st.objects["bucket/images/001.jpeg"] = []byte("jpeg")
+
+		var calls int32
+		fn := func(ctx context.Context) error {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				return errors.New("throttled")
+			}
+			return nil
+		}
+
+		succeeded := make(chan cron_jobs.Event, 1)
+		c := cron_jobs.NewCron(cron_jobs.WithListener(func(ev cron_jobs.Event) {
+			succeeded <- ev
+		}, cron_jobs.EventSucceeded))
+		defer c.Stop()
+
+		clk := clock.NewFake(time.Date(2023, 6, 19, 9, 0, 0, 0, time.UTC))
+		spec := cron_jobs.BatchSpec{Bucket: "bucket", Prefix: "images/", Retry: cron_jobs.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Hour}}
+		if _, err := c.AddJobWithCron("0 0 1 1 *", cron_jobs.NewBatchJob(st, spec, fn, cron_jobs.WithBatchClock(clk)), cron_jobs.WithName("rehash")); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		c.Trigger("rehash", nil)
+		clk.BlockUntil(1)
+		clk.Advance(time.Hour)
+
+		waitEvent(t, succeeded)
+		if n := atomic.LoadInt32(&calls); n != 2 {
+			t.Errorf("expected object retried once, got %d calls", n)
+		}
+	})
+
+	t.Run("should resume interrupted batch from its checkpoint", func(t *testing.T) {
+		st := &memStorage{objects: make(map[string][]byte)}
+		for i := 0; i < 30; i++ {
+			st.objects[fmt.Sprintf("bucket/%03d.jpeg", i)] = []byte("jpeg")
+		}
+
+		var (
+			mu        sync.Mutex
+			processed = make(map[string]int)
+			blocked   = make(chan struct{})
+			once      sync.Once
+		)
+		fn := func(ctx context.Context) error {
+			object := cron_jobs.Param(ctx, "object", "")
+			if object == "015.jpeg" && ctx.Value(resumedKey{}) == nil {
+				// first run is stopped in the middle of the second page
+				once.Do(func() { close(blocked) })
+				<-ctx.Done()
+				return ctx.Err()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			processed[object]++
+			return nil
+		}
+
+		spec := cron_jobs.BatchSpec{Bucket: "bucket", Workers: 2, PageSize: 10}
+
+		c := cron_jobs.NewCron()
+		if _, err := c.AddJobWithCron("0 0 1 1 *", cron_jobs.NewBatchJob(st, spec, fn), cron_jobs.WithName("archive")); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		firstRun, err := c.Trigger("archive", nil)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		<-blocked
+		c.Stop()
+
+		if rec := waitRun(t, c, firstRun); rec.Status != cron_jobs.RunCancelled {
+			t.Fatalf("expected first run cancelled, got %+v", rec)
+		}
+
+		// restarted instance
+		c = cron_jobs.NewCron(cron_jobs.WithMiddleware(func(next cron_jobs.JobFunc) cron_jobs.JobFunc {
+			return func(ctx context.Context) error {
+				return next(context.WithValue(ctx, resumedKey{}, true))
+			}
+		}))
+		defer c.Stop()
+		if _, err := c.AddJobWithCron("0 0 1 1 *", cron_jobs.NewBatchJob(st, spec, fn), cron_jobs.WithName("archive")); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		secondRun, err := c.Trigger("archive", nil)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		if rec := waitRun(t, c, secondRun); rec.Status != cron_jobs.RunSuccess {
+			t.Fatalf("expected second run to succeed, got %+v", rec)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		for i := 0; i < 30; i++ {
+			object := fmt.Sprintf("%03d.jpeg", i)
+			// the first page was checkpointed, it is not processed again
+			if i < 10 && processed[object] != 1 {
+				t.Errorf("expected %s processed once, got %d", object, processed[object])
+			}
+			if processed[object] == 0 {
+				t.Errorf("expected %s processed", object)
+			}
+		}
+
+		report := readReport(t, st, "archive", firstRun)
+		if report.Processed != 30 || report.Failed != 0 || len(report.ResumedBy) != 1 || report.ResumedBy[0] != secondRun {
+			t.Errorf("expected 30 objects processed and resumed by %s, got %+v", secondRun, report)
+		}
+	})
+}
+
+type resumedKey struct{}
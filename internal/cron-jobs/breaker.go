@@ -0,0 +1,183 @@
+package cron_jobs
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// BreakerPolicy suspend a job failing again and again, zero Threshold disable the breaker
+type BreakerPolicy struct {
+	// Threshold is how many consecutive failed runs trip the breaker
+	Threshold int
+	// Cooldown is how long scheduled runs are skipped once tripped, default to DefaultBreakerCooldown
+	Cooldown time.Duration
+}
+
+// DefaultBreakerCooldown is how long a tripped breaker stays open when the policy has no cooldown
+var DefaultBreakerCooldown = time.Minute * 5
+
+// BreakerState is the state of the job circuit breaker
+type BreakerState int
+
+const (
+	// BreakerClosed runs are executed
+	BreakerClosed BreakerState = iota
+	// BreakerOpen scheduled runs are skipped until the cooldown is over
+	BreakerOpen
+	// BreakerHalfOpen the cooldown is over, a single run probes whether the job recovered
+	BreakerHalfOpen
+)
+
+var breakerStateNames = map[BreakerState]string{
+	BreakerClosed:   "closed",
+	BreakerOpen:     "open",
+	BreakerHalfOpen: "half-open",
+}
+
+func (s BreakerState) String() string {
+	if name, ok := breakerStateNames[s]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("BreakerState(%d)", int(s))
+}
+
+// BreakerInfo is a snapshot of the breaker of a job
+type BreakerInfo struct {
+	State    string `json:"state"`
+	Failures int    `json:"failures"`
+	// OpenUntil is when the next run probes the job, zero unless the breaker is open
+	OpenUntil time.Time `json:"open_until,omitempty"`
+}
+
+// breaker count consecutive failures of a job, nil when the job has no breaker
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	// probing is set while the half-open run is running
+	probing bool
+}
+
+func newBreaker(p BreakerPolicy) *breaker {
+	if p.Threshold <= 0 {
+		return nil
+	}
+
+	b := &breaker{threshold: p.Threshold, cooldown: p.Cooldown}
+	if b.cooldown <= 0 {
+		b.cooldown = DefaultBreakerCooldown
+	}
+
+	return b
+}
+
+// allow tell whether a scheduled run may start at now, otherwise returns the skip reason.
+// probe reports the run is the half-open one and must be released once done
+func (b *breaker) allow(now time.Time) (probe bool, reason string) {
+	if b == nil {
+		return false, ""
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		until := b.openedAt.Add(b.cooldown)
+		if now.Before(until) {
+			return false, fmt.Sprintf("circuit open until %s", until.Format(time.RFC3339))
+		}
+
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return true, ""
+	case BreakerHalfOpen:
+		if b.probing {
+			return false, "circuit half-open, probe run still running"
+		}
+
+		b.probing = true
+		return true, ""
+	default:
+		return false, ""
+	}
+}
+
+// release let the next run probe the job, called when the probe run did not finish
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// done count the result of a finished run, returns the state it moved to or false if it did not change
+func (b *breaker) done(now time.Time, status RunStatus) (BreakerState, bool) {
+	if b == nil {
+		return BreakerClosed, false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	prev := b.state
+	switch status {
+	case RunSuccess:
+		b.failures = 0
+		b.state = BreakerClosed
+	case RunFailed:
+		b.failures++
+		if b.state == BreakerHalfOpen || (b.state == BreakerClosed && b.failures >= b.threshold) {
+			b.state = BreakerOpen
+			b.openedAt = now
+		}
+	}
+	b.probing = false
+
+	return b.state, b.state != prev
+}
+
+func (b *breaker) info() *BreakerInfo {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	info := &BreakerInfo{State: b.state.String(), Failures: b.failures}
+	if b.state == BreakerOpen {
+		info.OpenUntil = b.openedAt.Add(b.cooldown)
+	}
+
+	return info
+}
+
+// breakerDone feed the run result to the job breaker, emitting an event when it trips or resets
+func (c *Cron) breakerDone(j *Job, run *RunInfo, err error) {
+	state, changed := j.breaker.done(c.clock.Now(), statusOf(err))
+	if !changed {
+		return
+	}
+
+	ev := runEvent(EventBreakerReset, run)
+	switch state {
+	case BreakerOpen:
+		log.Printf("cron: job %s suspended for %s after %d consecutive failures\n", j.name, j.breaker.cooldown, j.breaker.info().Failures)
+		ev.Type = EventBreakerTripped
+		ev.Error = err.Error()
+	case BreakerClosed:
+		log.Printf("cron: job %s recovered, breaker reset\n", j.name)
+	default:
+		return
+	}
+
+	c.emit(ev)
+}
@@ -0,0 +1,134 @@
+package cron_jobs_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/vldcreation/sample-cron-go/internal/clock"
+	cron_jobs "github.com/vldcreation/sample-cron-go/internal/cron-jobs"
+)
+
+// nextMinute move the clock just past the next minute. gocron fired exactly on time
+// arms a zero timer running the job twice, a real clock is always a bit late
+func nextMinute(clk *clock.Fake) {
+	clk.Set(clk.Now().Truncate(time.Minute).Add(time.Minute + time.Millisecond))
+}
+
+func TestBreaker(t *testing.T) {
+	t.Run("should suspend failing job then probe it once cooled down", func(t *testing.T) {
+		start := time.Date(2023, 6, 19, 9, 0, 30, 0, time.UTC)
+		clk := clock.NewFake(start)
+
+		events := make(chan cron_jobs.Event, 100)
+		c := cron_jobs.NewCron(cron_jobs.WithLocation(time.UTC), cron_jobs.WithClock(clk), cron_jobs.WithListener(func(ev cron_jobs.Event) {
+			events <- ev
+		}, cron_jobs.EventSucceeded, cron_jobs.EventFailed, cron_jobs.EventSkipped, cron_jobs.EventBreakerTripped, cron_jobs.EventBreakerReset))
+
+		var healthy atomic.Bool
+		j, err := c.AddJobWithCron("* * * * *", func(ctx context.Context) error {
+			if !healthy.Load() {
+				return errors.New("storage unavailable")
+			}
+			return nil
+		}, cron_jobs.WithName("resign"), cron_jobs.WithBreaker(cron_jobs.BreakerPolicy{Threshold: 2, Cooldown: 2 * time.Minute}))
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		c.StartAsync()
+		defer c.Stop()
+
+		// fire the run of the next minute and returns its events, up to the run result
+		next := func(t *testing.T) []cron_jobs.Event {
+			t.Helper()
+
+			clk.BlockUntil(1)
+			nextMinute(clk)
+
+			var got []cron_jobs.Event
+			for {
+				select {
+				case ev := <-events:
+					got = append(got, ev)
+					if ev.Type == cron_jobs.EventSucceeded || ev.Type == cron_jobs.EventFailed || ev.Type == cron_jobs.EventSkipped {
+						return got
+					}
+				case <-time.After(time.Second):
+					t.Fatalf("expected run result, got %+v", got)
+					return nil
+				}
+			}
+		}
+
+		types := func(evs []cron_jobs.Event) string {
+			var names []string
+			for _, ev := range evs {
+				names = append(names, string(ev.Type))
+			}
+			return strings.Join(names, ",")
+		}
+
+		tests := []struct {
+			name     string
+			healthy  bool
+			expected string
+			state    string
+		}{
+			{name: "first failure", expected: "failed", state: "closed"},
+			{name: "threshold reached", expected: "breaker_tripped,failed", state: "open"},
+			{name: "cooling down", expected: "skipped", state: "open"},
+			{name: "probe succeeded", healthy: true, expected: "breaker_reset,succeeded", state: "closed"},
+		}
+
+		for _, tt := range tests {
+			healthy.Store(tt.healthy)
+
+			if got := types(next(t)); got != tt.expected {
+				t.Errorf("%s: expected %s, got %s", tt.name, tt.expected, got)
+			}
+			if info := j.Info().Breaker; info == nil || info.State != tt.state {
+				t.Errorf("%s: expected breaker %s, got %+v", tt.name, tt.state, info)
+			}
+		}
+	})
+
+	t.Run("should reopen breaker when probe fails", func(t *testing.T) {
+		start := time.Date(2023, 6, 19, 9, 0, 30, 0, time.UTC)
+		clk := clock.NewFake(start)
+
+		results := make(chan cron_jobs.Event, 100)
+		c := cron_jobs.NewCron(cron_jobs.WithLocation(time.UTC), cron_jobs.WithClock(clk), cron_jobs.WithListener(func(ev cron_jobs.Event) {
+			results <- ev
+		}, cron_jobs.EventFailed, cron_jobs.EventSkipped))
+
+		j, err := c.AddJobWithCron("* * * * *", func(ctx context.Context) error {
+			return errors.New("storage unavailable")
+		}, cron_jobs.WithName("resign"), cron_jobs.WithBreaker(cron_jobs.BreakerPolicy{Threshold: 1, Cooldown: 90 * time.Second}))
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		c.StartAsync()
+		defer c.Stop()
+
+		// 09:01 trips, 09:03 probes and fails, 09:04 is skipped again
+		expected := []cron_jobs.EventType{cron_jobs.EventFailed, cron_jobs.EventSkipped, cron_jobs.EventFailed, cron_jobs.EventSkipped}
+		for _, typ := range expected {
+			clk.BlockUntil(1)
+			nextMinute(clk)
+
+			if ev := waitEvent(t, results); ev.Type != typ {
+				t.Fatalf("expected %s, got %s", typ, ev.Type)
+			}
+		}
+
+		info := j.Info().Breaker
+		if expected := time.Date(2023, 6, 19, 9, 4, 30, 0, time.UTC); info.State != "open" || !info.OpenUntil.Truncate(time.Second).Equal(expected) {
+			t.Errorf("expected open until %s, got %+v", expected, info)
+		}
+	})
+}
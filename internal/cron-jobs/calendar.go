@@ -0,0 +1,627 @@
+package cron_jobs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrCalendarExists   = errors.New("cron: calendar already exists")
+	ErrCalendarNotFound = errors.New("cron: calendar not found")
+	ErrInvalidCalendar  = errors.New("cron: invalid calendar")
+)
+
+// maxShiftDays bound the search of the next business day
+const maxShiftDays = 366
+
+// CalendarPolicy decide what to do with a run falling on a non business day
+type CalendarPolicy int
+
+const (
+	// CalendarSkip skip the run
+	CalendarSkip CalendarPolicy = iota
+	// CalendarNextBusinessDay run at the same time on the next business day,
+	// unless the job is already scheduled then. The shifted run is kept in the
+	// cron state store, if any, so it survives restart
+	CalendarNextBusinessDay
+)
+
+var calendarPolicyNames = map[CalendarPolicy]string{
+	CalendarSkip:            "skip",
+	CalendarNextBusinessDay: "next_business_day",
+}
+
+func (p CalendarPolicy) String() string {
+	if name, ok := calendarPolicyNames[p]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("CalendarPolicy(%d)", int(p))
+}
+
+// ParseCalendarPolicy parse policy name: skip or next_business_day (or shift)
+func ParseCalendarPolicy(s string) (CalendarPolicy, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "":
+		return CalendarSkip, nil
+	case "shift":
+		return CalendarNextBusinessDay, nil
+	}
+
+	for p, name := range calendarPolicyNames {
+		if name == s {
+			return p, nil
+		}
+	}
+
+	return CalendarSkip, fmt.Errorf("cron: unknown calendar policy %q", s)
+}
+
+// ParseWeekday parse english weekday name, full or abbreviated, e.g. "saturday" or "sat"
+func ParseWeekday(s string) (time.Weekday, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if s == name || s == name[:3] {
+			return d, nil
+		}
+	}
+
+	return time.Sunday, fmt.Errorf("%w: unknown weekday %q", ErrInvalidCalendar, s)
+}
+
+// Holiday is a non business day. Zero Year means the holiday repeats every year
+type Holiday struct {
+	Name  string     `json:"name,omitempty"`
+	Year  int        `json:"year,omitempty"`
+	Month time.Month `json:"month"`
+	Day   int        `json:"day"`
+}
+
+// ParseHoliday parse date in form "2006-01-02", or "01-02" for a holiday repeating every year
+func ParseHoliday(date, name string) (Holiday, error) {
+	date = strings.TrimSpace(date)
+
+	if t, err := time.Parse("2006-01-02", date); err == nil {
+		return Holiday{Name: name, Year: t.Year(), Month: t.Month(), Day: t.Day()}, nil
+	}
+
+	// leap year so 02-29 is accepted
+	if t, err := time.Parse("2006-01-02", "2000-"+date); err == nil {
+		return Holiday{Name: name, Month: t.Month(), Day: t.Day()}, nil
+	}
+
+	return Holiday{}, fmt.Errorf("%w: invalid date %q, expected YYYY-MM-DD or MM-DD", ErrInvalidCalendar, date)
+}
+
+// Calendar tell business days apart from weekends and holidays.
+// A calendar loaded from a file can be reloaded while jobs use it.
+type Calendar struct {
+	name string
+	path string
+	loc  *time.Location
+	opts []CalendarOption
+
+	mu      sync.RWMutex
+	weekend map[time.Weekday]bool
+	// dates are fixed holidays keyed by "2006-01-02", annual by "01-02"
+	dates  map[string]string
+	annual map[string]string
+}
+
+// CalendarOption configure a calendar
+type CalendarOption func(cal *Calendar)
+
+// WithWeekend set the weekly days off, default saturday and sunday
+func WithWeekend(days ...time.Weekday) CalendarOption {
+	return func(cal *Calendar) {
+		cal.weekend = make(map[time.Weekday]bool, len(days))
+		for _, d := range days {
+			cal.weekend[d] = true
+		}
+	}
+}
+
+// WithHolidays add holidays to the calendar
+func WithHolidays(holidays ...Holiday) CalendarOption {
+	return func(cal *Calendar) {
+		for _, h := range holidays {
+			cal.addHoliday(h)
+		}
+	}
+}
+
+// WithCalendarLocation evaluate dates in loc, default to the location of the job
+func WithCalendarLocation(loc *time.Location) CalendarOption {
+	return func(cal *Calendar) {
+		cal.loc = loc
+	}
+}
+
+func NewCalendar(name string, opts ...CalendarOption) *Calendar {
+	cal := &Calendar{name: name, opts: opts}
+	cal.reset()
+
+	return cal
+}
+
+// LoadCalendar create calendar with the holidays of an iCalendar (.ics) or YAML (.yaml, .yml) file.
+// The YAML file has the form:
+//
+//	weekend: [saturday, sunday]
+//	holidays:
+//	  - date: 2023-06-29
+//	    name: Idul Adha
+//	  - date: 12-25 # every year
+//	    name: Christmas
+func LoadCalendar(name, path string, opts ...CalendarOption) (*Calendar, error) {
+	cal := &Calendar{name: name, path: path, opts: opts}
+	if err := cal.Reload(); err != nil {
+		return nil, err
+	}
+
+	return cal, nil
+}
+
+// Name returns the name of the calendar
+func (cal *Calendar) Name() string {
+	return cal.name
+}
+
+// Reload read the calendar file again, holidays removed from the file are removed from the calendar.
+// It does nothing for calendar not loaded from a file.
+func (cal *Calendar) Reload() error {
+	if cal.path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(cal.path)
+	if err != nil {
+		return fmt.Errorf("cron: load calendar %s: %w", cal.name, err)
+	}
+
+	var file calendarFile
+	switch strings.ToLower(filepath.Ext(cal.path)) {
+	case ".ics", ".ical":
+		file, err = parseICS(data)
+	case ".yaml", ".yml":
+		file, err = parseCalendarYAML(data)
+	default:
+		err = fmt.Errorf("%w: unsupported file %s", ErrInvalidCalendar, cal.path)
+	}
+	if err != nil {
+		return fmt.Errorf("cron: load calendar %s: %w", cal.name, err)
+	}
+
+	// build aside so jobs never see a partially loaded calendar
+	next := &Calendar{name: cal.name, opts: cal.opts}
+	next.reset()
+	if file.weekend != nil {
+		WithWeekend(file.weekend...)(next)
+	}
+	WithHolidays(file.holidays...)(next)
+
+	cal.mu.Lock()
+	cal.loc = next.loc
+	cal.weekend = next.weekend
+	cal.dates = next.dates
+	cal.annual = next.annual
+	cal.mu.Unlock()
+
+	return nil
+}
+
+// reset apply the options to an empty calendar
+func (cal *Calendar) reset() {
+	cal.weekend = map[time.Weekday]bool{time.Saturday: true, time.Sunday: true}
+	cal.dates = make(map[string]string)
+	cal.annual = make(map[string]string)
+
+	for _, opt := range cal.opts {
+		opt(cal)
+	}
+}
+
+func (cal *Calendar) addHoliday(h Holiday) {
+	if h.Year == 0 {
+		cal.annual[fmt.Sprintf("%02d-%02d", h.Month, h.Day)] = h.Name
+		return
+	}
+
+	cal.dates[fmt.Sprintf("%04d-%02d-%02d", h.Year, h.Month, h.Day)] = h.Name
+}
+
+// IsBusinessDay reports whether the day of t is neither a weekend nor a holiday
+func (cal *Calendar) IsBusinessDay(t time.Time) bool {
+	return cal.excluded(t) == ""
+}
+
+// excluded returns why the day of t is not a business day, empty for a business day
+func (cal *Calendar) excluded(t time.Time) string {
+	cal.mu.RLock()
+	defer cal.mu.RUnlock()
+
+	if cal.loc != nil {
+		t = t.In(cal.loc)
+	}
+
+	date := t.Format("2006-01-02")
+	name, ok := cal.dates[date]
+	if !ok {
+		name, ok = cal.annual[date[5:]]
+	}
+	if ok {
+		if name == "" {
+			name = date
+		}
+		return fmt.Sprintf("holiday %s in calendar %s", name, cal.name)
+	}
+
+	if cal.weekend[t.Weekday()] {
+		return fmt.Sprintf("%s is off in calendar %s", t.Weekday(), cal.name)
+	}
+
+	return ""
+}
+
+// NextBusinessDay returns t moved to the same wall clock time of the first business day after it,
+// zero when none is found within a year
+func (cal *Calendar) NextBusinessDay(t time.Time) time.Time {
+	for i := 0; i < maxShiftDays; i++ {
+		t = t.AddDate(0, 0, 1)
+		if cal.IsBusinessDay(t) {
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// Holidays returns the holidays of the calendar sorted by date, annual holidays first
+func (cal *Calendar) Holidays() []Holiday {
+	cal.mu.RLock()
+	defer cal.mu.RUnlock()
+
+	holidays := make([]Holiday, 0, len(cal.annual)+len(cal.dates))
+	for date, name := range cal.annual {
+		h, _ := ParseHoliday(date, name)
+		holidays = append(holidays, h)
+	}
+	for date, name := range cal.dates {
+		h, _ := ParseHoliday(date, name)
+		holidays = append(holidays, h)
+	}
+
+	sort.Slice(holidays, func(a, b int) bool {
+		ha, hb := holidays[a], holidays[b]
+		if ha.Year != hb.Year {
+			return ha.Year < hb.Year
+		}
+		if ha.Month != hb.Month {
+			return ha.Month < hb.Month
+		}
+		return ha.Day < hb.Day
+	})
+
+	return holidays
+}
+
+// calendarFile is the content of a calendar file
+type calendarFile struct {
+	// weekend is nil when the file does not set it
+	weekend  []time.Weekday
+	holidays []Holiday
+}
+
+func parseCalendarYAML(data []byte) (calendarFile, error) {
+	var raw struct {
+		Weekend  []string `yaml:"weekend"`
+		Holidays []struct {
+			Date string `yaml:"date"`
+			Name string `yaml:"name"`
+		} `yaml:"holidays"`
+	}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return calendarFile{}, fmt.Errorf("%w: %v", ErrInvalidCalendar, err)
+	}
+
+	var file calendarFile
+	if raw.Weekend != nil {
+		file.weekend = []time.Weekday{}
+		for _, s := range raw.Weekend {
+			d, err := ParseWeekday(s)
+			if err != nil {
+				return calendarFile{}, err
+			}
+			file.weekend = append(file.weekend, d)
+		}
+	}
+
+	for _, h := range raw.Holidays {
+		holiday, err := ParseHoliday(h.Date, h.Name)
+		if err != nil {
+			return calendarFile{}, err
+		}
+		file.holidays = append(file.holidays, holiday)
+	}
+
+	return file, nil
+}
+
+// parseICS read the all day events of an iCalendar file as holidays.
+// Multi days events cover every day until DTEND, events with a yearly RRULE repeat every year.
+func parseICS(data []byte) (calendarFile, error) {
+	var (
+		file    calendarFile
+		inEvent bool
+		start   time.Time
+		end     time.Time
+		summary string
+		yearly  bool
+	)
+
+	for _, line := range unfoldICS(data) {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		// drop parameters, e.g. DTSTART;VALUE=DATE
+		name, _, _ = strings.Cut(strings.ToUpper(name), ";")
+
+		switch name {
+		case "BEGIN":
+			if strings.EqualFold(value, "VEVENT") {
+				inEvent, start, end, summary, yearly = true, time.Time{}, time.Time{}, "", false
+			}
+		case "END":
+			if !strings.EqualFold(value, "VEVENT") || !inEvent {
+				continue
+			}
+			inEvent = false
+
+			if start.IsZero() {
+				return calendarFile{}, fmt.Errorf("%w: event %q without DTSTART", ErrInvalidCalendar, summary)
+			}
+			if !end.After(start) {
+				end = start.AddDate(0, 0, 1)
+			}
+
+			for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+				h := Holiday{Name: summary, Year: d.Year(), Month: d.Month(), Day: d.Day()}
+				if yearly {
+					h.Year = 0
+				}
+				file.holidays = append(file.holidays, h)
+			}
+		case "DTSTART", "DTEND":
+			if !inEvent {
+				continue
+			}
+
+			t, err := parseICSDate(value)
+			if err != nil {
+				return calendarFile{}, err
+			}
+			if name == "DTSTART" {
+				start = t
+			} else {
+				end = t
+			}
+		case "SUMMARY":
+			summary = unescapeICS(value)
+		case "RRULE":
+			yearly = strings.Contains(strings.ToUpper(value), "FREQ=YEARLY")
+		}
+	}
+
+	return file, nil
+}
+
+// parseICSDate parse DATE or DATE-TIME value, keeping only the date
+func parseICSDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if len(value) < 8 {
+		return time.Time{}, fmt.Errorf("%w: invalid date %q", ErrInvalidCalendar, value)
+	}
+
+	t, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: invalid date %q", ErrInvalidCalendar, value)
+	}
+
+	return t, nil
+}
+
+// unfoldICS join folded lines, a line starting with a space or a tab continues the previous one
+func unfoldICS(data []byte) []string {
+	var lines []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+func unescapeICS(s string) string {
+	return strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, " ", `\N`, " ", `\\`, `\`).Replace(s)
+}
+
+// RegisterCalendar make the calendar available to jobs through WithCalendar
+func (c *Cron) RegisterCalendar(cal *Calendar) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.calendars[cal.name]; ok {
+		return fmt.Errorf("%w: %s", ErrCalendarExists, cal.name)
+	}
+
+	c.calendars[cal.name] = cal
+	return nil
+}
+
+// Calendar returns the registered calendar with the given name
+func (c *Cron) Calendar(name string) (*Calendar, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	cal, ok := c.calendars[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrCalendarNotFound, name)
+	}
+
+	return cal, nil
+}
+
+// Calendars returns the names of registered calendars
+func (c *Cron) Calendars() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := make([]string, 0, len(c.calendars))
+	for name := range c.calendars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// ReloadCalendars reload every calendar loaded from a file.
+// A calendar failing to reload keeps its previous holidays, the first error is returned.
+func (c *Cron) ReloadCalendars() error {
+	var first error
+	for _, name := range c.Calendars() {
+		cal, err := c.Calendar(name)
+		if err != nil {
+			continue
+		}
+
+		if err := cal.Reload(); err != nil {
+			log.Printf("cron: %v\n", err)
+			if first == nil {
+				first = err
+			}
+		}
+	}
+
+	return first
+}
+
+// offDay skip a run falling on a non business day of the job calendar,
+// or postpone it to the next business day. It returns false when the run may start.
+func (c *Cron) offDay(j *Job, run *RunInfo) bool {
+	if j.cfg.Calendar == "" {
+		return false
+	}
+
+	cal, err := c.Calendar(j.cfg.Calendar)
+	if err != nil {
+		c.skip(run, err.Error())
+		return true
+	}
+
+	at := run.ScheduledAt.In(j.location())
+	reason := cal.excluded(at)
+	if reason == "" {
+		return false
+	}
+
+	if j.cfg.CalendarPolicy != CalendarNextBusinessDay {
+		c.skip(run, reason)
+		return true
+	}
+
+	next := cal.NextBusinessDay(at)
+	switch {
+	case next.IsZero():
+		c.skip(run, reason+", no business day within a year")
+	case j.scheduledOn(next):
+		c.skip(run, fmt.Sprintf("%s, already scheduled on %s", reason, next.Format(time.RFC3339)))
+	case !j.deferRun(next):
+		c.skip(run, fmt.Sprintf("%s, already shifted to %s", reason, next.Format(time.RFC3339)))
+	default:
+		c.skip(run, fmt.Sprintf("%s, shifted to %s", reason, next.Format(time.RFC3339)))
+		if c.opts.State != nil {
+			if err := c.opts.State.AddDeferredRun(context.Background(), j.name, next); err != nil {
+				log.Printf("cron: failed to save shifted run of job %s: %v\n", j.name, err)
+			}
+		}
+		go c.runAt(j, next)
+	}
+
+	return true
+}
+
+// runAt run the job at the given time unless the scheduler stops before
+func (c *Cron) runAt(j *Job, at time.Time) {
+	timer := c.clock.NewTimer(c.clock.Until(at))
+	defer timer.Stop()
+
+	select {
+	case <-c.ctx.Done():
+		return
+	case <-timer.C():
+	}
+
+	// the persisted run is removed by execute once the run started,
+	// a run that does not start is resumed after a restart
+	j.undeferRun(at)
+	c.execute(j, runRequest{ScheduledAt: at, Deferred: true})
+}
+
+// removeDeferred remove the persisted run shifted to at
+func (c *Cron) removeDeferred(j *Job, at time.Time) {
+	if c.opts.State == nil {
+		return
+	}
+
+	if err := c.opts.State.RemoveDeferredRun(context.Background(), j.name, at); err != nil {
+		log.Printf("cron: failed to remove shifted run of job %s: %v\n", j.name, err)
+	}
+}
+
+// resumeDeferred schedule again the runs of the job shifted to a business day before a restart,
+// a shifted run missed while the scheduler was down runs at once unless the job skips misfires
+func (c *Cron) resumeDeferred(j *Job) {
+	if c.opts.State == nil || j.cfg.Calendar == "" || j.cfg.CalendarPolicy != CalendarNextBusinessDay {
+		return
+	}
+
+	runs, err := c.opts.State.DeferredRuns(c.ctx, j.name)
+	if err != nil {
+		log.Printf("cron: failed to load shifted runs of job %s: %v\n", j.name, err)
+		return
+	}
+
+	now := c.clock.Now()
+	for _, at := range runs {
+		if at.Before(now) && j.cfg.Misfire == MisfireSkip {
+			c.removeDeferred(j, at)
+			continue
+		}
+
+		if j.deferRun(at) {
+			go c.runAt(j, at)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package cron_jobs_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vldcreation/sample-cron-go/internal/clock"
+	cron_jobs "github.com/vldcreation/sample-cron-go/internal/cron-jobs"
+)
+
+const holidaysICS = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20230628\r\n" +
+	"DTEND;VALUE=DATE:20230630\r\n" +
+	"SUMMARY:Idul Adha\\, cuti bersama\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20001225\r\n" +
+	"RRULE:FREQ=YEARLY\r\n" +
+	"SUMMARY:Christ\r\n" +
+	" mas\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestCalendar(t *testing.T) {
+	jakarta, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		t.Skipf("time zone database not available: %v", err)
+	}
+
+	independence, _ := cron_jobs.ParseHoliday("08-17", "Independence Day")
+	newYear, _ := cron_jobs.ParseHoliday("2024-01-01", "New Year")
+	cal := cron_jobs.NewCalendar("id",
+		cron_jobs.WithHolidays(independence, newYear),
+		cron_jobs.WithCalendarLocation(jakarta),
+	)
+
+	tests := []struct {
+		name     string
+		at       time.Time
+		expected bool
+	}{
+		{
+			name:     "should be business day on monday",
+			at:       time.Date(2023, 6, 19, 9, 0, 0, 0, jakarta),
+			expected: true,
+		},
+		{
+			name:     "should exclude weekend",
+			at:       time.Date(2023, 6, 24, 9, 0, 0, 0, jakarta),
+			expected: false,
+		},
+		{
+			name:     "should exclude annual holiday of any year",
+			at:       time.Date(2027, 8, 17, 9, 0, 0, 0, jakarta),
+			expected: false,
+		},
+		{
+			name:     "should exclude fixed holiday",
+			at:       time.Date(2024, 1, 1, 9, 0, 0, 0, jakarta),
+			expected: false,
+		},
+		{
+			name:     "should evaluate date in calendar location",
+			at:       time.Date(2023, 6, 23, 20, 0, 0, 0, time.UTC),
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cal.IsBusinessDay(tt.at); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	t.Run("should shift to next business day keeping the time", func(t *testing.T) {
+		// friday before the independence day long weekend of 2026
+		at := time.Date(2026, 8, 14, 9, 30, 0, 0, jakarta)
+		if !cal.IsBusinessDay(at) {
+			t.Fatalf("expected %s to be a business day", at)
+		}
+
+		expected := time.Date(2026, 8, 18, 9, 30, 0, 0, jakarta)
+		if got := cal.NextBusinessDay(at); !got.Equal(expected) {
+			t.Errorf("expected %s, got %s", expected, got)
+		}
+	})
+
+	t.Run("should load and reload yaml calendar", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "holidays.yaml")
+		write := func(content string) {
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+		}
+		write("weekend: [friday]\nholidays:\n  - date: 2023-06-19\n    name: Company Day\n")
+
+		cal, err := cron_jobs.LoadCalendar("company", path)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		monday := time.Date(2023, 6, 19, 9, 0, 0, 0, time.UTC)
+		if cal.IsBusinessDay(monday) || cal.IsBusinessDay(monday.AddDate(0, 0, 4)) || !cal.IsBusinessDay(monday.AddDate(0, 0, 5)) {
+			t.Errorf("expected company day and friday off, saturday on")
+		}
+
+		write("holidays: []\n")
+		if err := cal.Reload(); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if !cal.IsBusinessDay(monday) || cal.IsBusinessDay(monday.AddDate(0, 0, 5)) {
+			t.Errorf("expected reloaded calendar with default weekend and no holiday")
+		}
+
+		write("holidays:\n  - date: 2023-13-01\n")
+		if err := cal.Reload(); !errors.Is(err, cron_jobs.ErrInvalidCalendar) {
+			t.Errorf("expected %v, got %v", cron_jobs.ErrInvalidCalendar, err)
+		}
+		if !cal.IsBusinessDay(monday) {
+			t.Errorf("expected calendar to keep previous holidays when reload fails")
+		}
+	})
+
+	t.Run("should load ics calendar", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "holidays.ics")
+		if err := os.WriteFile(path, []byte(holidaysICS), 0o644); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		cal, err := cron_jobs.LoadCalendar("id", path)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		holidays := cal.Holidays()
+		if len(holidays) != 3 {
+			t.Fatalf("expected 3 holidays, got %+v", holidays)
+		}
+		if holidays[0].Name != "Christmas" || holidays[0].Year != 0 {
+			t.Errorf("expected annual Christmas, got %+v", holidays[0])
+		}
+		if holidays[1].Name != "Idul Adha, cuti bersama" || holidays[2].Day != 29 {
+			t.Errorf("expected 2 days of Idul Adha, got %+v", holidays[1:])
+		}
+	})
+}
+
+func TestJobCalendar(t *testing.T) {
+	t.Run("should return error when calendar is not registered", func(t *testing.T) {
+		c := cron_jobs.NewCron()
+		_, err := c.AddJobWithCron("0 9 * * *", func(ctx context.Context) error { return nil },
+			cron_jobs.WithCalendar("id", cron_jobs.CalendarSkip))
+		if !errors.Is(err, cron_jobs.ErrCalendarNotFound) {
+			t.Errorf("expected %v, got %v", cron_jobs.ErrCalendarNotFound, err)
+		}
+	})
+
+	t.Run("should skip run on holiday", func(t *testing.T) {
+		c := cron_jobs.NewCron(cron_jobs.WithLocation(time.UTC))
+
+		// tomorrow as well, so the test does not break around midnight
+		today, tomorrow := time.Now().UTC(), time.Now().UTC().AddDate(0, 0, 1)
+		cal := cron_jobs.NewCalendar("id", cron_jobs.WithWeekend(), cron_jobs.WithHolidays(
+			cron_jobs.Holiday{Name: "Test Day", Year: today.Year(), Month: today.Month(), Day: today.Day()},
+			cron_jobs.Holiday{Name: "Test Day", Year: tomorrow.Year(), Month: tomorrow.Month(), Day: tomorrow.Day()},
+		))
+		if err := c.RegisterCalendar(cal); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		if _, err := c.AddJobWithCron("* * * * * *", func(ctx context.Context) error {
+			t.Errorf("expected job not to run on holiday")
+			return nil
+		}, cron_jobs.WithName("resign"), cron_jobs.WithCalendar("id", cron_jobs.CalendarNextBusinessDay)); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		c.StartAsync()
+		defer c.Stop()
+
+		var records []cron_jobs.RunRecord
+		deadline := time.Now().Add(3 * time.Second)
+		for len(records) == 0 && time.Now().Before(deadline) {
+			time.Sleep(20 * time.Millisecond)
+			records, _ = c.History().Query(context.Background(), cron_jobs.HistoryQuery{Job: "resign"})
+		}
+		if len(records) == 0 {
+			t.Fatalf("expected skipped run")
+		}
+
+		rec := records[0]
+		if rec.Status != cron_jobs.RunSkipped || !strings.Contains(rec.Error, "Test Day") || !strings.Contains(rec.Error, "already scheduled") {
+			t.Errorf("expected run skipped on Test Day and covered by next business day run, got %s: %s", rec.Status, rec.Error)
+		}
+	})
+
+	t.Run("should resume shifted run after restart", func(t *testing.T) {
+		ctx := context.Background()
+		state := cron_jobs.NewMemoryState()
+		monday := time.Date(2023, 6, 19, 9, 0, 0, 0, time.UTC)
+
+		replica := func(start time.Time) (*cron_jobs.Cron, *clock.Fake, chan cron_jobs.Event) {
+			clk := clock.NewFake(start)
+			events := make(chan cron_jobs.Event, 10)
+			c := cron_jobs.NewCron(
+				cron_jobs.WithClock(clk),
+				cron_jobs.WithLocation(time.UTC),
+				cron_jobs.WithState(state),
+				cron_jobs.WithListener(func(ev cron_jobs.Event) {
+					events <- ev
+				}, cron_jobs.EventSucceeded, cron_jobs.EventSkipped),
+			)
+			if err := c.RegisterCalendar(cron_jobs.NewCalendar("id")); err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+			if _, err := c.AddJobWithCron("0 9 * * 6", func(ctx context.Context) error { return nil },
+				cron_jobs.WithName("report"), cron_jobs.WithCalendar("id", cron_jobs.CalendarNextBusinessDay)); err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+			return c, clk, events
+		}
+
+		// saturday run is shifted to monday, then the scheduler stops on sunday
+		first, clk, events := replica(time.Date(2023, 6, 17, 8, 59, 59, 0, time.UTC))
+		first.StartAsync()
+		clk.BlockUntil(1)
+		clk.Advance(time.Second + time.Millisecond)
+		if ev := waitEvent(t, events); ev.Type != cron_jobs.EventSkipped || !strings.Contains(ev.Error, "shifted to 2023-06-19T09:00:00Z") {
+			t.Fatalf("expected run shifted to monday, got %+v", ev)
+		}
+		first.Stop()
+
+		if runs, err := state.DeferredRuns(ctx, "report"); err != nil || len(runs) != 1 || !runs[0].Equal(monday) {
+			t.Fatalf("expected shifted run %v saved, got %v (%v)", monday, runs, err)
+		}
+
+		second, clk, events := replica(time.Date(2023, 6, 18, 12, 0, 0, 0, time.UTC))
+		defer second.Stop()
+		second.StartAsync()
+		// the shifted run fires at once when its timer is created after monday
+		clk.Set(monday.Add(time.Millisecond))
+
+		if ev := waitEvent(t, events); ev.Type != cron_jobs.EventSucceeded || !ev.ScheduledAt.Equal(monday) {
+			t.Fatalf("expected shifted run on monday, got %+v", ev)
+		}
+		if runs, err := state.DeferredRuns(ctx, "report"); err != nil || len(runs) != 0 {
+			t.Errorf("expected no shifted run left, got %v (%v)", runs, err)
+		}
+	})
+
+	t.Run("should keep shifted run which did not start", func(t *testing.T) {
+		ctx := context.Background()
+		state := cron_jobs.NewMemoryState()
+		monday := time.Date(2023, 6, 19, 9, 0, 0, 0, time.UTC)
+
+		clk := clock.NewFake(time.Date(2023, 6, 17, 8, 59, 59, 0, time.UTC))
+		events := make(chan cron_jobs.Event, 10)
+		c := cron_jobs.NewCron(
+			cron_jobs.WithClock(clk),
+			cron_jobs.WithLocation(time.UTC),
+			cron_jobs.WithState(state),
+			cron_jobs.WithLocker(heldLocker{}, time.Minute),
+			cron_jobs.WithListener(func(ev cron_jobs.Event) {
+				events <- ev
+			}, cron_jobs.EventSkipped),
+		)
+		defer c.Stop()
+		if err := c.RegisterCalendar(cron_jobs.NewCalendar("id")); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if _, err := c.AddJobWithCron("0 9 * * 6", func(ctx context.Context) error { return nil },
+			cron_jobs.WithName("report"), cron_jobs.WithCalendar("id", cron_jobs.CalendarNextBusinessDay)); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		c.StartAsync()
+		clk.BlockUntil(1)
+		clk.Advance(time.Second + time.Millisecond)
+		if ev := waitEvent(t, events); ev.Type != cron_jobs.EventSkipped || !strings.Contains(ev.Error, "shifted") {
+			t.Fatalf("expected run shifted to monday, got %+v", ev)
+		}
+
+		// the lease of the monday run is held by an instance which may never start it
+		clk.BlockUntil(2)
+		clk.Set(monday.Add(time.Millisecond))
+		if ev := waitEvent(t, events); ev.Type != cron_jobs.EventSkipped || !ev.ScheduledAt.Equal(monday) {
+			t.Fatalf("expected shifted run skipped on monday, got %+v", ev)
+		}
+
+		if runs, err := state.DeferredRuns(ctx, "report"); err != nil || len(runs) != 1 || !runs[0].Equal(monday) {
+			t.Errorf("expected shifted run %v kept, got %v (%v)", monday, runs, err)
+		}
+	})
+}
+
+// heldLocker never grants a lease, as if an other instance held it
+type heldLocker struct{}
+
+func (heldLocker) Acquire(ctx context.Context, key string, run time.Time, ttl time.Duration) (cron_jobs.Lease, error) {
+	return nil, cron_jobs.ErrLockHeld
+}
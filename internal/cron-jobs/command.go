@@ -0,0 +1,304 @@
+package cron_jobs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vldcreation/sample-cron-go/internal/clock"
+	"github.com/vldcreation/sample-cron-go/internal/storage"
+)
+
+var ErrCommandFailed = errors.New("cron: command failed")
+
+var (
+	// DefaultKillGrace is how long a command has to exit after SIGTERM before it is killed
+	DefaultKillGrace = time.Second * 10
+	// DefaultCommandMaxOutput is how many bytes of output are kept, the rest is dropped
+	DefaultCommandMaxOutput = 10 << 20
+)
+
+// CommandSpec describe an external command run by a job
+type CommandSpec struct {
+	Path string
+	Args []string
+	// Env are KEY=VALUE pairs added to the environment of the service
+	Env []string
+	// Dir is the working directory, empty means the one of the service
+	Dir string
+	// Stdin is written to the standard input of the command
+	Stdin string
+	// Timeout stop the command once elapsed, zero means no timeout
+	Timeout time.Duration
+	// KillGrace is how long the command has to exit after SIGTERM, default to DefaultKillGrace
+	KillGrace time.Duration
+}
+
+func (s CommandSpec) String() string {
+	return strings.Join(append([]string{s.Path}, s.Args...), " ")
+}
+
+// CommandError is returned when the command exits with a non zero code
+type CommandError struct {
+	ExitCode int
+	// Tail is the end of the command output
+	Tail string
+}
+
+func (e *CommandError) Error() string {
+	if e.Tail == "" {
+		return fmt.Sprintf("command exited with code %d", e.ExitCode)
+	}
+
+	return fmt.Sprintf("command exited with code %d: %s", e.ExitCode, e.Tail)
+}
+
+func (e *CommandError) Is(target error) bool {
+	return target == ErrCommandFailed
+}
+
+// commandTailSize is how many bytes of output are reported in CommandError
+const commandTailSize = 512
+
+// CommandOption configure a command job
+type CommandOption func(cj *commandJob)
+
+// WithCommandLogs upload the output of every attempt to bucket, under prefix/job/run_id/attempt-N.log
+func WithCommandLogs(st storage.Storage, bucket, prefix string) CommandOption {
+	return func(cj *commandJob) {
+		cj.st = st
+		cj.bucket = bucket
+		cj.prefix = prefix
+	}
+}
+
+// WithCommandMaxOutput set how many bytes of output are kept, default to DefaultCommandMaxOutput
+func WithCommandMaxOutput(n int) CommandOption {
+	return func(cj *commandJob) {
+		cj.maxOutput = n
+	}
+}
+
+// WithCommandClock set the clock timing the command and its kill grace period, default to the system clock
+func WithCommandClock(clk clock.Clock) CommandOption {
+	return func(cj *commandJob) {
+		cj.clock = clk
+	}
+}
+
+type commandJob struct {
+	spec      CommandSpec
+	maxOutput int
+	clock     clock.Clock
+
+	st     storage.Storage
+	bucket string
+	prefix string
+}
+
+// NewCommandJob returns job function running the command. The run and its params are passed
+// in the environment as CRON_JOB, CRON_RUN_ID, CRON_ATTEMPT and CRON_PARAM_<KEY>.
+// A non zero exit code fails the run with CommandError
+func NewCommandJob(spec CommandSpec, opts ...CommandOption) JobFunc {
+	cj := &commandJob{spec: spec, maxOutput: DefaultCommandMaxOutput, clock: clock.New()}
+	for _, opt := range opts {
+		opt(cj)
+	}
+	if cj.spec.KillGrace <= 0 {
+		cj.spec.KillGrace = DefaultKillGrace
+	}
+
+	return cj.run
+}
+
+func (cj *commandJob) run(ctx context.Context) error {
+	run, _ := RunFromContext(ctx)
+
+	if cj.spec.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cj.spec.Timeout)
+		defer cancel()
+	}
+
+	out := &outputBuffer{max: cj.maxOutput}
+
+	cmd := exec.Command(cj.spec.Path, cj.spec.Args...)
+	cmd.Dir = cj.spec.Dir
+	cmd.Env = append(append(os.Environ(), cj.spec.Env...), commandEnv(run, Params(ctx))...)
+	if cj.spec.Stdin != "" {
+		cmd.Stdin = strings.NewReader(cj.spec.Stdin)
+	}
+	cmd.Stdout = out
+	cmd.Stderr = out
+	setProcessGroup(cmd)
+
+	started := cj.clock.Now()
+	err := cj.exec(ctx, cmd)
+	duration := cj.clock.Since(started)
+
+	exitCode := -1
+	if cmd.ProcessState != nil {
+		exitCode = cmd.ProcessState.ExitCode()
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		err = fmt.Errorf("command %s: %w", cj.spec.Path, ctx.Err())
+	case exitCode > 0:
+		err = &CommandError{ExitCode: exitCode, Tail: out.tail(commandTailSize)}
+	case err != nil:
+		err = fmt.Errorf("command %s: %w", cj.spec.Path, err)
+	}
+
+	cj.upload(run, cj.report(out, started, duration, exitCode, err))
+
+	return err
+}
+
+// exec start the command and wait for it, on context done it is terminated then killed after the grace period
+func (cj *commandJob) exec(ctx context.Context, cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+
+	if err := terminate(cmd); err != nil {
+		log.Printf("cron: failed to terminate command %s: %v\n", cj.spec.Path, err)
+	}
+
+	grace := cj.clock.NewTimer(cj.spec.KillGrace)
+	defer grace.Stop()
+
+	select {
+	case err := <-done:
+		return err
+	case <-grace.C():
+	}
+
+	if err := kill(cmd); err != nil {
+		log.Printf("cron: failed to kill command %s: %v\n", cj.spec.Path, err)
+	}
+
+	return <-done
+}
+
+// report returns the log of the attempt: the command, its output and how it ended
+func (cj *commandJob) report(out *outputBuffer, started time.Time, duration time.Duration, exitCode int, err error) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "$ %s\n", cj.spec)
+	fmt.Fprintf(&b, "started: %s\n\n", started.Format(time.RFC3339))
+
+	out.mu.Lock()
+	b.Write(out.buf.Bytes())
+	if out.dropped > 0 {
+		fmt.Fprintf(&b, "\n... %d bytes dropped\n", out.dropped)
+	}
+	out.mu.Unlock()
+
+	fmt.Fprintf(&b, "\nexit code: %d\n", exitCode)
+	fmt.Fprintf(&b, "duration: %s\n", duration)
+	if err != nil {
+		fmt.Fprintf(&b, "error: %v\n", err)
+	}
+
+	return b.Bytes()
+}
+
+func (cj *commandJob) upload(run RunInfo, data []byte) {
+	if cj.st == nil {
+		return
+	}
+
+	name := commandLogName(cj.prefix, run)
+
+	// job context may already be cancelled, the log should still be uploaded
+	if err := cj.st.Put(context.Background(), cj.bucket, name, data, false, "text/plain; charset=utf-8"); err != nil {
+		log.Printf("cron: failed to upload log of run %s of job %s: %v\n", run.ID, run.Job, err)
+	}
+}
+
+// commandLogName returns the object name of the log of the run attempt
+func commandLogName(prefix string, run RunInfo) string {
+	return path.Join(prefix, run.Job, run.ID, fmt.Sprintf("attempt-%d.log", run.Attempt))
+}
+
+// commandEnv returns the environment describing the run to the command
+func commandEnv(run RunInfo, params map[string]string) []string {
+	env := []string{
+		"CRON_JOB=" + run.Job,
+		"CRON_RUN_ID=" + run.ID,
+		"CRON_ATTEMPT=" + strconv.Itoa(run.Attempt),
+	}
+
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		env = append(env, "CRON_PARAM_"+strings.ToUpper(k)+"="+params[k])
+	}
+
+	return env
+}
+
+// outputBuffer keep stdout and stderr interleaved as written, up to max bytes
+type outputBuffer struct {
+	max int
+
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	dropped int
+}
+
+func (o *outputBuffer) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	keep := len(p)
+	if room := o.max - o.buf.Len(); keep > room {
+		keep = room
+	}
+	if keep < 0 {
+		keep = 0
+	}
+
+	o.buf.Write(p[:keep])
+	o.dropped += len(p) - keep
+
+	return len(p), nil
+}
+
+// tail returns the last n bytes of output, trimmed
+func (o *outputBuffer) tail(n int) string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	b := o.buf.Bytes()
+	if len(b) > n {
+		b = b[len(b)-n:]
+	}
+
+	return strings.TrimSpace(string(b))
+}
@@ -0,0 +1,19 @@
+//go:build !unix
+
+package cron_jobs
+
+import (
+	"os/exec"
+)
+
+// setProcessGroup is not supported, children of the command are not stopped with it
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// terminate stop the command, there is no graceful signal on this platform
+func terminate(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
+
+func kill(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
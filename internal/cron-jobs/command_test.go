@@ -0,0 +1,175 @@
+package cron_jobs_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vldcreation/sample-cron-go/internal/clock"
+	cron_jobs "github.com/vldcreation/sample-cron-go/internal/cron-jobs"
+)
+
+func TestCommandJob(t *testing.T) {
+	st := &memStorage{objects: make(map[string][]byte)}
+
+	// run the command once with the given params, returns its error and uploaded log
+	run := func(t *testing.T, spec cron_jobs.CommandSpec, params map[string]string) (error, string) {
+		t.Helper()
+
+		c := cron_jobs.NewCron()
+		defer c.Stop()
+
+		if _, err := c.AddJobWithCron("0 0 1 1 *", cron_jobs.NewCommandJob(spec, cron_jobs.WithCommandLogs(st, "bucket", "logs")),
+			cron_jobs.WithName("cleanup")); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		runID, err := c.Trigger("cleanup", params)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		var records []cron_jobs.RunRecord
+		for deadline := time.Now().Add(5 * time.Second); len(records) == 0 && time.Now().Before(deadline); {
+			time.Sleep(5 * time.Millisecond)
+			records, _ = c.History().Query(context.Background(), cron_jobs.HistoryQuery{RunID: runID})
+		}
+		if len(records) != 1 {
+			t.Fatalf("expected 1 record, got %+v", records)
+		}
+
+		st.mu.Lock()
+		defer st.mu.Unlock()
+
+		var runErr error
+		if records[0].Error != "" {
+			runErr = errors.New(records[0].Error)
+		}
+		return runErr, string(st.objects["bucket/logs/cleanup/"+runID+"/attempt-1.log"])
+	}
+
+	t.Run("should upload combined output of successful command", func(t *testing.T) {
+		err, logs := run(t, cron_jobs.CommandSpec{
+			Path: "/bin/sh",
+			Args: []string{"-c", `echo "out $GREETING $CRON_PARAM_OBJECT"; echo err >&2; pwd`},
+			Env:  []string{"GREETING=hello"},
+			Dir:  "/",
+		}, map[string]string{"object": "a.jpeg"})
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		for _, expected := range []string{"out hello a.jpeg\n", "err\n", "/\n", "exit code: 0\n"} {
+			if !strings.Contains(logs, expected) {
+				t.Errorf("expected log containing %q, got %q", expected, logs)
+			}
+		}
+	})
+
+	t.Run("should fail run on non zero exit code", func(t *testing.T) {
+		err, logs := run(t, cron_jobs.CommandSpec{
+			Path: "/bin/sh",
+			Args: []string{"-c", "echo disk full; exit 3"},
+		}, nil)
+		if err == nil || err.Error() != "command exited with code 3: disk full" {
+			t.Errorf("expected exit code 3 with disk full, got %v", err)
+		}
+		if !strings.Contains(logs, "exit code: 3\n") {
+			t.Errorf("expected log with exit code 3, got %q", logs)
+		}
+	})
+
+	t.Run("should kill command ignoring SIGTERM after grace period", func(t *testing.T) {
+		started := time.Now()
+		err, logs := run(t, cron_jobs.CommandSpec{
+			Path:      "/bin/sh",
+			Args:      []string{"-c", `trap "" TERM; echo waiting; sleep 5`},
+			Timeout:   200 * time.Millisecond,
+			KillGrace: 200 * time.Millisecond,
+		}, nil)
+
+		if err == nil || !strings.Contains(err.Error(), context.DeadlineExceeded.Error()) {
+			t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+		}
+		if elapsed := time.Since(started); elapsed > 3*time.Second {
+			t.Errorf("expected command killed after grace period, took %s", elapsed)
+		}
+		if !strings.Contains(logs, "waiting\n") {
+			t.Errorf("expected log containing output before the kill, got %q", logs)
+		}
+	})
+
+	t.Run("should time command and its grace period by its clock", func(t *testing.T) {
+		clk := clock.NewFake(time.Date(2023, 6, 19, 9, 0, 0, 0, time.UTC))
+		ready := filepath.Join(t.TempDir(), "ready")
+		st := &memStorage{objects: make(map[string][]byte)}
+
+		fn := cron_jobs.NewCommandJob(cron_jobs.CommandSpec{
+			Path:      "/bin/sh",
+			Args:      []string{"-c", `trap "" TERM; touch "$READY"; sleep 5`},
+			Env:       []string{"READY=" + ready},
+			KillGrace: time.Minute,
+		}, cron_jobs.WithCommandClock(clk), cron_jobs.WithCommandLogs(st, "bucket", "logs"))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			done <- fn(ctx)
+		}()
+
+		for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(5 * time.Millisecond) {
+			if _, err := os.Stat(ready); err == nil {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("expected command to start")
+			}
+		}
+		cancel()
+
+		// the command ignores SIGTERM, it is killed once the grace period elapsed on the clock
+		clk.BlockUntil(1)
+		select {
+		case err := <-done:
+			t.Fatalf("expected command to wait for the grace period, got %v", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+		clk.Advance(time.Minute)
+
+		select {
+		case err := <-done:
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("expected %v, got %v", context.Canceled, err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expected command to be killed")
+		}
+
+		st.mu.Lock()
+		defer st.mu.Unlock()
+		for name, log := range st.objects {
+			if !strings.Contains(string(log), "started: 2023-06-19T09:00:00Z") || !strings.Contains(string(log), "duration: 1m0s") {
+				t.Errorf("expected %s to report a run of 1m0s, got %q", name, log)
+			}
+		}
+		if len(st.objects) != 1 {
+			t.Errorf("expected 1 log, got %d", len(st.objects))
+		}
+	})
+
+	t.Run("should report command not found", func(t *testing.T) {
+		err := cron_jobs.NewCommandJob(cron_jobs.CommandSpec{Path: "/nonexistent/command"})(context.Background())
+		if err == nil || errors.Is(err, cron_jobs.ErrCommandFailed) {
+			t.Errorf("expected start error, got %v", err)
+		}
+
+		err = cron_jobs.NewCommandJob(cron_jobs.CommandSpec{Path: "/bin/sh", Args: []string{"-c", "exit 1"}})(context.Background())
+		if !errors.Is(err, cron_jobs.ErrCommandFailed) {
+			t.Errorf("expected %v, got %v", cron_jobs.ErrCommandFailed, err)
+		}
+	})
+}
@@ -0,0 +1,23 @@
+//go:build unix
+
+package cron_jobs
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup start the command in its own process group, so its children are stopped with it
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// terminate ask the process group of the command to exit
+func terminate(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+}
+
+// kill stop the process group of the command
+func kill(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
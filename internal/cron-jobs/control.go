@@ -0,0 +1,200 @@
+package cron_jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/vldcreation/sample-cron-go/internal/pubsubs"
+)
+
+var ErrUnknownAction = errors.New("cron: unknown control action")
+
+// control actions
+const (
+	ActionTrigger    = "trigger"
+	ActionPause      = "pause"
+	ActionResume     = "resume"
+	ActionReschedule = "reschedule"
+	ActionStop       = "stop"
+)
+
+// Command is a control message, published as JSON on the control topic.
+// Job empty means the whole scheduler, trigger and reschedule require a job.
+type Command struct {
+	ID     string `json:"id"`
+	Action string `json:"action"`
+	Job    string `json:"job,omitempty"`
+	// Params override the job params of a triggered run
+	Params map[string]string `json:"params,omitempty"`
+	// Schedule and Timezone of a reschedule
+	Schedule string `json:"schedule,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// Ack is published back for every command received
+type Ack struct {
+	CommandID string    `json:"command_id"`
+	Action    string    `json:"action"`
+	Job       string    `json:"job,omitempty"`
+	OK        bool      `json:"ok"`
+	Error     string    `json:"error,omitempty"`
+	RunID     string    `json:"run_id,omitempty"`
+	Instance  string    `json:"instance"`
+	At        time.Time `json:"at"`
+}
+
+// Controller apply commands received from pub/sub to the scheduler
+type Controller struct {
+	cron     *Cron
+	sub      pubsubs.Subscriberer
+	pub      pubsubs.Publisher
+	topic    string
+	ackTopic string
+	instance string
+	// onStop is called by a stop command without job, default to stopping the scheduler
+	onStop func()
+}
+
+// ControlOption configure the Controller
+type ControlOption func(ctl *Controller)
+
+// WithAckTopic publish acknowledgements to the given topic, empty disable them
+func WithAckTopic(topic string) ControlOption {
+	return func(ctl *Controller) {
+		ctl.ackTopic = topic
+	}
+}
+
+// WithOnStop replace what a scheduler stop command does, e.g. cancel the app context
+func WithOnStop(fn func()) ControlOption {
+	return func(ctl *Controller) {
+		ctl.onStop = fn
+	}
+}
+
+// WithInstance set the instance name reported in acknowledgements, default to InstanceID
+func WithInstance(name string) ControlOption {
+	return func(ctl *Controller) {
+		ctl.instance = name
+	}
+}
+
+// NewController create controller consuming commands from topic
+func NewController(c *Cron, sub pubsubs.Subscriberer, pub pubsubs.Publisher, topic string, opts ...ControlOption) *Controller {
+	ctl := &Controller{
+		cron:     c,
+		sub:      sub,
+		pub:      pub,
+		topic:    topic,
+		instance: InstanceID(),
+		onStop:   c.Stop,
+	}
+	for _, opt := range opts {
+		opt(ctl)
+	}
+
+	return ctl
+}
+
+// Run consume commands until ctx is done. Every instance has its own subscription to the topic,
+// named after the topic and the instance, so a command reaches all of them
+func (ctl *Controller) Run(ctx context.Context) error {
+	return ctl.sub.Subscribe(ctx, ctl.receive,
+		pubsubs.WithTopic(ctl.topic),
+		pubsubs.WithSubscription(ctl.subscription()),
+	)
+}
+
+// subscription is the subscription of this instance to the control topic
+func (ctl *Controller) subscription() string {
+	return ctl.topic + "-" + ctl.instance
+}
+
+func (ctl *Controller) receive(ctx context.Context, msg *pubsubs.Message) {
+	var cmd Command
+	if err := json.Unmarshal(msg.Data, &cmd); err != nil {
+		log.Printf("cron: invalid command %s: %v\n", msg.ID, err)
+		ctl.ack(ctx, Ack{CommandID: msg.ID, Error: fmt.Sprintf("invalid command: %v", err)})
+		return
+	}
+
+	if cmd.ID == "" {
+		cmd.ID = msg.ID
+	}
+
+	ctl.ack(ctx, ctl.Handle(cmd))
+}
+
+// Handle apply a single command and returns its acknowledgement
+func (ctl *Controller) Handle(cmd Command) Ack {
+	ack := Ack{
+		CommandID: cmd.ID,
+		Action:    cmd.Action,
+		Job:       cmd.Job,
+	}
+
+	log.Printf("cron: command %s received: %s %s\n", cmd.ID, cmd.Action, cmd.Job)
+
+	var err error
+	switch cmd.Action {
+	case ActionTrigger:
+		ack.RunID, err = ctl.cron.Trigger(cmd.Job, cmd.Params)
+	case ActionPause:
+		if cmd.Job == "" {
+			ctl.cron.PauseAll()
+		} else {
+			err = ctl.cron.Pause(cmd.Job)
+		}
+	case ActionResume:
+		if cmd.Job == "" {
+			ctl.cron.ResumeAll()
+		} else {
+			err = ctl.cron.Resume(cmd.Job)
+		}
+	case ActionReschedule:
+		err = ctl.cron.UpdateSchedule(cmd.Job, cmd.Schedule, WithTimezone(cmd.Timezone))
+	case ActionStop:
+		if cmd.Job == "" {
+			// stop waits for running jobs, do not block the subscriber
+			go ctl.onStop()
+		} else {
+			err = ctl.cron.StopJob(cmd.Job)
+		}
+	default:
+		err = fmt.Errorf("%w: %q", ErrUnknownAction, cmd.Action)
+	}
+
+	ack.OK = err == nil
+	if err != nil {
+		ack.Error = err.Error()
+	}
+
+	return ack
+}
+
+func (ctl *Controller) ack(ctx context.Context, ack Ack) {
+	if ctl.ackTopic == "" {
+		return
+	}
+
+	ack.Instance = ctl.instance
+	ack.At = ctl.cron.clock.Now()
+
+	data, err := json.Marshal(ack)
+	if err != nil {
+		log.Printf("cron: failed to encode ack of command %s: %v\n", ack.CommandID, err)
+		return
+	}
+
+	if err := ctl.pub.Publish(ctx, &pubsubs.Message{
+		Topic:     ctl.ackTopic,
+		Data:      data,
+		Attribute: map[string]string{"command_id": ack.CommandID},
+	}); err != nil {
+		log.Printf("cron: failed to publish ack of command %s: %v\n", ack.CommandID, err)
+	}
+}
@@ -0,0 +1,141 @@
+package cron_jobs_test
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	cron_jobs "github.com/vldcreation/sample-cron-go/internal/cron-jobs"
+	"github.com/vldcreation/sample-cron-go/internal/pubsubs"
+)
+
+// fakeBroker deliver published commands to the subscriber and keep the acks
+type fakeBroker struct {
+	commands chan *pubsubs.Message
+
+	mu   sync.Mutex
+	acks []cron_jobs.Ack
+}
+
+func (b *fakeBroker) Subscribe(ctx context.Context, handler func(context.Context, *pubsubs.Message), opts ...pubsubs.Option) error {
+	for {
+		select {
+		case msg := <-b.commands:
+			handler(ctx, msg)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (b *fakeBroker) Publish(ctx context.Context, msg *pubsubs.Message) error {
+	var ack cron_jobs.Ack
+	if err := json.Unmarshal(msg.Data, &ack); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.acks = append(b.acks, ack)
+	b.mu.Unlock()
+
+	return nil
+}
+
+func (b *fakeBroker) send(t *testing.T, cmd cron_jobs.Command) cron_jobs.Ack {
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	b.commands <- &pubsubs.Message{ID: cmd.ID, Data: data}
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		b.mu.Lock()
+		for _, ack := range b.acks {
+			if ack.CommandID == cmd.ID {
+				b.mu.Unlock()
+				return ack
+			}
+		}
+		b.mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	t.Fatalf("expected ack of command %s", cmd.ID)
+	return cron_jobs.Ack{}
+}
+
+func TestController(t *testing.T) {
+	c := cron_jobs.NewCron()
+	defer c.Stop()
+
+	got := make(chan string, 1)
+	if _, err := c.AddJobWithCron("0 0 1 1 *", func(ctx context.Context) error {
+		got <- cron_jobs.Param(ctx, "object", "")
+		return nil
+	}, cron_jobs.WithName("resign"), cron_jobs.WithParams(map[string]string{"object": "a.jpeg"})); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	broker := &fakeBroker{commands: make(chan *pubsubs.Message)}
+	ctl := cron_jobs.NewController(c, broker, broker, "cron-control", cron_jobs.WithAckTopic("cron-ack"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go ctl.Run(ctx)
+
+	t.Run("should trigger job with params", func(t *testing.T) {
+		ack := broker.send(t, cron_jobs.Command{ID: "1", Action: cron_jobs.ActionTrigger, Job: "resign", Params: map[string]string{"object": "b.jpeg"}})
+		if !ack.OK || ack.RunID == "" {
+			t.Fatalf("expected ok ack with run id, got %+v", ack)
+		}
+
+		select {
+		case object := <-got:
+			if object != "b.jpeg" {
+				t.Errorf("expected b.jpeg, got %s", object)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected job to run")
+		}
+	})
+
+	t.Run("should pause and resume job", func(t *testing.T) {
+		if ack := broker.send(t, cron_jobs.Command{ID: "2", Action: cron_jobs.ActionPause, Job: "resign"}); !ack.OK {
+			t.Fatalf("expected ok ack, got %+v", ack)
+		}
+
+		j, _ := c.Job("resign")
+		if !j.Paused() {
+			t.Errorf("expected job to be paused")
+		}
+
+		if ack := broker.send(t, cron_jobs.Command{ID: "3", Action: cron_jobs.ActionResume}); !ack.OK {
+			t.Fatalf("expected ok ack, got %+v", ack)
+		}
+		if j.Paused() {
+			t.Errorf("expected job to be resumed")
+		}
+	})
+
+	t.Run("should reschedule job", func(t *testing.T) {
+		if ack := broker.send(t, cron_jobs.Command{ID: "4", Action: cron_jobs.ActionReschedule, Job: "resign", Schedule: "*/5 * * * *", Timezone: "UTC"}); !ack.OK {
+			t.Fatalf("expected ok ack, got %+v", ack)
+		}
+
+		if info := c.List()[0]; info.Schedule != "CRON_TZ=UTC */5 * * * *" {
+			t.Errorf("expected new schedule, got %s", info.Schedule)
+		}
+	})
+
+	t.Run("should reject unknown job and action", func(t *testing.T) {
+		if ack := broker.send(t, cron_jobs.Command{ID: "5", Action: cron_jobs.ActionTrigger, Job: "unknown"}); ack.OK || ack.Error == "" {
+			t.Errorf("expected error ack, got %+v", ack)
+		}
+		if ack := broker.send(t, cron_jobs.Command{ID: "6", Action: "restart"}); ack.OK || ack.Error == "" {
+			t.Errorf("expected error ack, got %+v", ack)
+		}
+	})
+}
@@ -0,0 +1,584 @@
+package cron_jobs
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrInvalidCrontab = errors.New("cron: invalid crontab")
+
+// DefaultCrontabShell run the crontab commands unless SHELL is set
+var DefaultCrontabShell = "/bin/sh"
+
+var (
+	// envLine match an environment assignment: NAME = value
+	envLine = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)\s*=\s*(.*)$`)
+
+	crontabMacros = map[string]bool{
+		"@reboot":   true,
+		"@yearly":   true,
+		"@annually": true,
+		"@monthly":  true,
+		"@weekly":   true,
+		"@daily":    true,
+		"@midnight": true,
+		"@hourly":   true,
+	}
+)
+
+// CrontabEntry is a command line of a crontab file
+type CrontabEntry struct {
+	Line int
+	// Spec is the 5 fields schedule or the macro, e.g. "*/5 * * * *" or "@hourly"
+	Spec string
+	// Command is run by Shell -c, Stdin is the text after the first unescaped %
+	Command string
+	Stdin   string
+	Shell   string
+	// Env are the assignments preceding the entry, as KEY=VALUE
+	Env []string
+	// Timezone is set by CRON_TZ, empty means the scheduler location
+	Timezone string
+}
+
+// Reboot reports whether the entry runs once on start instead of on a schedule
+func (e CrontabEntry) Reboot() bool {
+	return e.Spec == "@reboot"
+}
+
+// key identify the entry by its content, so an unchanged entry keeps its job when lines move
+func (e CrontabEntry) key() string {
+	h := sha1.New()
+	for _, part := range append([]string{e.Spec, e.Command, e.Stdin, e.Shell, e.Timezone}, e.Env...) {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// CrontabLineError is an invalid line of a crontab file
+type CrontabLineError struct {
+	Line int
+	Text string
+	Err  error
+}
+
+func (e CrontabLineError) Error() string {
+	return fmt.Sprintf("line %d: %v: %q", e.Line, e.Err, e.Text)
+}
+
+// CrontabError list every invalid line of a crontab file
+type CrontabError struct {
+	Path  string
+	Lines []CrontabLineError
+}
+
+func (e *CrontabError) Error() string {
+	msgs := make([]string, 0, len(e.Lines))
+	for _, l := range e.Lines {
+		msgs = append(msgs, l.Error())
+	}
+
+	return fmt.Sprintf("crontab %s: %s", e.Path, strings.Join(msgs, "; "))
+}
+
+func (e *CrontabError) Is(target error) bool {
+	return target == ErrInvalidCrontab
+}
+
+// ParseCrontab parse a Vixie cron style crontab: comments, NAME=value assignments
+// (SHELL and CRON_TZ apply to the following entries), 5 fields specs and macros like @hourly or @reboot.
+// An unescaped % in the command ends it, the rest is the standard input with % as new lines.
+// Every invalid line is reported in CrontabError
+func ParseCrontab(r io.Reader) ([]CrontabEntry, error) {
+	var (
+		entries []CrontabEntry
+		invalid []CrontabLineError
+		env     []string
+		shell   = DefaultCrontabShell
+		tz      string
+	)
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		text := scanner.Text()
+		line := strings.TrimSpace(text)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if m := envLine.FindStringSubmatch(line); m != nil {
+			name, value := m[1], unquote(strings.TrimSpace(m[2]))
+			switch name {
+			case "SHELL":
+				shell = value
+			case "CRON_TZ":
+				if _, err := time.LoadLocation(value); err != nil {
+					invalid = append(invalid, CrontabLineError{Line: n, Text: text, Err: err})
+					continue
+				}
+				tz = value
+			}
+			env = append(env, name+"="+value)
+			continue
+		}
+
+		entry, err := parseCrontabEntry(line, tz)
+		if err != nil {
+			invalid = append(invalid, CrontabLineError{Line: n, Text: text, Err: err})
+			continue
+		}
+
+		entry.Line = n
+		entry.Shell = shell
+		entry.Timezone = tz
+		entry.Env = append([]string(nil), env...)
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(invalid) > 0 {
+		return entries, &CrontabError{Lines: invalid}
+	}
+
+	return entries, nil
+}
+
+func parseCrontabEntry(line, tz string) (CrontabEntry, error) {
+	var entry CrontabEntry
+
+	var command string
+	if strings.HasPrefix(line, "@") {
+		macro := strings.Fields(line)[0]
+		if !crontabMacros[macro] {
+			return entry, fmt.Errorf("%w: unknown macro %s", ErrInvalidSpec, macro)
+		}
+
+		entry.Spec = macro
+		command = afterFields(line, 1)
+	} else {
+		fields := strings.Fields(line)
+		if len(fields) < 6 {
+			return entry, fmt.Errorf("%w: expected 5 fields and a command", ErrInvalidSpec)
+		}
+
+		fields[4] = normalizeWeekdays(fields[4])
+		entry.Spec = strings.Join(fields[:5], " ")
+		command = afterFields(line, 5)
+	}
+
+	if !entry.Reboot() {
+		if _, err := ParseCronSpec(entry.Spec, tz); err != nil {
+			return entry, err
+		}
+	}
+
+	entry.Command, entry.Stdin = splitPercent(strings.TrimSpace(command))
+	if entry.Command == "" {
+		return entry, errors.New("missing command")
+	}
+
+	return entry, nil
+}
+
+// normalizeWeekdays rewrite sunday written as 7 into 0, the only sunday the cron parser knows,
+// e.g. "7" into "0", "5-7" into "5-6,0" and "1-7/2" into "1-6/2,0"
+func normalizeWeekdays(field string) string {
+	items := strings.Split(field, ",")
+	for i, item := range items {
+		rng, step, hasStep := strings.Cut(item, "/")
+		from, to, isRange := strings.Cut(rng, "-")
+		if !isRange {
+			to = from
+		}
+		if to != "7" {
+			continue
+		}
+
+		if from == "7" {
+			items[i] = "0"
+			continue
+		}
+
+		// sunday is part of the stepped range when 7 is on a step from its start
+		start, err := strconv.Atoi(from)
+		if err != nil {
+			continue
+		}
+		every := 1
+		if hasStep {
+			if every, err = strconv.Atoi(step); err != nil || every <= 0 {
+				continue
+			}
+		}
+
+		items[i] = from + "-6"
+		if hasStep {
+			items[i] += "/" + step
+		}
+		if (7-start)%every == 0 {
+			items[i] += ",0"
+		}
+	}
+
+	return strings.Join(items, ",")
+}
+
+// afterFields returns what follows the first n whitespace separated fields of line
+func afterFields(line string, n int) string {
+	rest := line
+	for i := 0; i < n; i++ {
+		rest = strings.TrimLeft(rest, " \t")
+		if j := strings.IndexAny(rest, " \t"); j >= 0 {
+			rest = rest[j:]
+		} else {
+			rest = ""
+		}
+	}
+
+	return rest
+}
+
+// splitPercent split the command at the first unescaped %, later ones are new lines of the input.
+// \% is a literal %
+func splitPercent(command string) (cmd, stdin string) {
+	var (
+		b        strings.Builder
+		inStdin  bool
+		cmdLine  string
+		escaping bool
+	)
+
+	for _, r := range command {
+		switch {
+		case escaping:
+			if r != '%' {
+				b.WriteRune('\\')
+			}
+			b.WriteRune(r)
+			escaping = false
+		case r == '\\':
+			escaping = true
+		case r == '%' && !inStdin:
+			cmdLine = b.String()
+			b.Reset()
+			inStdin = true
+		case r == '%':
+			b.WriteRune('\n')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if escaping {
+		b.WriteRune('\\')
+	}
+
+	if !inStdin {
+		return strings.TrimSpace(b.String()), ""
+	}
+
+	stdin = b.String()
+	if stdin != "" {
+		stdin += "\n"
+	}
+
+	return strings.TrimSpace(cmdLine), stdin
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+
+	return s
+}
+
+// CrontabImporter register the entries of a crontab file as command jobs and keep them in sync with the file
+type CrontabImporter struct {
+	c       *Cron
+	path    string
+	prefix  string
+	cmdOpts []CommandOption
+	jobOpts []JobOption
+
+	mu sync.Mutex
+	// jobs are the imported job names keyed by entry content
+	jobs    map[string]string
+	modTime time.Time
+	size    int64
+	// booted is set once the @reboot entries ran
+	booted bool
+}
+
+// CrontabOption configure the CrontabImporter
+type CrontabOption func(im *CrontabImporter)
+
+// WithCrontabPrefix set the prefix of the job names and their tag, default to "crontab"
+func WithCrontabPrefix(prefix string) CrontabOption {
+	return func(im *CrontabImporter) {
+		im.prefix = prefix
+	}
+}
+
+// WithCrontabCommandOptions configure the command of every entry, e.g. WithCommandLogs
+func WithCrontabCommandOptions(opts ...CommandOption) CrontabOption {
+	return func(im *CrontabImporter) {
+		im.cmdOpts = append(im.cmdOpts, opts...)
+	}
+}
+
+// WithCrontabJobOptions configure the job of every entry, e.g. WithOverlap
+func WithCrontabJobOptions(opts ...JobOption) CrontabOption {
+	return func(im *CrontabImporter) {
+		im.jobOpts = append(im.jobOpts, opts...)
+	}
+}
+
+// NewCrontabImporter create importer of the crontab at path, call Load to import it
+func NewCrontabImporter(c *Cron, path string, opts ...CrontabOption) *CrontabImporter {
+	im := &CrontabImporter{
+		c:      c,
+		path:   path,
+		prefix: "crontab",
+		jobs:   make(map[string]string),
+	}
+	for _, opt := range opts {
+		opt(im)
+	}
+
+	return im
+}
+
+// Load parse the file and sync the jobs: entries gone are removed, new ones are added
+// and unchanged ones keep their job. Nothing changes when the file has an invalid line
+// or an entry cannot be added, e.g. its job name is taken.
+// @reboot entries are not scheduled, they run once on the first successful load,
+// the ones added later wait for the next start of the process
+func (im *CrontabImporter) Load() error {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	f, err := os.Open(im.path)
+	if err != nil {
+		return fmt.Errorf("crontab %s: %w", im.path, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("crontab %s: %w", im.path, err)
+	}
+	im.modTime, im.size = info.ModTime(), info.Size()
+
+	entries, err := ParseCrontab(f)
+	if err != nil {
+		var cerr *CrontabError
+		if errors.As(err, &cerr) {
+			cerr.Path = im.path
+			return cerr
+		}
+		return fmt.Errorf("crontab %s: %w", im.path, err)
+	}
+
+	wanted := make(map[string]CrontabEntry, len(entries))
+	var reboots []CrontabEntry
+	for _, e := range entries {
+		if e.Reboot() {
+			reboots = append(reboots, e)
+			continue
+		}
+		wanted[e.key()] = e
+	}
+
+	// new entries in file order, so jobs added to a running scheduler start in that order
+	var keys []string
+	for key := range wanted {
+		if _, ok := im.jobs[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(a, b int) bool {
+		return wanted[keys[a]].Line < wanted[keys[b]].Line
+	})
+
+	for _, key := range keys {
+		if err := im.check(key, wanted[key]); err != nil {
+			return fmt.Errorf("crontab %s: line %d: %w", im.path, wanted[key].Line, err)
+		}
+	}
+
+	// add before removing anything, a failed add takes the added jobs back
+	added := make(map[string]string, len(keys))
+	for _, key := range keys {
+		e := wanted[key]
+		name, err := im.add(key, e)
+		if err != nil {
+			for _, name := range added {
+				if err := im.c.RemoveJob(name); err != nil {
+					log.Printf("cron: failed to take back job %s of crontab %s: %v\n", name, im.path, err)
+				}
+			}
+			return fmt.Errorf("crontab %s: line %d: %w", im.path, e.Line, err)
+		}
+		added[key] = name
+	}
+
+	removed := 0
+	for key, name := range im.jobs {
+		if _, ok := wanted[key]; ok {
+			continue
+		}
+		if err := im.c.RemoveJob(name); err != nil && !errors.Is(err, ErrJobNotFound) {
+			log.Printf("cron: failed to remove job %s of crontab %s: %v\n", name, im.path, err)
+		}
+		delete(im.jobs, key)
+		removed++
+	}
+	for key, name := range added {
+		im.jobs[key] = name
+	}
+
+	log.Printf("cron: crontab %s loaded, %d job(s) added, %d removed\n", im.path, len(added), removed)
+
+	if !im.booted {
+		im.booted = true
+		for _, e := range reboots {
+			im.reboot(e)
+		}
+	}
+
+	return nil
+}
+
+// command returns the command job of the entry
+func (im *CrontabImporter) command(e CrontabEntry) JobFunc {
+	return NewCommandJob(CommandSpec{
+		Path:  e.Shell,
+		Args:  []string{"-c", e.Command},
+		Env:   e.Env,
+		Stdin: e.Stdin,
+	}, im.cmdOpts...)
+}
+
+// options returns the options of the job of the entry
+func (im *CrontabImporter) options(name string, e CrontabEntry) []JobOption {
+	return append([]JobOption{
+		WithName(name),
+		WithTags(im.prefix),
+		WithTimezone(e.Timezone),
+	}, im.jobOpts...)
+}
+
+// name is the job name of the entry keyed by key
+func (im *CrontabImporter) name(key string) string {
+	return im.prefix + "-" + key[:8]
+}
+
+// check reports whether the entry can be added: its spec is valid in its time zone
+// and its name is free
+func (im *CrontabImporter) check(key string, e CrontabEntry) error {
+	if _, err := parseCronSpec(e.Spec, e.Timezone, im.c.opts.Location); err != nil {
+		return err
+	}
+	if _, err := im.c.Job(im.name(key)); err == nil {
+		return fmt.Errorf("%w: %s", ErrJobExists, im.name(key))
+	}
+
+	return nil
+}
+
+func (im *CrontabImporter) add(key string, e CrontabEntry) (string, error) {
+	name := im.name(key)
+
+	_, err := im.c.AddJobWithCron(e.Spec, im.command(e), im.options(name, e)...)
+	return name, err
+}
+
+// reboot run the @reboot entry once in background. The run is claimed, not locked:
+// every start of the process runs it, a lease would tell the runs of the starts apart by time only
+func (im *CrontabImporter) reboot(e CrontabEntry) {
+	name := im.name(e.key())
+	cfg := newJobConfig(im.options(name, e)...)
+
+	// CRON_TZ was checked by the parser
+	loc := im.c.opts.Location
+	if e.Timezone != "" {
+		loc, _ = time.LoadLocation(e.Timezone)
+	}
+
+	j := &Job{
+		name:    name,
+		tags:    cfg.Tags,
+		cmd:     im.command(e),
+		cfg:     cfg,
+		overlap: newOverlapGuard(cfg.Overlap),
+		breaker: newBreaker(cfg.Breaker),
+		loc:     loc,
+		clock:   im.c.clock,
+	}
+
+	log.Printf("cron: crontab %s line %d runs on start as job %s\n", im.path, e.Line, name)
+	go im.c.execute(j, runRequest{ScheduledAt: im.c.clock.Now(), Claimed: true})
+}
+
+// Jobs returns the names of the imported jobs
+func (im *CrontabImporter) Jobs() []string {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	names := make([]string, 0, len(im.jobs))
+	for _, name := range im.jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// changed reports whether the file was modified since it was loaded
+func (im *CrontabImporter) changed() bool {
+	info, err := os.Stat(im.path)
+	if err != nil {
+		return false
+	}
+
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	return !info.ModTime().Equal(im.modTime) || info.Size() != im.size
+}
+
+// Watch reload the file every time it changes, checked every interval until ctx is done
+func (im *CrontabImporter) Watch(ctx context.Context, interval time.Duration) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-im.c.clock.After(interval):
+		}
+
+		if !im.changed() {
+			continue
+		}
+
+		if err := im.Load(); err != nil {
+			log.Printf("cron: failed to reload crontab, keeping previous jobs: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package cron_jobs_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	cron_jobs "github.com/vldcreation/sample-cron-go/internal/cron-jobs"
+)
+
+func TestParseCrontab(t *testing.T) {
+	t.Run("should parse env, specs, macros and percent signs", func(t *testing.T) {
+		entries, err := cron_jobs.ParseCrontab(strings.NewReader(`
+# nightly maintenance
+MAILTO=""
+SHELL=/bin/bash
+  */5 * * * *   /usr/bin/resign --all   # keep the comment
+CRON_TZ = 'Asia/Jakarta'
+0 2 * * mon-fri  mail -s report ops%Hello,%Report attached\%
+@hourly date +\%H
+@reboot /usr/bin/warmup
+`))
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		expected := []cron_jobs.CrontabEntry{
+			{Line: 5, Spec: "*/5 * * * *", Command: "/usr/bin/resign --all   # keep the comment", Shell: "/bin/bash",
+				Env: []string{"MAILTO=", "SHELL=/bin/bash"}},
+			{Line: 7, Spec: "0 2 * * mon-fri", Command: "mail -s report ops", Stdin: "Hello,\nReport attached%\n", Shell: "/bin/bash",
+				Env: []string{"MAILTO=", "SHELL=/bin/bash", "CRON_TZ=Asia/Jakarta"}, Timezone: "Asia/Jakarta"},
+			{Line: 8, Spec: "@hourly", Command: "date +%H", Shell: "/bin/bash",
+				Env: []string{"MAILTO=", "SHELL=/bin/bash", "CRON_TZ=Asia/Jakarta"}, Timezone: "Asia/Jakarta"},
+			{Line: 9, Spec: "@reboot", Command: "/usr/bin/warmup", Shell: "/bin/bash",
+				Env: []string{"MAILTO=", "SHELL=/bin/bash", "CRON_TZ=Asia/Jakarta"}, Timezone: "Asia/Jakarta"},
+		}
+		if !reflect.DeepEqual(entries, expected) {
+			t.Errorf("expected %+v, got %+v", expected, entries)
+		}
+	})
+
+	t.Run("should read 7 as sunday", func(t *testing.T) {
+		tests := []struct {
+			weekdays string
+			expected string
+		}{
+			{weekdays: "7", expected: "0"},
+			{weekdays: "0", expected: "0"},
+			{weekdays: "5-7", expected: "5-6,0"},
+			{weekdays: "1,3,7", expected: "1,3,0"},
+			{weekdays: "1-7/2", expected: "1-6/2,0"},
+			{weekdays: "2-7/2", expected: "2-6/2"},
+			{weekdays: "7/2", expected: "0"},
+			{weekdays: "mon-fri", expected: "mon-fri"},
+		}
+
+		for _, tt := range tests {
+			entries, err := cron_jobs.ParseCrontab(strings.NewReader("0 9 * * " + tt.weekdays + " /bin/report\n"))
+			if err != nil {
+				t.Errorf("%s: expected nil, got %v", tt.weekdays, err)
+				continue
+			}
+			if expected := "0 9 * * " + tt.expected; entries[0].Spec != expected {
+				t.Errorf("%s: expected %s, got %s", tt.weekdays, expected, entries[0].Spec)
+			}
+		}
+
+		// sunday 2023-06-18 and saturday 2023-06-24
+		sch, _ := cron_jobs.ParseCronSpec("0 9 * * 6,0", "UTC")
+		entries, _ := cron_jobs.ParseCrontab(strings.NewReader("0 9 * * 6-7 /bin/report\n"))
+		got, _ := cron_jobs.ParseCronSpec(entries[0].Spec, "UTC")
+		from := time.Date(2023, 6, 17, 12, 0, 0, 0, time.UTC)
+		if expected, runs := cron_jobs.NextRuns(sch, from, 2), cron_jobs.NextRuns(got, from, 2); !reflect.DeepEqual(runs, expected) {
+			t.Errorf("expected %v, got %v", expected, runs)
+		}
+	})
+
+	t.Run("should report every invalid line with its number", func(t *testing.T) {
+		entries, err := cron_jobs.ParseCrontab(strings.NewReader(`* * * * * /bin/true
+61 * * * * /bin/minute
+@weekdays /bin/macro
+* * * * *
+CRON_TZ=Mars/Olympus
+@daily /bin/daily
+`))
+		if !errors.Is(err, cron_jobs.ErrInvalidCrontab) {
+			t.Fatalf("expected %v, got %v", cron_jobs.ErrInvalidCrontab, err)
+		}
+
+		var cerr *cron_jobs.CrontabError
+		if !errors.As(err, &cerr) {
+			t.Fatalf("expected CrontabError, got %T", err)
+		}
+
+		var lines []int
+		for _, l := range cerr.Lines {
+			lines = append(lines, l.Line)
+		}
+		if expected := []int{2, 3, 4, 5}; !reflect.DeepEqual(lines, expected) {
+			t.Errorf("expected invalid lines %v, got %v (%v)", expected, lines, err)
+		}
+
+		if len(entries) != 2 {
+			t.Errorf("expected 2 valid entries, got %+v", entries)
+		}
+	})
+}
+
+func TestCrontabImporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crontab")
+	write := func(t *testing.T, content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+	}
+
+	c := cron_jobs.NewCron()
+	defer c.Stop()
+
+	im := cron_jobs.NewCrontabImporter(c, path)
+
+	write(t, "0 1 * * * /bin/backup\n0 2 * * * /bin/report\n")
+	if err := im.Load(); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	first := im.Jobs()
+	if len(first) != 2 || len(c.JobsByTag("crontab")) != 2 {
+		t.Fatalf("expected 2 jobs, got %v", first)
+	}
+
+	t.Run("should keep unchanged entries and sync the others", func(t *testing.T) {
+		backup, _ := c.Job(first[0])
+		if !strings.Contains(backup.Info().Schedule, "0 1") {
+			backup, _ = c.Job(first[1])
+		}
+
+		write(t, "# moved\n0 1 * * * /bin/backup\n30 3 * * * /bin/cleanup\n")
+		if err := im.Load(); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		jobs := im.Jobs()
+		if len(jobs) != 2 || len(c.JobsByTag("crontab")) != 2 {
+			t.Fatalf("expected 2 jobs, got %v", jobs)
+		}
+		if j, err := c.Job(backup.Name()); err != nil || j != backup {
+			t.Errorf("expected job %s to be kept, got %v", backup.Name(), err)
+		}
+	})
+
+	t.Run("should keep jobs when file is invalid", func(t *testing.T) {
+		before := im.Jobs()
+
+		write(t, "0 1 * * * /bin/backup\nnot a cron line\n")
+		err := im.Load()
+		if !errors.Is(err, cron_jobs.ErrInvalidCrontab) || !strings.Contains(err.Error(), "line 2") {
+			t.Fatalf("expected %v at line 2, got %v", cron_jobs.ErrInvalidCrontab, err)
+		}
+
+		if after := im.Jobs(); !reflect.DeepEqual(before, after) {
+			t.Errorf("expected %v, got %v", before, after)
+		}
+	})
+
+	t.Run("should keep jobs when an entry cannot be added", func(t *testing.T) {
+		otherPath := filepath.Join(t.TempDir(), "crontab")
+		other := cron_jobs.NewCrontabImporter(c, otherPath)
+
+		if err := os.WriteFile(otherPath, []byte("0 5 * * * /bin/other\n"), 0o644); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if err := other.Load(); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		before := other.Jobs()
+
+		// the backup entry of the first crontab already took the job name
+		if err := os.WriteFile(otherPath, []byte("0 9 * * * /bin/new\n0 1 * * * /bin/backup\n"), 0o644); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if err := other.Load(); !errors.Is(err, cron_jobs.ErrJobExists) {
+			t.Fatalf("expected %v, got %v", cron_jobs.ErrJobExists, err)
+		}
+
+		if after := other.Jobs(); !reflect.DeepEqual(before, after) {
+			t.Errorf("expected %v, got %v", before, after)
+		}
+		if _, err := c.Job(before[0]); err != nil {
+			t.Errorf("expected job %s to be kept, got %v", before[0], err)
+		}
+		if jobs := c.JobsByTag("crontab"); len(jobs) != 3 {
+			t.Errorf("expected 3 jobs, got %d", len(jobs))
+		}
+	})
+
+	t.Run("should run reboot entry on every start", func(t *testing.T) {
+		write(t, "@reboot echo started%ignored\n")
+		// the lease of a previous start must not keep the entry from running again
+		dir := t.TempDir()
+
+		for start := 1; start <= 2; start++ {
+			locker, err := cron_jobs.NewFileLocker(dir, cron_jobs.InstanceID())
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+			c := cron_jobs.NewCron(cron_jobs.WithLocker(locker, time.Second))
+
+			im := cron_jobs.NewCrontabImporter(c, path, cron_jobs.WithCrontabPrefix("boot"))
+			if err := im.Load(); err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+			if jobs := im.Jobs(); len(jobs) != 0 {
+				t.Errorf("expected reboot entry not to be scheduled, got %v", jobs)
+			}
+			c.StartAsync()
+
+			var records []cron_jobs.RunRecord
+			for deadline := time.Now().Add(2 * time.Second); len(records) == 0 && time.Now().Before(deadline); {
+				time.Sleep(10 * time.Millisecond)
+				records, _ = c.History().Query(context.Background(), cron_jobs.HistoryQuery{})
+			}
+
+			// a reload does not run it again
+			if err := im.Load(); err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+			c.Stop()
+
+			records, _ = c.History().Query(context.Background(), cron_jobs.HistoryQuery{})
+			if len(records) != 1 || records[0].Status != cron_jobs.RunSuccess || !strings.HasPrefix(records[0].Job, "boot-") {
+				t.Fatalf("start %d: expected 1 successful run, got %+v", start, records)
+			}
+		}
+	})
+}
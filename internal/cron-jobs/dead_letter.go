@@ -0,0 +1,121 @@
+package cron_jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+var ErrDeadLetterNotFound = errors.New("cron: dead letter not found")
+
+// DeadLetter capture a run that failed after exhausting its retries, so it can be inspected and replayed
+type DeadLetter struct {
+	RunID string `json:"run_id"`
+	Job   string `json:"job"`
+	// Handler is the handler run by an at job, there is no job of that name to replay
+	Handler     string            `json:"handler,omitempty"`
+	Params      map[string]string `json:"params,omitempty"`
+	ScheduledAt time.Time         `json:"scheduled_at"`
+	Manual      bool              `json:"manual"`
+	// Error is the final error, Errors its chain from the outermost to the root cause
+	Error  string   `json:"error"`
+	Errors []string `json:"errors"`
+	// Attempts is the history of every attempt of the run
+	Attempts []RunRecord `json:"attempts"`
+	// ReplayOf is the run id of the dead letter replayed by this run, empty for a regular run
+	ReplayOf string    `json:"replay_of,omitempty"`
+	At       time.Time `json:"at"`
+}
+
+// DeadLetterQueue keep dead letters
+type DeadLetterQueue interface {
+	// Send store the dead letter
+	Send(ctx context.Context, dl DeadLetter) error
+	// Get returns the dead letter of the run, ErrDeadLetterNotFound if there is none
+	Get(ctx context.Context, runID string) (*DeadLetter, error)
+}
+
+// errorChain returns the message of err and of every error it wraps
+func errorChain(err error) []string {
+	var chain []string
+	for ; err != nil; err = errors.Unwrap(err) {
+		chain = append(chain, err.Error())
+	}
+
+	return chain
+}
+
+// deadLetter send the failed run to the dead letter queue, cancelled runs are not dead letters
+func (c *Cron) deadLetter(j *Job, run *RunInfo, req runRequest, params map[string]string, attempts []RunRecord, err error) {
+	if c.opts.DeadLetters == nil || statusOf(err) != RunFailed {
+		return
+	}
+
+	dl := DeadLetter{
+		RunID:       run.ID,
+		Job:         run.Job,
+		Handler:     j.handler,
+		Params:      params,
+		ScheduledAt: run.ScheduledAt,
+		Manual:      run.Manual,
+		Error:       err.Error(),
+		Errors:      errorChain(err),
+		Attempts:    attempts,
+		ReplayOf:    req.ReplayOf,
+		At:          c.clock.Now(),
+	}
+
+	// job context may already be cancelled, the dead letter should still be sent
+	if err := c.opts.DeadLetters.Send(context.Background(), dl); err != nil {
+		log.Printf("cron: failed to send dead letter of run %s of job %s: %v\n", run.ID, run.Job, err)
+	}
+}
+
+// DeadLetter returns the dead letter of the failed run
+func (c *Cron) DeadLetter(ctx context.Context, runID string) (*DeadLetter, error) {
+	if c.opts.DeadLetters == nil {
+		return nil, fmt.Errorf("%w: %s", ErrDeadLetterNotFound, runID)
+	}
+
+	return c.opts.DeadLetters.Get(ctx, runID)
+}
+
+// Replay run the job of the dead letter again with the same params, like Trigger.
+// A dead letter of an at job runs its handler again right away, without a new at job,
+// and one of a graph runs the whole graph again, its steps are not replayed alone.
+// It returns the id of the new run
+func (c *Cron) Replay(ctx context.Context, runID string) (string, error) {
+	dl, err := c.DeadLetter(ctx, runID)
+	if err != nil {
+		return "", err
+	}
+
+	req := runRequest{Params: dl.Params, ReplayOf: dl.RunID}
+
+	var id string
+	if dl.Handler != "" {
+		id, err = c.replayHandler(dl, req)
+	} else {
+		id, err = c.trigger(dl.Job, req)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	log.Printf("cron: dead letter %s of job %s replayed, run %s\n", dl.RunID, dl.Job, id)
+
+	return id, nil
+}
+
+// replayHandler run the handler of the at job dead letter, the run is exclusive to this instance like the at job
+func (c *Cron) replayHandler(dl *DeadLetter, req runRequest) (string, error) {
+	fn, err := c.Handler(dl.Handler)
+	if err != nil {
+		return "", err
+	}
+
+	req.Claimed = true
+	return c.start(c.handlerJob(dl.Job, dl.Handler, fn), req)
+}
@@ -0,0 +1,99 @@
+package cron_jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+
+	"github.com/vldcreation/sample-cron-go/internal/pubsubs"
+	"github.com/vldcreation/sample-cron-go/internal/storage"
+)
+
+// StorageDeadLetters store dead letters as JSON objects under prefix in bucket,
+// and publish them to a topic when one is set
+type StorageDeadLetters struct {
+	st     storage.Storage
+	bucket string
+	prefix string
+
+	pub   pubsubs.Publisher
+	topic string
+}
+
+var _ DeadLetterQueue = (*StorageDeadLetters)(nil)
+
+// DeadLetterOption configure the StorageDeadLetters
+type DeadLetterOption func(q *StorageDeadLetters)
+
+// WithDeadLetterTopic publish every dead letter to topic, empty disable publishing
+func WithDeadLetterTopic(pub pubsubs.Publisher, topic string) DeadLetterOption {
+	return func(q *StorageDeadLetters) {
+		q.pub = pub
+		q.topic = topic
+	}
+}
+
+// NewStorageDeadLetters returns a dead letter queue storing dead letters under prefix in bucket
+func NewStorageDeadLetters(st storage.Storage, bucket, prefix string, opts ...DeadLetterOption) *StorageDeadLetters {
+	q := &StorageDeadLetters{st: st, bucket: bucket, prefix: prefix}
+	for _, opt := range opts {
+		opt(q)
+	}
+
+	return q
+}
+
+func (q *StorageDeadLetters) name(runID string) string {
+	return path.Join(q.prefix, runID+".json")
+}
+
+// Send write the dead letter then publish it, the dead letter is kept even if publishing fails
+func (q *StorageDeadLetters) Send(ctx context.Context, dl DeadLetter) error {
+	data, err := json.Marshal(dl)
+	if err != nil {
+		return fmt.Errorf("dead letter %s: %w", dl.RunID, err)
+	}
+
+	if err := q.st.Put(ctx, q.bucket, q.name(dl.RunID), data, false, "application/json"); err != nil {
+		return fmt.Errorf("dead letter %s: %w", dl.RunID, err)
+	}
+
+	if q.pub == nil || q.topic == "" {
+		return nil
+	}
+
+	if err := q.pub.Publish(ctx, &pubsubs.Message{
+		Topic: q.topic,
+		Data:  data,
+		Attribute: map[string]string{
+			"job":      dl.Job,
+			"run_id":   dl.RunID,
+			"attempts": strconv.Itoa(len(dl.Attempts)),
+			"error":    attributeError(dl.Error),
+		},
+	}); err != nil {
+		return fmt.Errorf("dead letter %s: %w", dl.RunID, err)
+	}
+
+	return nil
+}
+
+func (q *StorageDeadLetters) Get(ctx context.Context, runID string) (*DeadLetter, error) {
+	data, _, err := q.st.Read(ctx, q.bucket, q.name(runID))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrDeadLetterNotFound, runID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("dead letter %s: %w", runID, err)
+	}
+
+	dl := &DeadLetter{}
+	if err := json.Unmarshal(data, dl); err != nil {
+		return nil, fmt.Errorf("dead letter %s: %w", runID, err)
+	}
+
+	return dl, nil
+}
@@ -0,0 +1,294 @@
+package cron_jobs_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	cron_jobs "github.com/vldcreation/sample-cron-go/internal/cron-jobs"
+	"github.com/vldcreation/sample-cron-go/internal/storage"
+)
+
+// memStorage keep objects in memory, only the methods used by the jobs under test are implemented
+type memStorage struct {
+	storage.Storage
+
+	mu      sync.Mutex
+	objects map[string][]byte
+	// version is incremented on every write, the object version is the value at its last write
+	version  int
+	versions map[string]string
+}
+
+func (m *memStorage) Put(ctx context.Context, parent, name string, contents []byte, cacheAble bool, contentType string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.put(parent+"/"+name, contents)
+	return nil
+}
+
+func (m *memStorage) PutIfMatch(ctx context.Context, parent, name string, contents []byte, contentType, version string) (*storage.ObjectInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := parent + "/" + name
+	if m.versions[key] != version {
+		return nil, storage.ErrPreconditionFailed
+	}
+
+	m.put(key, contents)
+	return &storage.ObjectInfo{Name: name, Size: int64(len(contents)), Version: m.versions[key]}, nil
+}
+
+// put store the object, caller must hold m.mu
+func (m *memStorage) put(key string, contents []byte) {
+	if m.versions == nil {
+		m.versions = make(map[string]string)
+	}
+
+	m.version++
+	m.objects[key] = contents
+	m.versions[key] = strconv.Itoa(m.version)
+}
+
+func (m *memStorage) Read(ctx context.Context, parent, name string) ([]byte, *storage.ObjectInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, ok := m.objects[parent+"/"+name]
+	if !ok {
+		return nil, nil, storage.ErrNotFound
+	}
+	return data, &storage.ObjectInfo{Name: name, Size: int64(len(data)), Version: m.versions[parent+"/"+name]}, nil
+}
+
+func (m *memStorage) Delete(ctx context.Context, parent, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.objects, parent+"/"+name)
+	return nil
+}
+
+func (m *memStorage) List(ctx context.Context, parent, prefix, startAfter string, limit int) ([]*storage.ObjectInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var names []string
+	for key := range m.objects {
+		name := strings.TrimPrefix(key, parent+"/")
+		if name != key && strings.HasPrefix(name, prefix) && name > startAfter {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	if limit > 0 && len(names) > limit {
+		names = names[:limit]
+	}
+
+	objects := make([]*storage.ObjectInfo, 0, len(names))
+	for _, name := range names {
+		objects = append(objects, &storage.ObjectInfo{Name: name, Size: int64(len(m.objects[parent+"/"+name]))})
+	}
+	return objects, nil
+}
+
+// waitDeadLetter returns the dead letter of the run once it is sent
+func waitDeadLetter(t *testing.T, c *cron_jobs.Cron, runID string) *cron_jobs.DeadLetter {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		dl, err := c.DeadLetter(context.Background(), runID)
+		if err == nil {
+			return dl
+		}
+		if !errors.Is(err, cron_jobs.ErrDeadLetterNotFound) || time.Now().After(deadline) {
+			t.Fatalf("expected dead letter of run %s, got %v", runID, err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDeadLetters(t *testing.T) {
+	errUnavailable := errors.New("storage unavailable")
+
+	st := &memStorage{objects: make(map[string][]byte)}
+	pub := &recordPublisher{}
+	c := cron_jobs.NewCron(cron_jobs.WithDeadLetters(
+		cron_jobs.NewStorageDeadLetters(st, "bucket", "dead-letters", cron_jobs.WithDeadLetterTopic(pub, "cron-dead-letters")),
+	))
+	defer c.Stop()
+
+	objects := make(chan string, 10)
+	if _, err := c.AddJobWithCron("0 0 1 1 *", func(ctx context.Context) error {
+		object := cron_jobs.Param(ctx, "object", "")
+		objects <- object
+		return fmt.Errorf("resign %s: %w", object, errUnavailable)
+	}, cron_jobs.WithName("resign"), cron_jobs.WithRetry(cron_jobs.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond})); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	runID, err := c.Trigger("resign", map[string]string{"object": "a.jpeg"})
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	t.Run("should store and publish run failing after its retries", func(t *testing.T) {
+		dl := waitDeadLetter(t, c, runID)
+
+		if dl.Job != "resign" || dl.Params["object"] != "a.jpeg" {
+			t.Errorf("expected resign of a.jpeg, got %+v", dl)
+		}
+		// the chain ends with the root cause, under the retries exhausted error
+		if n := len(dl.Errors); n < 2 || dl.Errors[n-2] != "resign a.jpeg: storage unavailable" || dl.Errors[n-1] != "storage unavailable" {
+			t.Errorf("expected chain ending with resign a.jpeg and storage unavailable, got %v", dl.Errors)
+		}
+		if len(dl.Attempts) != 2 || dl.Attempts[1].Attempt != 2 || dl.Attempts[1].Status != cron_jobs.RunFailed {
+			t.Errorf("expected 2 failed attempts, got %+v", dl.Attempts)
+		}
+
+		pub.mu.Lock()
+		defer pub.mu.Unlock()
+		if len(pub.msgs) != 1 {
+			t.Fatalf("expected 1 message, got %d", len(pub.msgs))
+		}
+		if msg := pub.msgs[0]; msg.Topic != "cron-dead-letters" || msg.Attribute["run_id"] != runID || msg.Attribute["attempts"] != "2" {
+			t.Errorf("expected dead letter of run %s on cron-dead-letters, got %+v", runID, msg)
+		}
+	})
+
+	t.Run("should replay dead letter with its params", func(t *testing.T) {
+		for len(objects) > 0 {
+			<-objects
+		}
+
+		replayID, err := c.Replay(context.Background(), runID)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		select {
+		case object := <-objects:
+			if object != "a.jpeg" {
+				t.Errorf("expected a.jpeg, got %s", object)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected job to run")
+		}
+
+		if dl := waitDeadLetter(t, c, replayID); dl.ReplayOf != runID {
+			t.Errorf("expected replay of %s, got %q", runID, dl.ReplayOf)
+		}
+	})
+
+	t.Run("should return not found for unknown run", func(t *testing.T) {
+		if _, err := c.Replay(context.Background(), "unknown"); !errors.Is(err, cron_jobs.ErrDeadLetterNotFound) {
+			t.Errorf("expected %v, got %v", cron_jobs.ErrDeadLetterNotFound, err)
+		}
+	})
+}
+
+func TestReplayDeadLetter(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should replay dead letter of at job with its handler", func(t *testing.T) {
+		st := &memStorage{objects: make(map[string][]byte)}
+		c := cron_jobs.NewCron(
+			cron_jobs.WithDeadLetters(cron_jobs.NewStorageDeadLetters(st, "bucket", "dead-letters")),
+			cron_jobs.WithAtStore(cron_jobs.NewFileAtStore(filepath.Join(t.TempDir(), "at_jobs.json"))),
+		)
+		defer c.Stop()
+
+		objects := make(chan string, 10)
+		if err := c.RegisterHandler("delete-object", func(ctx context.Context) error {
+			objects <- cron_jobs.Param(ctx, "object", "")
+			return errors.New("object locked")
+		}); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		aj, err := c.At(ctx, time.Now(), "delete-object", map[string]string{"object": "a.jpeg"})
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		c.StartAsync()
+
+		for deadline := time.Now().Add(2 * time.Second); aj.Status != cron_jobs.AtFailed; time.Sleep(5 * time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected at job to fail, got %+v", aj)
+			}
+			aj, _ = c.AtJob(ctx, aj.ID)
+		}
+		<-objects
+
+		if dl := waitDeadLetter(t, c, aj.RunID); dl.Job != "at-delete-object" || dl.Handler != "delete-object" {
+			t.Errorf("expected dead letter of handler delete-object, got %+v", dl)
+		}
+
+		replayID, err := c.Replay(ctx, aj.RunID)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		select {
+		case object := <-objects:
+			if object != "a.jpeg" {
+				t.Errorf("expected a.jpeg, got %s", object)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected handler to run")
+		}
+
+		if dl := waitDeadLetter(t, c, replayID); dl.ReplayOf != aj.RunID || dl.Handler != "delete-object" {
+			t.Errorf("expected replay of %s, got %+v", aj.RunID, dl)
+		}
+	})
+
+	t.Run("should replay dead letter of graph with every step", func(t *testing.T) {
+		st := &memStorage{objects: make(map[string][]byte)}
+		c := cron_jobs.NewCron(cron_jobs.WithDeadLetters(cron_jobs.NewStorageDeadLetters(st, "bucket", "dead-letters")))
+		defer c.Stop()
+
+		steps := make(chan string, 10)
+		g := cron_jobs.NewGraph()
+		_ = g.AddNode("resign", func(ctx context.Context) error {
+			steps <- "resign"
+			return nil
+		})
+		_ = g.AddNode("notify", func(ctx context.Context) error {
+			steps <- "notify"
+			return errors.New("smtp down")
+		})
+		_ = g.AddEdge("resign", "notify", cron_jobs.OnSuccess)
+		if _, err := c.AddGraph("0 0 1 1 *", g, cron_jobs.WithName("sync")); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		runID, _ := c.Trigger("sync", nil)
+		if dl := waitDeadLetter(t, c, runID); dl.Job != "sync" || dl.Handler != "" {
+			t.Errorf("expected dead letter of graph sync, got %+v", dl)
+		}
+		for len(steps) > 0 {
+			<-steps
+		}
+
+		replayID, err := c.Replay(ctx, runID)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		waitDeadLetter(t, c, replayID)
+
+		if n := len(steps); n != 2 {
+			t.Errorf("expected both steps to run again, got %d", n)
+		}
+	})
+}
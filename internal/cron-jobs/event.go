@@ -0,0 +1,106 @@
+package cron_jobs
+
+import (
+	"log"
+	"time"
+)
+
+// EventType is a step of the run lifecycle
+type EventType string
+
+const (
+	// EventScheduled a run was fired by the schedule, a trigger or a catch up
+	EventScheduled EventType = "scheduled"
+	// EventStarted the run passed every check and its first attempt starts
+	EventStarted EventType = "started"
+	// EventSucceeded the run finished without error
+	EventSucceeded EventType = "succeeded"
+	// EventFailed the run finished with an error, retries included
+	EventFailed EventType = "failed"
+	// EventRetried an attempt failed and the run is retried
+	EventRetried EventType = "retried"
+	// EventSkipped the run did not start, Error holds the reason
+	EventSkipped EventType = "skipped"
+	// EventBreakerTripped the run failure suspended the job, Error holds the failure
+	EventBreakerTripped EventType = "breaker_tripped"
+	// EventBreakerReset the run success resumed the suspended job
+	EventBreakerReset EventType = "breaker_reset"
+)
+
+// Event describe a step of a job run
+type Event struct {
+	Type  EventType `json:"type"`
+	Job   string    `json:"job"`
+	RunID string    `json:"run_id"`
+	// Attempt is the last attempt made, zero before the first one
+	Attempt     int           `json:"attempt"`
+	Manual      bool          `json:"manual"`
+	ScheduledAt time.Time     `json:"scheduled_at"`
+	StartedAt   time.Time     `json:"started_at"`
+	FinishedAt  time.Time     `json:"finished_at"`
+	Duration    time.Duration `json:"duration"`
+	Error       string        `json:"error,omitempty"`
+	// At is when the event happened
+	At time.Time `json:"at"`
+}
+
+// Listener is called synchronously by the run goroutine, it must not block
+type Listener func(ev Event)
+
+// only wrap fn so it is called for the given event types, none means every event
+func only(fn Listener, types []EventType) Listener {
+	if len(types) == 0 {
+		return fn
+	}
+
+	want := make(map[EventType]bool, len(types))
+	for _, t := range types {
+		want[t] = true
+	}
+
+	return func(ev Event) {
+		if want[ev.Type] {
+			fn(ev)
+		}
+	}
+}
+
+// OnEvent register listener for the given event types, none means every event
+func (c *Cron) OnEvent(fn Listener, types ...EventType) {
+	c.listenerMu.Lock()
+	defer c.listenerMu.Unlock()
+
+	c.listeners = append(c.listeners, only(fn, types))
+}
+
+// emit call every listener interested in the event, a panicking listener does not stop the run
+func (c *Cron) emit(ev Event) {
+	ev.At = c.clock.Now()
+
+	c.listenerMu.RLock()
+	listeners := c.listeners
+	c.listenerMu.RUnlock()
+
+	for _, l := range listeners {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("cron: event listener panic on %s of job %s: %v\n", ev.Type, ev.Job, r)
+				}
+			}()
+			l(ev)
+		}()
+	}
+}
+
+// runEvent returns event of the given type describing the run
+func runEvent(typ EventType, run *RunInfo) Event {
+	return Event{
+		Type:        typ,
+		Job:         run.Job,
+		RunID:       run.ID,
+		Attempt:     run.Attempt,
+		Manual:      run.Manual,
+		ScheduledAt: run.ScheduledAt,
+	}
+}
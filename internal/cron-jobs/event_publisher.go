@@ -0,0 +1,168 @@
+package cron_jobs
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/vldcreation/sample-cron-go/internal/pubsubs"
+)
+
+// DefaultEventQueueSize is how many events the publisher buffers before dropping
+var DefaultEventQueueSize = 256
+
+// maxAttributeError is how many bytes of an error are kept in a message attribute,
+// pub/sub limit attribute values to 1024 bytes, the full error is in the data
+const maxAttributeError = 1024
+
+// EventPublisher is a listener publishing every event as JSON to a topic.
+// Events are published in the background so a slow broker does not delay runs,
+// they are dropped when the queue is full
+type EventPublisher struct {
+	pub      pubsubs.Publisher
+	topic    string
+	instance string
+	size     int
+
+	queue chan Event
+	done  chan struct{}
+	once  sync.Once
+}
+
+// EventPublisherOption configure the EventPublisher
+type EventPublisherOption func(p *EventPublisher)
+
+// WithEventQueueSize set how many events are buffered, default to DefaultEventQueueSize
+func WithEventQueueSize(n int) EventPublisherOption {
+	return func(p *EventPublisher) {
+		p.size = n
+	}
+}
+
+// WithEventInstance set the instance name reported in attributes, default to InstanceID
+func WithEventInstance(name string) EventPublisherOption {
+	return func(p *EventPublisher) {
+		p.instance = name
+	}
+}
+
+// NewEventPublisher start publishing events to topic, register Handle as listener
+// and Close it on shutdown
+func NewEventPublisher(pub pubsubs.Publisher, topic string, opts ...EventPublisherOption) *EventPublisher {
+	p := &EventPublisher{
+		pub:      pub,
+		topic:    topic,
+		instance: InstanceID(),
+		size:     DefaultEventQueueSize,
+		done:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if p.size < 1 {
+		p.size = 1
+	}
+	p.queue = make(chan Event, p.size)
+
+	go p.loop()
+
+	return p
+}
+
+// Handle queue the event, it never blocks
+func (p *EventPublisher) Handle(ev Event) {
+	select {
+	case p.queue <- ev:
+	default:
+		log.Printf("cron: event queue full, dropped %s event of job %s run %s\n", ev.Type, ev.Job, ev.RunID)
+	}
+}
+
+// Close stop accepting events and wait until the queued ones are published or ctx is done.
+// Handle must not be called after Close
+func (p *EventPublisher) Close(ctx context.Context) error {
+	p.once.Do(func() {
+		close(p.queue)
+	})
+
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *EventPublisher) loop() {
+	defer close(p.done)
+
+	for ev := range p.queue {
+		p.publish(ev)
+	}
+}
+
+func (p *EventPublisher) publish(ev Event) {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		log.Printf("cron: failed to encode %s event of job %s: %v\n", ev.Type, ev.Job, err)
+		return
+	}
+
+	// events are published after the run context may be cancelled
+	if err := p.pub.Publish(context.Background(), &pubsubs.Message{
+		Topic:     p.topic,
+		Data:      data,
+		Attribute: p.attributes(ev),
+	}); err != nil {
+		log.Printf("cron: failed to publish %s event of job %s run %s: %v\n", ev.Type, ev.Job, ev.RunID, err)
+	}
+}
+
+// attributes describe the event so subscribers can filter without decoding the data
+func (p *EventPublisher) attributes(ev Event) map[string]string {
+	attrs := map[string]string{
+		"event":    string(ev.Type),
+		"job":      ev.Job,
+		"run_id":   ev.RunID,
+		"manual":   strconv.FormatBool(ev.Manual),
+		"instance": p.instance,
+	}
+
+	if ev.Attempt > 0 {
+		attrs["attempt"] = strconv.Itoa(ev.Attempt)
+	}
+	if !ev.ScheduledAt.IsZero() {
+		attrs["scheduled_at"] = ev.ScheduledAt.UTC().Format(time.RFC3339)
+	}
+	if !ev.StartedAt.IsZero() {
+		attrs["started_at"] = ev.StartedAt.UTC().Format(time.RFC3339Nano)
+	}
+	if !ev.FinishedAt.IsZero() {
+		attrs["finished_at"] = ev.FinishedAt.UTC().Format(time.RFC3339Nano)
+		attrs["duration_ms"] = strconv.FormatInt(ev.Duration.Milliseconds(), 10)
+	}
+	if ev.Error != "" {
+		attrs["error"] = attributeError(ev.Error)
+	}
+
+	return attrs
+}
+
+// attributeError truncate msg to fit in a message attribute, on a rune boundary
+func attributeError(msg string) string {
+	if len(msg) <= maxAttributeError {
+		return msg
+	}
+
+	cut := maxAttributeError - len("...")
+	for cut > 0 && !utf8.RuneStart(msg[cut]) {
+		cut--
+	}
+
+	return msg[:cut] + "..."
+}
@@ -0,0 +1,231 @@
+package cron_jobs_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	cron_jobs "github.com/vldcreation/sample-cron-go/internal/cron-jobs"
+	"github.com/vldcreation/sample-cron-go/internal/pubsubs"
+)
+
+// waitEvent returns the next event of events or fail after a second
+func waitEvent(t *testing.T, events <-chan cron_jobs.Event) cron_jobs.Event {
+	t.Helper()
+
+	select {
+	case ev := <-events:
+		return ev
+	case <-time.After(time.Second):
+		t.Fatalf("expected event")
+		return cron_jobs.Event{}
+	}
+}
+
+func TestCronEvents(t *testing.T) {
+	t.Run("should emit every step of a retried run", func(t *testing.T) {
+		events := make(chan cron_jobs.Event, 10)
+		c := cron_jobs.NewCron(cron_jobs.WithListener(func(ev cron_jobs.Event) {
+			events <- ev
+		}))
+		defer c.Stop()
+
+		attempts := 0
+		if _, err := c.AddJobWithCron("0 0 1 1 *", func(ctx context.Context) error {
+			attempts++
+			if attempts == 1 {
+				return errors.New("storage unavailable")
+			}
+			return nil
+		}, cron_jobs.WithName("resign"), cron_jobs.WithRetry(cron_jobs.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond})); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		runID, err := c.Trigger("resign", nil)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		expected := []cron_jobs.EventType{cron_jobs.EventScheduled, cron_jobs.EventStarted, cron_jobs.EventRetried, cron_jobs.EventSucceeded}
+		for _, typ := range expected {
+			ev := waitEvent(t, events)
+			if ev.Type != typ {
+				t.Fatalf("expected %s, got %s", typ, ev.Type)
+			}
+			if ev.Job != "resign" || ev.RunID != runID || !ev.Manual {
+				t.Errorf("expected manual run %s of resign, got %+v", runID, ev)
+			}
+
+			switch ev.Type {
+			case cron_jobs.EventRetried:
+				if ev.Attempt != 1 || ev.Error != "storage unavailable" {
+					t.Errorf("expected failed attempt 1, got %+v", ev)
+				}
+			case cron_jobs.EventSucceeded:
+				if ev.Attempt != 2 || ev.Error != "" || ev.FinishedAt.Before(ev.StartedAt) {
+					t.Errorf("expected successful attempt 2, got %+v", ev)
+				}
+			}
+		}
+	})
+
+	t.Run("should emit skipped run to listener of skipped events only", func(t *testing.T) {
+		c := cron_jobs.NewCron()
+		defer c.Stop()
+
+		skipped := make(chan cron_jobs.Event, 10)
+		c.OnEvent(func(ev cron_jobs.Event) {
+			skipped <- ev
+		}, cron_jobs.EventSkipped)
+
+		running, release := make(chan struct{}), make(chan struct{})
+		if _, err := c.AddJobWithCron("0 0 1 1 *", func(ctx context.Context) error {
+			close(running)
+			<-release
+			return nil
+		}, cron_jobs.WithName("resign"), cron_jobs.WithOverlap(cron_jobs.OverlapSkip)); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		if _, err := c.Trigger("resign", nil); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		<-running
+
+		runID, err := c.Trigger("resign", nil)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		ev := waitEvent(t, skipped)
+		close(release)
+
+		if ev.Type != cron_jobs.EventSkipped || ev.RunID != runID || ev.Error != "previous run still running" {
+			t.Errorf("expected run %s skipped as previous run still running, got %+v", runID, ev)
+		}
+	})
+
+	t.Run("should run job when listener panics", func(t *testing.T) {
+		c := cron_jobs.NewCron(cron_jobs.WithListener(func(ev cron_jobs.Event) {
+			panic("listener bug")
+		}))
+		defer c.Stop()
+
+		done := make(chan struct{})
+		if _, err := c.AddJobWithCron("0 0 1 1 *", func(ctx context.Context) error {
+			close(done)
+			return nil
+		}, cron_jobs.WithName("resign")); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		if _, err := c.Trigger("resign", nil); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("expected job to run")
+		}
+	})
+}
+
+// recordPublisher keep every published message
+type recordPublisher struct {
+	mu   sync.Mutex
+	msgs []*pubsubs.Message
+}
+
+func (p *recordPublisher) Publish(ctx context.Context, msg *pubsubs.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.msgs = append(p.msgs, msg)
+	return nil
+}
+
+func TestEventPublisher(t *testing.T) {
+	t.Run("should publish event with attributes before close returns", func(t *testing.T) {
+		pub := &recordPublisher{}
+		p := cron_jobs.NewEventPublisher(pub, "cron-events", cron_jobs.WithEventInstance("replica-1"))
+
+		started := time.Date(2023, 6, 19, 9, 0, 0, 0, time.UTC)
+		p.Handle(cron_jobs.Event{
+			Type:        cron_jobs.EventFailed,
+			Job:         "resign",
+			RunID:       "run-1",
+			Attempt:     3,
+			ScheduledAt: started,
+			StartedAt:   started,
+			FinishedAt:  started.Add(1500 * time.Millisecond),
+			Duration:    1500 * time.Millisecond,
+			Error:       "storage unavailable",
+		})
+
+		if err := p.Close(context.Background()); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		if len(pub.msgs) != 1 {
+			t.Fatalf("expected 1 message, got %d", len(pub.msgs))
+		}
+		msg := pub.msgs[0]
+		if msg.Topic != "cron-events" {
+			t.Errorf("expected cron-events, got %s", msg.Topic)
+		}
+
+		expected := map[string]string{
+			"event":        "failed",
+			"job":          "resign",
+			"run_id":       "run-1",
+			"attempt":      "3",
+			"manual":       "false",
+			"scheduled_at": "2023-06-19T09:00:00Z",
+			"started_at":   "2023-06-19T09:00:00Z",
+			"finished_at":  "2023-06-19T09:00:01.5Z",
+			"duration_ms":  "1500",
+			"error":        "storage unavailable",
+			"instance":     "replica-1",
+		}
+		for key, value := range expected {
+			if got := msg.Attribute[key]; got != value {
+				t.Errorf("expected %s=%s, got %s", key, value, got)
+			}
+		}
+
+		var ev cron_jobs.Event
+		if err := json.Unmarshal(msg.Data, &ev); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if ev.RunID != "run-1" || ev.Duration != 1500*time.Millisecond {
+			t.Errorf("expected run-1 lasting 1.5s, got %+v", ev)
+		}
+	})
+
+	t.Run("should truncate long error in attributes only", func(t *testing.T) {
+		pub := &recordPublisher{}
+		p := cron_jobs.NewEventPublisher(pub, "cron-events")
+
+		long := strings.Repeat("é", 1000)
+		p.Handle(cron_jobs.Event{Type: cron_jobs.EventFailed, Job: "resign", RunID: "run-1", Error: long})
+		if err := p.Close(context.Background()); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		attr := pub.msgs[0].Attribute["error"]
+		if len(attr) > 1024 || !utf8.ValidString(attr) || !strings.HasSuffix(attr, "...") {
+			t.Errorf("expected valid error of at most 1024 bytes, got %d bytes", len(attr))
+		}
+
+		var ev cron_jobs.Event
+		if err := json.Unmarshal(pub.msgs[0].Data, &ev); err != nil || ev.Error != long {
+			t.Errorf("expected full error in data, got %d bytes (%v)", len(ev.Error), err)
+		}
+	})
+}
@@ -1,37 +1,423 @@
 package cron_jobs
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/go-co-op/gocron"
+	"github.com/vldcreation/sample-cron-go/internal/clock"
 )
 
 // compile-time interface check
 var _ CronJob = (*Cron)(nil)
 
+var (
+	ErrJobExists   = errors.New("cron: job already exists")
+	ErrJobNotFound = errors.New("cron: job not found")
+)
+
 type Cron struct {
-	s *gocron.Scheduler
+	s    *gocron.Scheduler
+	opts CronOptions
+
+	// mu protects jobs and the scheduler chain, gocron chain is not safe for concurrent use
+	mu        sync.RWMutex
+	jobs      map[string]*Job
+	handlers  map[string]JobFunc
+	calendars map[string]*Calendar
+	seq       int
+
+	// ctx is passed to every job and cancelled on stop
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// runMu protects closing, wg tracks running jobs
+	runMu   sync.Mutex
+	closing bool
+	wg      sync.WaitGroup
+
+	// slots limit concurrent jobs, nil means no limit
+	slots chan struct{}
+
+	clock clock.Clock
+
+	// listenerMu protects listeners, registered by OnEvent at any time
+	listenerMu sync.RWMutex
+	listeners  []Listener
+
+	// at runs the jobs of AtStore once started
+	at atRunner
 }
 
 type CronOptions struct {
+	// Location is the default location used by the scheduler and cron spec
+	// without time zone, default to time.Local
+	Location *time.Location
+
+	// DrainTimeout is how long stop waits for running jobs after cancelling them,
+	// zero means wait until the jobs return
+	DrainTimeout time.Duration
+
+	// MaxConcurrentJobs limit how many jobs run at the same time, zero means no limit
+	MaxConcurrentJobs int
+
+	// History store every job execution, default to in memory store keeping
+	// the last DefaultHistoryRecords records
+	History HistoryStore
+
+	// State persist the last successful run of each job, used to catch up missed runs on start,
+	// and the runs shifted to a business day so they survive restart.
+	// nil disable catch up, shifted runs are then lost when the scheduler stops before them
+	State StateStore
+
+	// Locker make sure only one instance execute a scheduled run, nil disable locking
+	Locker Locker
+	// LockTTL is the lease duration, renewed while the run is running, default to DefaultLockTTL
+	LockTTL time.Duration
+
+	// Middlewares wrap every attempt of every job, the first one is the outermost
+	Middlewares []Middleware
+
+	// Clock tell the time to the scheduler and runs, default to the system clock.
+	// The default history uses it too, pass it to the locker and history store
+	// with WithLockerClock and WithHistoryClock
+	Clock clock.Clock
+
+	// Listeners are called on every run lifecycle event, see Event
+	Listeners []Listener
+
+	// DeadLetters receive runs failing after their retries, nil disable dead letters
+	DeadLetters DeadLetterQueue
+
+	// AtStore persist the one-shot jobs scheduled by At, nil disable them
+	AtStore AtStore
+	// AtPollInterval is how often AtStore is checked for jobs added by other instances,
+	// default to DefaultAtPollInterval
+	AtPollInterval time.Duration
+}
+
+// DefaultHistoryRecords is how many records the default history store keeps
+var DefaultHistoryRecords = 1000
+
+func NewCron(opts ...Option) *Cron {
+	history := NewMemoryHistory(HistoryRetention{MaxRecords: DefaultHistoryRecords})
+	o := CronOptions{
+		Location: time.Local,
+		History:  history,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	clk := clock.OrReal(o.Clock)
+	// the default history drops old records by the cron clock
+	history.clock = clk
+	s := gocron.NewScheduler(o.Location)
+	s.CustomTime(gocronTime{clk})
+	s.CustomTimer(clk.AfterFunc)
+
+	c := &Cron{
+		s:         s,
+		clock:     clk,
+		opts:      o,
+		jobs:      make(map[string]*Job),
+		handlers:  make(map[string]JobFunc),
+		calendars: make(map[string]*Calendar),
+		listeners: append([]Listener(nil), o.Listeners...),
+		ctx:       ctx,
+		cancel:    cancel,
+		at:        atRunner{wake: make(chan struct{}, 1)},
+	}
+
+	if o.AtStore != nil {
+		c.at.owner = InstanceID()
+	}
+
+	if o.MaxConcurrentJobs > 0 {
+		c.slots = make(chan struct{}, o.MaxConcurrentJobs)
+	}
+
+	return c
+}
+
+// AddJobWithInterval register job running every interval, e.g. time.Second * 10 or "10s".
+// Use WithName to give the job a name, otherwise it is generated.
+func (c *Cron) AddJobWithInterval(interval any, cmd JobFunc, opts ...JobOption) (*Job, error) {
+	sch, err := parseInterval(interval)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.addJob(sch, cmd, opts...)
+}
+
+// AddJobWithCron register job using cron expression, e.g. "0 9 * * 1-5" or "@every 15m".
+// Use WithTimezone to evaluate the spec in other time zone than the scheduler location.
+func (c *Cron) AddJobWithCron(spec string, cmd JobFunc, opts ...JobOption) (*Job, error) {
+	cfg := newJobConfig(opts...)
+
+	sch, err := parseCronSpec(spec, cfg.Timezone, c.opts.Location)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.addJob(sch, cmd, opts...)
+}
+
+// AddJobWithSpec register job using either a duration ("10s") or a cron expression ("*/5 * * * *")
+func (c *Cron) AddJobWithSpec(spec string, cmd JobFunc, opts ...JobOption) (*Job, error) {
+	cfg := newJobConfig(opts...)
+
+	sch, err := parseSchedule(spec, cfg.Timezone, c.opts.Location)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.addJob(sch, cmd, opts...)
+}
+
+// AddJob run cmd once immediately in background
+func (c *Cron) AddJob(cmd JobFunc) error {
+	if !c.track() {
+		return ErrCronStopped
+	}
+
+	go func() {
+		defer c.wg.Done()
+
+		if err := invoke(c.ctx, cmd); err != nil {
+			log.Printf("cron: job failed: %v\n", err)
+		}
+	}()
+
+	return nil
+}
+
+// History returns the store recording job executions
+func (c *Cron) History() HistoryStore {
+	return c.opts.History
+}
+
+// Job returns registered job by name
+func (c *Cron) Job(name string) (*Job, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	j, ok := c.jobs[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+
+	return j, nil
+}
+
+// Jobs returns all registered jobs sorted by name
+func (c *Cron) Jobs() []*Job {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	jobs := make([]*Job, 0, len(c.jobs))
+	for _, j := range c.jobs {
+		jobs = append(jobs, j)
+	}
+
+	sort.Slice(jobs, func(a, b int) bool {
+		return jobs[a].name < jobs[b].name
+	})
+
+	return jobs
+}
+
+// List returns snapshot of all registered jobs including their next and last run
+func (c *Cron) List() []JobInfo {
+	jobs := c.Jobs()
+
+	infos := make([]JobInfo, 0, len(jobs))
+	for _, j := range jobs {
+		infos = append(infos, j.Info())
+	}
+
+	return infos
+}
+
+// JobsByTag returns all registered jobs having the given tag
+func (c *Cron) JobsByTag(tag string) []*Job {
+	var jobs []*Job
+	for _, j := range c.Jobs() {
+		if j.HasTag(tag) {
+			jobs = append(jobs, j)
+		}
+	}
+
+	return jobs
 }
 
-func NewCron() *Cron {
-	return &Cron{
-		s: gocron.NewScheduler(time.Local),
+// UpdateSchedule change the schedule of a running job in place.
+// spec is either a duration ("10s") or a cron expression ("*/5 * * * *"),
+// the job keeps its time zone unless WithTimezone is given
+func (c *Cron) UpdateSchedule(name, spec string, opts ...JobOption) error {
+	cfg := newJobConfig(opts...)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	j, ok := c.jobs[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	if j.Retired() {
+		return fmt.Errorf("%w: %s", ErrJobRetired, name)
+	}
+
+	// keep the time zone of the job unless a new one is given
+	loc := j.location()
+	if cfg.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(cfg.Timezone); err != nil {
+			return fmt.Errorf("%w: unknown time zone %q: %v", ErrInvalidSpec, cfg.Timezone, err)
+		}
+	}
+
+	sch, err := parseSchedule(spec, cfg.Timezone, loc)
+	if err != nil {
+		return err
+	}
+
+	// do not run immediately, wait for the new schedule
+	gj, err := c.schedule(sch, j, true)
+	if err != nil {
+		return err
 	}
+
+	j.mu.Lock()
+	old := j.job
+	j.schedule = sch
+	j.job = gj
+	j.loc = loc
+	if cfg.Timezone != "" {
+		j.cfg.Timezone = cfg.Timezone
+	}
+	j.mu.Unlock()
+
+	c.s.RemoveByReference(old)
+
+	return nil
 }
 
-func (c *Cron) AddJobWithInterval(interval any, cmd func()) error {
-	_, err := c.s.Every(interval).Do(cmd)
+// RemoveJob remove the job from the scheduler, a run already in progress is not interrupted
+func (c *Cron) RemoveJob(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	return err
+	j, ok := c.jobs[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+
+	c.s.RemoveByReference(j.job)
+	delete(c.jobs, name)
+
+	return nil
 }
 
-func (c *Cron) AddJob(cmd func()) error {
-	_, err := c.s.Do(cmd)
+func (c *Cron) addJob(sch Schedule, cmd JobFunc, opts ...JobOption) (*Job, error) {
+	j, running, err := c.registerJob(sch, cmd, opts...)
+	if err != nil {
+		return nil, err
+	}
 
-	return err
+	// added to a running scheduler, catch up now instead of on start.
+	// It reads the state store, so it is done without holding c.mu
+	if running {
+		c.catchUp(j)
+		c.resumeDeferred(j)
+	}
+
+	return j, nil
+}
+
+// registerJob create the job and register it to the scheduler,
+// running reports whether the scheduler was already running
+func (c *Cron) registerJob(sch Schedule, cmd JobFunc, opts ...JobOption) (j *Job, running bool, err error) {
+	cfg := newJobConfig(opts...)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.seq++
+	if cfg.Name == "" {
+		cfg.Name = fmt.Sprintf("job-%d", c.seq)
+	}
+
+	if _, ok := c.jobs[cfg.Name]; ok {
+		return nil, false, fmt.Errorf("%w: %s", ErrJobExists, cfg.Name)
+	}
+
+	loc := c.opts.Location
+	if cfg.Timezone != "" {
+		if loc, err = time.LoadLocation(cfg.Timezone); err != nil {
+			return nil, false, fmt.Errorf("%w: unknown time zone %q: %v", ErrInvalidSpec, cfg.Timezone, err)
+		}
+	}
+
+	if cfg.Calendar != "" {
+		if _, ok := c.calendars[cfg.Calendar]; !ok {
+			return nil, false, fmt.Errorf("%w: %s", ErrCalendarNotFound, cfg.Calendar)
+		}
+	}
+
+	j = &Job{
+		name:     cfg.Name,
+		tags:     cfg.Tags,
+		cmd:      cmd,
+		cfg:      cfg,
+		overlap:  newOverlapGuard(cfg.Overlap),
+		breaker:  newBreaker(cfg.Breaker),
+		loc:      loc,
+		clock:    c.clock,
+		schedule: sch,
+	}
+
+	gj, err := c.schedule(sch, j, false)
+	if err != nil {
+		return nil, false, err
+	}
+
+	j.job = gj
+	c.jobs[j.name] = j
+
+	return j, c.s.IsRunning(), nil
+}
+
+// schedule register the job to the scheduler, caller must hold c.mu
+func (c *Cron) schedule(sch Schedule, j *Job, waitForSchedule bool) (*gocron.Job, error) {
+	var s *gocron.Scheduler
+
+	switch v := sch.(type) {
+	case *intervalSchedule:
+		s = c.s.Every(v.every)
+	case *cronSchedule:
+		if v.withSeconds {
+			s = c.s.CronWithSeconds(v.spec)
+		} else {
+			s = c.s.Cron(v.spec)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported schedule %T", ErrInvalidSpec, sch)
+	}
+
+	if waitForSchedule {
+		s = s.WaitForSchedule()
+	}
+
+	return s.Do(c.runJob, j)
 }
 
 func (c *Cron) SetupJob(sch *gocron.Scheduler) *Cron {
@@ -39,11 +425,21 @@ func (c *Cron) SetupJob(sch *gocron.Scheduler) *Cron {
 	return c
 }
 
+// StartWithBlocking start the scheduler and block until it is stopped
 func (c *Cron) StartWithBlocking() {
+	c.catchUpAll()
+	c.startAt()
 	c.s.StartBlocking()
 }
 
+// StartAsync start the scheduler without blocking, missed runs are caught up in background
 func (c *Cron) StartAsync() {
+	if c.s.IsRunning() {
+		return
+	}
+
+	c.catchUpAll()
+	c.startAt()
 	c.s.StartAsync()
 }
 
@@ -51,6 +447,67 @@ func (c *Cron) IsRunning() bool {
 	return c.s.IsRunning()
 }
 
+// Stop stop the scheduler, cancel running jobs and wait for them up to the drain timeout
 func (c *Cron) Stop() {
-	c.s.Stop()
+	if err := c.Shutdown(context.Background()); err != nil {
+		log.Printf("cron: %v\n", err)
+	}
+}
+
+// Run start the scheduler and block until ctx is done, then shutdown gracefully.
+// Use it with signal.NotifyContext to handle SIGINT/SIGTERM.
+func (c *Cron) Run(ctx context.Context) error {
+	c.StartAsync()
+
+	<-ctx.Done()
+
+	return c.Shutdown(context.Background())
+}
+
+// Shutdown stop scheduling new runs, cancel the context of running jobs
+// and wait for them to return until ctx is done or the drain timeout is exceeded
+func (c *Cron) Shutdown(ctx context.Context) error {
+	c.runMu.Lock()
+	c.closing = true
+	c.runMu.Unlock()
+
+	c.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		// gocron waits for its running jobs on stop
+		c.s.Stop()
+		c.wg.Wait()
+		close(done)
+	}()
+
+	if c.opts.DrainTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opts.DrainTimeout)
+		defer cancel()
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %v", ErrDrainTimeout, ctx.Err())
+	}
+}
+
+// gocronTime make the scheduler tell the time with the cron clock
+type gocronTime struct {
+	clock clock.Clock
+}
+
+func (t gocronTime) Now(loc *time.Location) time.Time {
+	return t.clock.Now().In(loc)
+}
+
+func (t gocronTime) Unix(sec, nsec int64) time.Time {
+	return time.Unix(sec, nsec)
+}
+
+func (t gocronTime) Sleep(d time.Duration) {
+	t.clock.Sleep(d)
 }
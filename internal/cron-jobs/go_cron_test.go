@@ -0,0 +1,235 @@
+package cron_jobs_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vldcreation/sample-cron-go/internal/clock"
+	cron_jobs "github.com/vldcreation/sample-cron-go/internal/cron-jobs"
+)
+
+func TestCronRegistry(t *testing.T) {
+	noop := func(ctx context.Context) error { return nil }
+
+	t.Run("should reject duplicate job name", func(t *testing.T) {
+		c := cron_jobs.NewCron()
+		if _, err := c.AddJobWithInterval(time.Minute, noop, cron_jobs.WithName("resign")); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		_, err := c.AddJobWithCron("@daily", noop, cron_jobs.WithName("resign"))
+		if !errors.Is(err, cron_jobs.ErrJobExists) {
+			t.Errorf("expected %v, got %v", cron_jobs.ErrJobExists, err)
+		}
+	})
+
+	t.Run("should list, update and remove job by name", func(t *testing.T) {
+		c := cron_jobs.NewCron()
+		if _, err := c.AddJobWithCron("0 1 * * *", noop, cron_jobs.WithName("cleanup"), cron_jobs.WithTags("storage")); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if _, err := c.AddJobWithInterval("10s", noop, cron_jobs.WithName("resign")); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		jobs := c.List()
+		if len(jobs) != 2 || jobs[0].Name != "cleanup" || jobs[1].Name != "resign" {
+			t.Fatalf("expected [cleanup resign], got %+v", jobs)
+		}
+
+		if got := c.JobsByTag("storage"); len(got) != 1 || got[0].Name() != "cleanup" {
+			t.Errorf("expected [cleanup], got %v", got)
+		}
+
+		if err := c.UpdateSchedule("resign", "*/5 * * * *", cron_jobs.WithTimezone("UTC")); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		j, err := c.Job("resign")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if runs := j.NextRuns(2); len(runs) != 2 || runs[0].Minute()%5 != 0 || runs[1].Sub(runs[0]) != 5*time.Minute {
+			t.Errorf("expected runs every 5 minutes, got %v", runs)
+		}
+
+		if err := c.RemoveJob("resign"); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if _, err := c.Job("resign"); !errors.Is(err, cron_jobs.ErrJobNotFound) {
+			t.Errorf("expected %v, got %v", cron_jobs.ErrJobNotFound, err)
+		}
+	})
+
+	t.Run("should keep time zone of job when updating its schedule", func(t *testing.T) {
+		c := cron_jobs.NewCron()
+
+		j, err := c.AddJobWithCron("0 9 * * *", noop, cron_jobs.WithName("report"), cron_jobs.WithTimezone("Asia/Jakarta"))
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		if err := c.UpdateSchedule("report", "0 10 * * *"); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if got := j.Info().Schedule; got != "CRON_TZ=Asia/Jakarta 0 10 * * *" {
+			t.Errorf("expected CRON_TZ=Asia/Jakarta 0 10 * * *, got %s", got)
+		}
+
+		if err := c.UpdateSchedule("report", "0 11 * * *", cron_jobs.WithTimezone("UTC")); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if got := j.Info().Schedule; got != "CRON_TZ=UTC 0 11 * * *" {
+			t.Errorf("expected CRON_TZ=UTC 0 11 * * *, got %s", got)
+		}
+
+		if err := c.UpdateSchedule("report", "0 12 * * *", cron_jobs.WithTimezone("Mars/Olympus")); !errors.Is(err, cron_jobs.ErrInvalidSpec) {
+			t.Errorf("expected %v, got %v", cron_jobs.ErrInvalidSpec, err)
+		}
+	})
+
+	t.Run("should run registered handler with params", func(t *testing.T) {
+		c := cron_jobs.NewCron()
+
+		got := make(chan string, 1)
+		if err := c.RegisterHandler("resign-url", func(ctx context.Context) error {
+			select {
+			case got <- cron_jobs.Param(ctx, "object", "none"):
+			default:
+			}
+			return nil
+		}); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		if _, err := c.AddJobWithHandler("unknown", "10s", cron_jobs.WithName("unknown")); !errors.Is(err, cron_jobs.ErrHandlerNotFound) {
+			t.Errorf("expected %v, got %v", cron_jobs.ErrHandlerNotFound, err)
+		}
+
+		if _, err := c.AddJobWithHandler("resign-url", "1h", cron_jobs.WithName("resign"),
+			cron_jobs.WithParams(map[string]string{"object": "sample.jpeg"})); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		c.StartAsync()
+		defer c.Stop()
+
+		select {
+		case object := <-got:
+			if object != "sample.jpeg" {
+				t.Errorf("expected sample.jpeg, got %s", object)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected handler to run")
+		}
+	})
+}
+
+func TestCronShutdown(t *testing.T) {
+	t.Run("should cancel running job and wait for it", func(t *testing.T) {
+		c := cron_jobs.NewCron(cron_jobs.WithDrainTimeout(time.Second))
+
+		started := make(chan struct{})
+		var cancelled bool
+		if err := c.AddJob(func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			cancelled = true
+			return ctx.Err()
+		}); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		<-started
+		if err := c.Shutdown(context.Background()); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		if !cancelled {
+			t.Errorf("expected job context to be cancelled")
+		}
+
+		if err := c.AddJob(func(ctx context.Context) error { return nil }); !errors.Is(err, cron_jobs.ErrCronStopped) {
+			t.Errorf("expected %v, got %v", cron_jobs.ErrCronStopped, err)
+		}
+	})
+
+	t.Run("should return error when drain timeout exceeded", func(t *testing.T) {
+		c := cron_jobs.NewCron(cron_jobs.WithDrainTimeout(10 * time.Millisecond))
+
+		started := make(chan struct{})
+		release := make(chan struct{})
+		defer close(release)
+
+		if err := c.AddJob(func(ctx context.Context) error {
+			close(started)
+			<-release // ignore cancellation
+			return nil
+		}); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		<-started
+		if err := c.Shutdown(context.Background()); !errors.Is(err, cron_jobs.ErrDrainTimeout) {
+			t.Errorf("expected %v, got %v", cron_jobs.ErrDrainTimeout, err)
+		}
+	})
+}
+
+func TestCronClock(t *testing.T) {
+	t.Run("should fire job and retry when fake clock is advanced", func(t *testing.T) {
+		start := time.Date(2023, 6, 19, 9, 0, 30, 0, time.UTC)
+		clk := clock.NewFake(start)
+
+		c := cron_jobs.NewCron(cron_jobs.WithLocation(time.UTC), cron_jobs.WithClock(clk))
+
+		ran := make(chan time.Time, 2)
+		attempts := 0
+		if _, err := c.AddJobWithCron("* * * * *", func(ctx context.Context) error {
+			attempts++
+			if attempts == 1 {
+				return errors.New("storage unavailable")
+			}
+
+			run, _ := cron_jobs.RunFromContext(ctx)
+			ran <- run.ScheduledAt
+			return nil
+		}, cron_jobs.WithName("resign"), cron_jobs.WithRetry(cron_jobs.RetryPolicy{MaxAttempts: 2, InitialBackoff: 10 * time.Second})); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		c.StartAsync()
+		defer c.Stop()
+
+		// wait for the scheduler to arm the timer of 09:01
+		clk.BlockUntil(1)
+		clk.Advance(30 * time.Second)
+
+		// first attempt failed, wait for the backoff timer next to the timer of 09:02
+		clk.BlockUntil(2)
+		clk.Advance(10 * time.Second)
+
+		select {
+		case at := <-ran:
+			expected := time.Date(2023, 6, 19, 9, 1, 0, 0, time.UTC)
+			if !at.Equal(expected) {
+				t.Errorf("expected %s, got %s", expected, at)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected job to run")
+		}
+
+		// the last attempt is recorded once the job returns
+		var records []cron_jobs.RunRecord
+		deadline := time.Now().Add(time.Second)
+		for len(records) < 2 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+			records, _ = c.History().Query(context.Background(), cron_jobs.HistoryQuery{Job: "resign"})
+		}
+		if len(records) != 2 || records[0].StartedAt.Sub(records[1].StartedAt) != 10*time.Second {
+			t.Errorf("expected 2 attempts 10s apart, got %+v", records)
+		}
+	})
+}
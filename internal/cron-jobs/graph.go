@@ -0,0 +1,486 @@
+package cron_jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidGraph = errors.New("cron: invalid graph")
+	ErrGraphFailed  = errors.New("cron: graph failed")
+)
+
+// EdgeCondition decide whether a downstream node fires given the outcome of its upstream node
+type EdgeCondition int
+
+const (
+	// OnSuccess fire when the upstream node succeeded
+	OnSuccess EdgeCondition = iota
+	// OnFailure fire when the upstream node failed, e.g. to notify
+	OnFailure
+	// Always fire once the upstream node finished, whatever its outcome
+	Always
+)
+
+var edgeConditionNames = map[EdgeCondition]string{
+	OnSuccess: "on_success",
+	OnFailure: "on_failure",
+	Always:    "always",
+}
+
+func (e EdgeCondition) String() string {
+	if name, ok := edgeConditionNames[e]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("EdgeCondition(%d)", int(e))
+}
+
+// ParseEdgeCondition parse condition name: on_success, on_failure or always
+func ParseEdgeCondition(s string) (EdgeCondition, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return OnSuccess, nil
+	}
+
+	for c, name := range edgeConditionNames {
+		if name == s {
+			return c, nil
+		}
+	}
+
+	return OnSuccess, fmt.Errorf("cron: unknown edge condition %q", s)
+}
+
+// Graph is a DAG of named steps executed as a single scheduled run, see Cron.AddGraph.
+// A step fires once all its upstream steps finished and every incoming edge condition holds,
+// otherwise it is skipped. Steps without dependency between them run concurrently.
+type Graph struct {
+	nodes map[string]*graphNode
+	// order keep the insertion order for stable results
+	order []string
+}
+
+type graphNode struct {
+	name     string
+	fn       JobFunc
+	retry    RetryPolicy
+	params   map[string]string
+	upstream []graphEdge
+}
+
+type graphEdge struct {
+	from string
+	cond EdgeCondition
+}
+
+// NodeOption configure a single graph step
+type NodeOption func(n *graphNode)
+
+// WithNodeRetry set the retry policy of the step, default no retry
+func WithNodeRetry(policy RetryPolicy) NodeOption {
+	return func(n *graphNode) {
+		n.retry = policy
+	}
+}
+
+// WithNodeParams set parameters of the step, overriding the graph params
+func WithNodeParams(params map[string]string) NodeOption {
+	return func(n *graphNode) {
+		n.params = params
+	}
+}
+
+func NewGraph() *Graph {
+	return &Graph{nodes: make(map[string]*graphNode)}
+}
+
+// AddNode add a named step
+func (g *Graph) AddNode(name string, fn JobFunc, opts ...NodeOption) error {
+	if name == "" {
+		return fmt.Errorf("%w: empty node name", ErrInvalidGraph)
+	}
+	if _, ok := g.nodes[name]; ok {
+		return fmt.Errorf("%w: duplicate node %s", ErrInvalidGraph, name)
+	}
+
+	n := &graphNode{name: name, fn: fn}
+	for _, opt := range opts {
+		opt(n)
+	}
+
+	g.nodes[name] = n
+	g.order = append(g.order, name)
+
+	return nil
+}
+
+// AddEdge make to run after from, according to cond
+func (g *Graph) AddEdge(from, to string, cond EdgeCondition) error {
+	if _, ok := g.nodes[from]; !ok {
+		return fmt.Errorf("%w: unknown node %s", ErrInvalidGraph, from)
+	}
+
+	n, ok := g.nodes[to]
+	if !ok {
+		return fmt.Errorf("%w: unknown node %s", ErrInvalidGraph, to)
+	}
+
+	for _, e := range n.upstream {
+		if e.from == from {
+			return fmt.Errorf("%w: duplicate edge %s -> %s", ErrInvalidGraph, from, to)
+		}
+	}
+
+	n.upstream = append(n.upstream, graphEdge{from: from, cond: cond})
+	return nil
+}
+
+// Validate check the graph has nodes and no cycle
+func (g *Graph) Validate() error {
+	if len(g.nodes) == 0 {
+		return fmt.Errorf("%w: no node", ErrInvalidGraph)
+	}
+
+	// kahn's algorithm, nodes left with upstream are part of a cycle
+	pending := make(map[string]int, len(g.nodes))
+	downstream := make(map[string][]string, len(g.nodes))
+	var ready []string
+	for _, name := range g.order {
+		n := g.nodes[name]
+		pending[name] = len(n.upstream)
+		for _, e := range n.upstream {
+			downstream[e.from] = append(downstream[e.from], name)
+		}
+		if len(n.upstream) == 0 {
+			ready = append(ready, name)
+		}
+	}
+
+	visited := 0
+	for len(ready) > 0 {
+		name := ready[0]
+		ready = ready[1:]
+		visited++
+
+		for _, d := range downstream[name] {
+			pending[d]--
+			if pending[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+
+	if visited != len(g.nodes) {
+		var cycle []string
+		for name, n := range pending {
+			if n > 0 {
+				cycle = append(cycle, name)
+			}
+		}
+		sort.Strings(cycle)
+
+		return fmt.Errorf("%w: cycle between %s", ErrInvalidGraph, strings.Join(cycle, ", "))
+	}
+
+	return nil
+}
+
+// GraphRun is the status of a graph run covering every step
+type GraphRun struct {
+	RunID  string                `json:"run_id"`
+	Job    string                `json:"job"`
+	Status RunStatus             `json:"status"`
+	Nodes  map[string]NodeResult `json:"nodes"`
+}
+
+// NodeResult is the outcome of a single step of a graph run
+type NodeResult struct {
+	Status     RunStatus `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	Attempts   int       `json:"attempts,omitempty"`
+	StartedAt  time.Time `json:"started_at,omitempty"`
+	FinishedAt time.Time `json:"finished_at,omitempty"`
+}
+
+// GraphError is returned by a graph run with at least one failed step
+type GraphError struct {
+	Run *GraphRun
+}
+
+func (e *GraphError) Error() string {
+	var failed []string
+	for name, res := range e.Run.Nodes {
+		if res.Status == RunFailed || res.Status == RunCancelled {
+			failed = append(failed, fmt.Sprintf("%s: %s", name, res.Error))
+		}
+	}
+	sort.Strings(failed)
+
+	return fmt.Sprintf("%v: %s", ErrGraphFailed, strings.Join(failed, "; "))
+}
+
+func (e *GraphError) Is(target error) bool {
+	return target == ErrGraphFailed
+}
+
+// nodeSeparator join the graph job name and the step name in run records
+const nodeSeparator = "/"
+
+// AddGraph register a graph running on spec, a duration ("10s") or a cron expression.
+// Each step is recorded to the history as "<job>/<step>" with the run id of the graph run,
+// it runs through the scheduler middlewares and emits its own events under that name.
+func (c *Cron) AddGraph(spec string, g *Graph, opts ...JobOption) (*Job, error) {
+	if err := g.Validate(); err != nil {
+		return nil, err
+	}
+
+	return c.AddJobWithSpec(spec, c.graphFunc(g), opts...)
+}
+
+// GraphRun rebuild the status of a graph run from the history
+func (c *Cron) GraphRun(ctx context.Context, runID string) (*GraphRun, error) {
+	if c.opts.History == nil {
+		return nil, errors.New("cron: history is disabled")
+	}
+
+	records, err := c.opts.History.Query(ctx, HistoryQuery{RunID: runID})
+	if err != nil {
+		return nil, err
+	}
+
+	run := &GraphRun{RunID: runID, Nodes: make(map[string]NodeResult)}
+
+	// records are newest first, keep the latest attempt of each step
+	for i := len(records) - 1; i >= 0; i-- {
+		rec := records[i]
+
+		job, node, ok := strings.Cut(rec.Job, nodeSeparator)
+		if !ok {
+			run.Job = rec.Job
+			run.Status = rec.Status
+			continue
+		}
+
+		run.Job = job
+		run.Nodes[node] = NodeResult{
+			Status:     rec.Status,
+			Error:      rec.Error,
+			Attempts:   rec.Attempt,
+			StartedAt:  rec.StartedAt,
+			FinishedAt: rec.FinishedAt,
+		}
+	}
+
+	if len(run.Nodes) == 0 {
+		return nil, fmt.Errorf("%w: run %s", ErrJobNotFound, runID)
+	}
+
+	return run, nil
+}
+
+// graphFunc returns the job function executing every step of g
+func (c *Cron) graphFunc(g *Graph) JobFunc {
+	return func(ctx context.Context) error {
+		run, _ := RunFromContext(ctx)
+
+		res := c.runGraph(ctx, g, run)
+		if res.Status != RunSuccess {
+			return &GraphError{Run: res}
+		}
+
+		return nil
+	}
+}
+
+// runGraph execute the graph, every step starts as soon as its upstream steps are done
+func (c *Cron) runGraph(ctx context.Context, g *Graph, run RunInfo) *GraphRun {
+	res := &GraphRun{
+		RunID:  run.ID,
+		Job:    run.Job,
+		Status: RunSuccess,
+		Nodes:  make(map[string]NodeResult, len(g.nodes)),
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+		// done is signalled every time a step finishes
+		done    = make(chan struct{}, len(g.nodes))
+		started = make(map[string]bool, len(g.nodes))
+	)
+
+	for {
+		mu.Lock()
+		for _, name := range g.order {
+			if started[name] {
+				continue
+			}
+
+			fire, resolved := g.nodes[name].ready(res.Nodes)
+			if !resolved {
+				continue
+			}
+
+			started[name] = true
+			if !fire {
+				now := c.clock.Now()
+				res.Nodes[name] = NodeResult{Status: RunSkipped, StartedAt: now, FinishedAt: now}
+				c.skipNode(run, name, res.Nodes[name], "upstream condition not met")
+				done <- struct{}{}
+				continue
+			}
+
+			wg.Add(1)
+			go func(n *graphNode) {
+				defer wg.Done()
+
+				result := c.runNode(ctx, n, run)
+
+				mu.Lock()
+				res.Nodes[n.name] = result
+				mu.Unlock()
+
+				done <- struct{}{}
+			}(g.nodes[name])
+		}
+
+		finished := len(res.Nodes) == len(g.nodes)
+		mu.Unlock()
+
+		if finished {
+			break
+		}
+		<-done
+	}
+
+	wg.Wait()
+
+	for _, n := range res.Nodes {
+		switch n.Status {
+		case RunFailed:
+			res.Status = RunFailed
+		case RunCancelled:
+			if res.Status != RunFailed {
+				res.Status = RunCancelled
+			}
+		}
+	}
+
+	return res
+}
+
+// ready reports whether every upstream step finished and, if so, whether the step fires
+func (n *graphNode) ready(results map[string]NodeResult) (fire, resolved bool) {
+	fire = true
+
+	for _, e := range n.upstream {
+		up, ok := results[e.from]
+		if !ok {
+			return false, false
+		}
+
+		switch e.cond {
+		case OnSuccess:
+			fire = fire && up.Status == RunSuccess
+		case OnFailure:
+			fire = fire && (up.Status == RunFailed || up.Status == RunCancelled)
+		case Always:
+			fire = fire && up.Status != RunSkipped
+		}
+	}
+
+	return fire, true
+}
+
+// runNode execute a step with its retry policy through the scheduler middlewares,
+// recording every attempt and emitting the events of the step run
+func (c *Cron) runNode(ctx context.Context, n *graphNode, run RunInfo) NodeResult {
+	params := mergeParams(Params(ctx), n.params)
+	result := NodeResult{StartedAt: c.clock.Now()}
+
+	step := nodeRun(run, n.name)
+
+	started := runEvent(EventStarted, &step)
+	started.StartedAt = result.StartedAt
+	c.emit(started)
+
+	err := c.retryPolicy(n.retry).Do(ctx, func(ctx context.Context) error {
+		result.Attempts++
+		step.Attempt = result.Attempts
+
+		rec := NodeResult{StartedAt: c.clock.Now()}
+		err := invoke(withRun(withParams(ctx, params), step), c.wrap(n.fn))
+		rec.FinishedAt = c.clock.Now()
+		rec.Attempts = result.Attempts
+		rec.Status = statusOf(err)
+
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		}
+		c.recordNode(run, n.name, rec, reason)
+
+		return err
+	}, func(attempt int, err error) {
+		retried := runEvent(EventRetried, &step)
+		retried.StartedAt = started.StartedAt
+		retried.Error = err.Error()
+		c.emit(retried)
+	})
+
+	result.FinishedAt = c.clock.Now()
+	result.Status = statusOf(err)
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	finished := runEvent(EventSucceeded, &step)
+	finished.StartedAt = result.StartedAt
+	finished.FinishedAt = result.FinishedAt
+	finished.Duration = result.FinishedAt.Sub(result.StartedAt)
+	if err != nil {
+		finished.Type = EventFailed
+		finished.Error = err.Error()
+	}
+	c.emit(finished)
+
+	return result
+}
+
+// nodeRun returns the run of a step, named "<job>/<step>" like its history records
+func nodeRun(run RunInfo, node string) RunInfo {
+	run.Job += nodeSeparator + node
+	run.Attempt = 0
+
+	return run
+}
+
+// skipNode record a step that did not fire and emit its skipped event
+func (c *Cron) skipNode(run RunInfo, node string, res NodeResult, reason string) {
+	c.recordNode(run, node, res, reason)
+
+	step := nodeRun(run, node)
+	ev := runEvent(EventSkipped, &step)
+	ev.Error = reason
+	c.emit(ev)
+}
+
+func (c *Cron) recordNode(run RunInfo, node string, res NodeResult, reason string) {
+	c.record(RunRecord{
+		RunID:       run.ID,
+		Job:         run.Job + nodeSeparator + node,
+		ScheduledAt: run.ScheduledAt,
+		StartedAt:   res.StartedAt,
+		FinishedAt:  res.FinishedAt,
+		Attempt:     res.Attempts,
+		Status:      res.Status,
+		Error:       reason,
+		Duration:    res.FinishedAt.Sub(res.StartedAt),
+	})
+}
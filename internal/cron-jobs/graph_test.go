@@ -0,0 +1,196 @@
+package cron_jobs_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	cron_jobs "github.com/vldcreation/sample-cron-go/internal/cron-jobs"
+)
+
+func TestGraph(t *testing.T) {
+	ok := func(ctx context.Context) error { return nil }
+
+	t.Run("should return error when graph has a cycle", func(t *testing.T) {
+		g := cron_jobs.NewGraph()
+		for _, name := range []string{"a", "b", "c"} {
+			if err := g.AddNode(name, ok); err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+		}
+		_ = g.AddEdge("a", "b", cron_jobs.OnSuccess)
+		_ = g.AddEdge("b", "c", cron_jobs.OnSuccess)
+		_ = g.AddEdge("c", "b", cron_jobs.Always)
+
+		if err := g.Validate(); !errors.Is(err, cron_jobs.ErrInvalidGraph) {
+			t.Errorf("expected %v, got %v", cron_jobs.ErrInvalidGraph, err)
+		}
+	})
+
+	t.Run("should return error when edge refer unknown node", func(t *testing.T) {
+		g := cron_jobs.NewGraph()
+		_ = g.AddNode("a", ok)
+
+		if err := g.AddEdge("a", "b", cron_jobs.OnSuccess); !errors.Is(err, cron_jobs.ErrInvalidGraph) {
+			t.Errorf("expected %v, got %v", cron_jobs.ErrInvalidGraph, err)
+		}
+	})
+
+	t.Run("should run downstream steps according to edge conditions", func(t *testing.T) {
+		var (
+			mu  sync.Mutex
+			ran []string
+		)
+		step := func(name string, err error) cron_jobs.JobFunc {
+			return func(ctx context.Context) error {
+				mu.Lock()
+				ran = append(ran, name+":"+cron_jobs.Param(ctx, "object", ""))
+				mu.Unlock()
+				return err
+			}
+		}
+
+		g := cron_jobs.NewGraph()
+		nodes := []struct {
+			name string
+			err  error
+		}{
+			{"extract", nil},
+			{"transform", nil},
+			{"validate", errors.New("checksum mismatch")},
+			{"load", nil},
+			{"alert", nil},
+			{"cleanup", nil},
+		}
+		for _, n := range nodes {
+			if err := g.AddNode(n.name, step(n.name, n.err)); err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+		}
+
+		edges := []struct {
+			from, to string
+			cond     cron_jobs.EdgeCondition
+		}{
+			{"extract", "transform", cron_jobs.OnSuccess},
+			{"extract", "validate", cron_jobs.OnSuccess},
+			{"transform", "load", cron_jobs.OnSuccess},
+			{"validate", "load", cron_jobs.OnSuccess},
+			{"validate", "alert", cron_jobs.OnFailure},
+			{"load", "cleanup", cron_jobs.Always},
+		}
+		for _, e := range edges {
+			if err := g.AddEdge(e.from, e.to, e.cond); err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+		}
+
+		c := cron_jobs.NewCron(cron_jobs.WithHistory(cron_jobs.NewMemoryHistory(cron_jobs.HistoryRetention{})))
+		defer c.Stop()
+
+		if _, err := c.AddGraph("0 0 1 1 *", g, cron_jobs.WithName("nightly"), cron_jobs.WithParams(map[string]string{"object": "a.jpeg"})); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		runID, err := c.Trigger("nightly", nil)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		var run *cron_jobs.GraphRun
+		deadline := time.Now().Add(time.Second)
+		for time.Now().Before(deadline) {
+			run, err = c.GraphRun(context.Background(), runID)
+			if err == nil && run.Status != "" {
+				break
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		if run == nil {
+			t.Fatalf("expected graph run, got %v", err)
+		}
+
+		if run.Status != cron_jobs.RunFailed {
+			t.Errorf("expected %s, got %s", cron_jobs.RunFailed, run.Status)
+		}
+
+		expected := map[string]cron_jobs.RunStatus{
+			"extract":   cron_jobs.RunSuccess,
+			"transform": cron_jobs.RunSuccess,
+			"validate":  cron_jobs.RunFailed,
+			"load":      cron_jobs.RunSkipped,
+			"alert":     cron_jobs.RunSuccess,
+			"cleanup":   cron_jobs.RunSkipped,
+		}
+		for name, status := range expected {
+			if got := run.Nodes[name].Status; got != status {
+				t.Errorf("%s: expected %s, got %s", name, status, got)
+			}
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if len(ran) != 4 || ran[0] != "extract:a.jpeg" {
+			t.Errorf("expected 4 steps starting with extract:a.jpeg, got %v", ran)
+		}
+	})
+
+	t.Run("should run steps through middlewares and emit their events", func(t *testing.T) {
+		var (
+			mu      sync.Mutex
+			wrapped []string
+		)
+		middleware := func(next cron_jobs.JobFunc) cron_jobs.JobFunc {
+			return func(ctx context.Context) error {
+				run, _ := cron_jobs.RunFromContext(ctx)
+				mu.Lock()
+				wrapped = append(wrapped, run.Job)
+				mu.Unlock()
+				return next(ctx)
+			}
+		}
+
+		events := make(chan cron_jobs.Event, 20)
+		c := cron_jobs.NewCron(cron_jobs.WithMiddleware(middleware), cron_jobs.WithListener(func(ev cron_jobs.Event) {
+			events <- ev
+		}, cron_jobs.EventSucceeded, cron_jobs.EventFailed, cron_jobs.EventSkipped))
+		defer c.Stop()
+
+		g := cron_jobs.NewGraph()
+		_ = g.AddNode("resign", ok)
+		_ = g.AddNode("notify", ok)
+		_ = g.AddEdge("resign", "notify", cron_jobs.OnFailure)
+		if _, err := c.AddGraph("0 0 1 1 *", g, cron_jobs.WithName("sync")); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		runID, _ := c.Trigger("sync", nil)
+
+		got := make(map[string]cron_jobs.EventType)
+		for i := 0; i < 3; i++ {
+			ev := waitEvent(t, events)
+			if ev.RunID != runID {
+				t.Errorf("expected event of run %s, got %+v", runID, ev)
+			}
+			got[ev.Job] = ev.Type
+		}
+		expected := map[string]cron_jobs.EventType{
+			"sync/resign": cron_jobs.EventSucceeded,
+			"sync/notify": cron_jobs.EventSkipped,
+			"sync":        cron_jobs.EventSucceeded,
+		}
+		for job, typ := range expected {
+			if got[job] != typ {
+				t.Errorf("%s: expected %s, got %s", job, typ, got[job])
+			}
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if len(wrapped) != 2 || wrapped[0] != "sync" || wrapped[1] != "sync/resign" {
+			t.Errorf("expected [sync sync/resign] wrapped, got %v", wrapped)
+		}
+	})
+}
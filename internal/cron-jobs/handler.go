@@ -0,0 +1,99 @@
+package cron_jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+var ErrHandlerNotFound = errors.New("cron: handler not found")
+
+type paramsKey struct{}
+
+// Params returns the parameters of the running job, the map must not be modified
+func Params(ctx context.Context) map[string]string {
+	params, _ := ctx.Value(paramsKey{}).(map[string]string)
+	return params
+}
+
+// Param returns a single parameter of the running job or def when it is empty
+func Param(ctx context.Context, key, def string) string {
+	if v := Params(ctx)[key]; v != "" {
+		return v
+	}
+
+	return def
+}
+
+func withParams(ctx context.Context, params map[string]string) context.Context {
+	return context.WithValue(ctx, paramsKey{}, params)
+}
+
+// mergeParams returns job params overridden by run params
+func mergeParams(job, run map[string]string) map[string]string {
+	if len(run) == 0 {
+		return job
+	}
+
+	params := make(map[string]string, len(job)+len(run))
+	for k, v := range job {
+		params[k] = v
+	}
+	for k, v := range run {
+		params[k] = v
+	}
+
+	return params
+}
+
+// RegisterHandler register a named job function, so jobs can be declared by handler name
+func (c *Cron) RegisterHandler(name string, fn JobFunc) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.handlers[name]; ok {
+		return fmt.Errorf("cron: handler %s already registered", name)
+	}
+
+	c.handlers[name] = fn
+	return nil
+}
+
+// Handlers returns the names of registered handlers
+func (c *Cron) Handlers() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := make([]string, 0, len(c.handlers))
+	for name := range c.handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Handler returns the registered handler with the given name
+func (c *Cron) Handler(name string) (JobFunc, error) {
+	c.mu.RLock()
+	fn, ok := c.handlers[name]
+	c.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrHandlerNotFound, name)
+	}
+
+	return fn, nil
+}
+
+// AddJobWithHandler register job running a registered handler.
+// spec is either a duration ("10s") or a cron expression ("*/5 * * * *")
+func (c *Cron) AddJobWithHandler(handler, spec string, opts ...JobOption) (*Job, error) {
+	fn, err := c.Handler(handler)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.AddJobWithSpec(spec, fn, opts...)
+}
@@ -0,0 +1,129 @@
+package cron_jobs
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// RunStatus is the outcome of a job execution
+type RunStatus string
+
+const (
+	RunSuccess   RunStatus = "success"
+	RunFailed    RunStatus = "failed"
+	RunCancelled RunStatus = "cancelled"
+	RunSkipped   RunStatus = "skipped"
+)
+
+// RunRecord is a single execution (attempt) of a job
+type RunRecord struct {
+	RunID       string        `json:"run_id"`
+	Job         string        `json:"job"`
+	ScheduledAt time.Time     `json:"scheduled_at"`
+	StartedAt   time.Time     `json:"started_at"`
+	FinishedAt  time.Time     `json:"finished_at"`
+	Attempt     int           `json:"attempt"`
+	Status      RunStatus     `json:"status"`
+	Error       string        `json:"error,omitempty"`
+	Duration    time.Duration `json:"duration"`
+}
+
+// HistoryQuery filter run records, zero value fields are ignored
+type HistoryQuery struct {
+	Job    string
+	RunID  string
+	Status RunStatus
+	// From and To filter by start time, inclusive
+	From time.Time
+	To   time.Time
+	// Limit the number of records returned, newest first
+	Limit int
+}
+
+// HistoryRetention limit how many records are kept, zero value fields are ignored
+type HistoryRetention struct {
+	// MaxRecords keep only the newest records
+	MaxRecords int
+	// MaxAge drop records started before now - MaxAge
+	MaxAge time.Duration
+}
+
+// HistoryStore keep the record of every job execution
+type HistoryStore interface {
+	// Record store a single execution
+	Record(ctx context.Context, rec RunRecord) error
+	// Query returns records matching the query, newest first
+	Query(ctx context.Context, q HistoryQuery) ([]RunRecord, error)
+}
+
+func (q HistoryQuery) match(rec RunRecord) bool {
+	if q.Job != "" && rec.Job != q.Job {
+		return false
+	}
+
+	if q.RunID != "" && rec.RunID != q.RunID {
+		return false
+	}
+
+	if q.Status != "" && rec.Status != q.Status {
+		return false
+	}
+
+	if !q.From.IsZero() && rec.StartedAt.Before(q.From) {
+		return false
+	}
+
+	if !q.To.IsZero() && rec.StartedAt.After(q.To) {
+		return false
+	}
+
+	return true
+}
+
+// filter returns records matching q, newest first. records must be sorted oldest first
+func (q HistoryQuery) filter(records []RunRecord) []RunRecord {
+	var out []RunRecord
+	for i := len(records) - 1; i >= 0; i-- {
+		if !q.match(records[i]) {
+			continue
+		}
+
+		out = append(out, records[i])
+		if q.Limit > 0 && len(out) >= q.Limit {
+			break
+		}
+	}
+
+	return out
+}
+
+// apply returns records within the retention, records must be sorted oldest first
+func (r HistoryRetention) apply(records []RunRecord, now time.Time) []RunRecord {
+	if r.MaxAge > 0 {
+		cutoff := now.Add(-r.MaxAge)
+		i := sort.Search(len(records), func(i int) bool {
+			return !records[i].StartedAt.Before(cutoff)
+		})
+		records = records[i:]
+	}
+
+	if r.MaxRecords > 0 && len(records) > r.MaxRecords {
+		records = records[len(records)-r.MaxRecords:]
+	}
+
+	return records
+}
+
+// insertRecord insert rec keeping records sorted by start time, oldest first
+func insertRecord(records []RunRecord, rec RunRecord) []RunRecord {
+	i := sort.Search(len(records), func(i int) bool {
+		return records[i].StartedAt.After(rec.StartedAt)
+	})
+
+	records = append(records, RunRecord{})
+	copy(records[i+1:], records[i:])
+	records[i] = rec
+
+	return records
+}
@@ -0,0 +1,173 @@
+package cron_jobs
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// compile-time interface check
+var _ HistoryStore = (*FileHistory)(nil)
+
+// compactEvery rewrite the history file after this many records are dropped by the retention
+const compactEvery = 100
+
+// FileHistory keep run records in a local JSON lines file, records survive restart
+type FileHistory struct {
+	mem  *MemoryHistory
+	path string
+	file *os.File
+
+	// dropped count records dropped from memory but still in the file
+	dropped int
+}
+
+// NewFileHistory open or create the history file at path and load its records
+func NewFileHistory(path string, retention HistoryRetention, opts ...HistoryOption) (*FileHistory, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("history: %w", err)
+	}
+
+	h := &FileHistory{
+		mem:  NewMemoryHistory(retention, opts...),
+		path: path,
+	}
+
+	if err := h.load(); err != nil {
+		return nil, err
+	}
+
+	// rewrite so the file only contains records within the retention
+	if err := h.compact(); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+func (h *FileHistory) Record(ctx context.Context, rec RunRecord) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("history: %w", err)
+	}
+
+	h.mem.mu.Lock()
+	defer h.mem.mu.Unlock()
+
+	if _, err := h.file.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("history: %w", err)
+	}
+
+	h.mem.records = insertRecord(h.mem.records, rec)
+	h.dropped += h.mem.prune()
+
+	if h.dropped >= compactEvery {
+		return h.compactLocked()
+	}
+
+	return nil
+}
+
+func (h *FileHistory) Query(ctx context.Context, q HistoryQuery) ([]RunRecord, error) {
+	return h.mem.Query(ctx, q)
+}
+
+// Close close the underlying file
+func (h *FileHistory) Close() error {
+	h.mem.mu.Lock()
+	defer h.mem.mu.Unlock()
+
+	return h.file.Close()
+}
+
+func (h *FileHistory) load() error {
+	f, err := os.Open(h.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("history: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var rec RunRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return fmt.Errorf("history: %s line %d: %w", h.path, line, err)
+		}
+
+		h.mem.records = insertRecord(h.mem.records, rec)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("history: %w", err)
+	}
+
+	h.mem.prune()
+
+	return nil
+}
+
+func (h *FileHistory) compact() error {
+	h.mem.mu.Lock()
+	defer h.mem.mu.Unlock()
+
+	return h.compactLocked()
+}
+
+// compactLocked rewrite the file with records kept in memory, caller must hold h.mem.mu
+func (h *FileHistory) compactLocked() error {
+	tmp := h.path + ".tmp"
+
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("history: %w", err)
+	}
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, rec := range h.mem.records {
+		if err := enc.Encode(rec); err != nil {
+			f.Close()
+			return fmt.Errorf("history: %w", err)
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("history: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("history: %w", err)
+	}
+
+	if err := os.Rename(tmp, h.path); err != nil {
+		return fmt.Errorf("history: %w", err)
+	}
+
+	if h.file != nil {
+		h.file.Close()
+	}
+
+	h.file, err = os.OpenFile(h.path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("history: %w", err)
+	}
+
+	h.dropped = 0
+
+	return nil
+}
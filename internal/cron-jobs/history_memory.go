@@ -0,0 +1,75 @@
+package cron_jobs
+
+import (
+	"context"
+	"sync"
+
+	"github.com/vldcreation/sample-cron-go/internal/clock"
+)
+
+// compile-time interface check
+var _ HistoryStore = (*MemoryHistory)(nil)
+
+// MemoryHistory keep run records in memory, records are lost on restart
+type MemoryHistory struct {
+	mu        sync.RWMutex
+	records   []RunRecord
+	retention HistoryRetention
+	clock     clock.Clock
+}
+
+// HistoryOption configure a history store
+type HistoryOption func(h *MemoryHistory)
+
+// WithHistoryClock set the clock the max age of records is checked by, default to the system clock
+func WithHistoryClock(clk clock.Clock) HistoryOption {
+	return func(h *MemoryHistory) {
+		h.clock = clk
+	}
+}
+
+func NewMemoryHistory(retention HistoryRetention, opts ...HistoryOption) *MemoryHistory {
+	h := &MemoryHistory{retention: retention, clock: clock.New()}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+func (h *MemoryHistory) Record(ctx context.Context, rec RunRecord) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.records = insertRecord(h.records, rec)
+	h.prune()
+
+	return nil
+}
+
+func (h *MemoryHistory) Query(ctx context.Context, q HistoryQuery) ([]RunRecord, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return q.filter(h.records), nil
+}
+
+// prune drop records outside the retention, returns how many records were dropped.
+// caller must hold h.mu
+func (h *MemoryHistory) prune() int {
+	n := len(h.records)
+	kept := h.retention.apply(h.records, h.clock.Now())
+	if len(kept) == n {
+		return 0
+	}
+
+	// kept is the tail of the records, move it to the front and clear the rest
+	// so the dropped records can be garbage collected
+	copy(h.records, kept)
+	for i := len(kept); i < n; i++ {
+		h.records[i] = RunRecord{}
+	}
+	h.records = h.records[:len(kept)]
+
+	return n - len(kept)
+}
@@ -0,0 +1,122 @@
+package cron_jobs_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vldcreation/sample-cron-go/internal/clock"
+	cron_jobs "github.com/vldcreation/sample-cron-go/internal/cron-jobs"
+)
+
+func TestHistoryStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	records := []cron_jobs.RunRecord{
+		{RunID: "1", Job: "resign", StartedAt: now.Add(-3 * time.Hour), Attempt: 1, Status: cron_jobs.RunFailed, Error: "timeout"},
+		{RunID: "1", Job: "resign", StartedAt: now.Add(-3*time.Hour + time.Second), Attempt: 2, Status: cron_jobs.RunSuccess},
+		{RunID: "2", Job: "cleanup", StartedAt: now.Add(-2 * time.Hour), Attempt: 1, Status: cron_jobs.RunSuccess},
+		{RunID: "3", Job: "resign", StartedAt: now.Add(-time.Hour), Attempt: 1, Status: cron_jobs.RunFailed, Error: "timeout"},
+	}
+
+	stores := map[string]func(t *testing.T, retention cron_jobs.HistoryRetention) cron_jobs.HistoryStore{
+		"memory": func(t *testing.T, retention cron_jobs.HistoryRetention) cron_jobs.HistoryStore {
+			return cron_jobs.NewMemoryHistory(retention)
+		},
+		"file": func(t *testing.T, retention cron_jobs.HistoryRetention) cron_jobs.HistoryStore {
+			h, err := cron_jobs.NewFileHistory(filepath.Join(t.TempDir(), "history.jsonl"), retention)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+			t.Cleanup(func() { h.Close() })
+			return h
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name+" should query by job, status and time range", func(t *testing.T) {
+			h := newStore(t, cron_jobs.HistoryRetention{})
+			for _, rec := range records {
+				if err := h.Record(ctx, rec); err != nil {
+					t.Fatalf("expected nil, got %v", err)
+				}
+			}
+
+			got, _ := h.Query(ctx, cron_jobs.HistoryQuery{Job: "resign"})
+			if len(got) != 3 || got[0].RunID != "3" {
+				t.Errorf("expected 3 records newest first, got %+v", got)
+			}
+
+			got, _ = h.Query(ctx, cron_jobs.HistoryQuery{Job: "resign", Status: cron_jobs.RunFailed})
+			if len(got) != 2 {
+				t.Errorf("expected 2 failed records, got %+v", got)
+			}
+
+			got, _ = h.Query(ctx, cron_jobs.HistoryQuery{From: now.Add(-150 * time.Minute), To: now})
+			if len(got) != 2 {
+				t.Errorf("expected 2 records in range, got %+v", got)
+			}
+
+			got, _ = h.Query(ctx, cron_jobs.HistoryQuery{Limit: 1})
+			if len(got) != 1 || got[0].RunID != "3" {
+				t.Errorf("expected newest record, got %+v", got)
+			}
+		})
+
+		t.Run(name+" should apply retention", func(t *testing.T) {
+			h := newStore(t, cron_jobs.HistoryRetention{MaxRecords: 3, MaxAge: 150 * time.Minute})
+			for _, rec := range records {
+				if err := h.Record(ctx, rec); err != nil {
+					t.Fatalf("expected nil, got %v", err)
+				}
+			}
+
+			got, _ := h.Query(ctx, cron_jobs.HistoryQuery{})
+			if len(got) != 2 {
+				t.Errorf("expected 2 records within retention, got %+v", got)
+			}
+		})
+	}
+
+	t.Run("should drop records by the history clock", func(t *testing.T) {
+		clk := clock.NewFake(time.Date(2023, 6, 19, 9, 0, 0, 0, time.UTC))
+		h := cron_jobs.NewMemoryHistory(cron_jobs.HistoryRetention{MaxAge: time.Hour}, cron_jobs.WithHistoryClock(clk))
+
+		h.Record(ctx, cron_jobs.RunRecord{RunID: "1", Job: "resign", StartedAt: clk.Now().Add(-time.Minute)})
+		if got, _ := h.Query(ctx, cron_jobs.HistoryQuery{}); len(got) != 1 {
+			t.Fatalf("expected record within an hour of the clock, got %+v", got)
+		}
+
+		clk.Advance(time.Hour)
+		h.Record(ctx, cron_jobs.RunRecord{RunID: "2", Job: "resign", StartedAt: clk.Now()})
+		if got, _ := h.Query(ctx, cron_jobs.HistoryQuery{}); len(got) != 1 || got[0].RunID != "2" {
+			t.Errorf("expected only run 2, got %+v", got)
+		}
+	})
+
+	t.Run("file should keep records after reopen", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "history.jsonl")
+
+		h, err := cron_jobs.NewFileHistory(path, cron_jobs.HistoryRetention{})
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		for _, rec := range records {
+			h.Record(ctx, rec)
+		}
+		h.Close()
+
+		h, err = cron_jobs.NewFileHistory(path, cron_jobs.HistoryRetention{})
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		defer h.Close()
+
+		got, _ := h.Query(ctx, cron_jobs.HistoryQuery{RunID: "1"})
+		if len(got) != 2 || got[0].Attempt != 2 || got[1].Error != "timeout" {
+			t.Errorf("expected both attempts of run 1, got %+v", got)
+		}
+	})
+}
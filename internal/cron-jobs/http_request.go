@@ -0,0 +1,402 @@
+package cron_jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/vldcreation/sample-cron-go/internal/storage"
+)
+
+var (
+	ErrHTTPCheckFailed = errors.New("cron: http check failed")
+	ErrInvalidHTTPSpec = errors.New("cron: invalid http request")
+)
+
+// DefaultHTTPMaxBody is how many bytes of the response are read, the rest is dropped
+var DefaultHTTPMaxBody int64 = 10 << 20
+
+// HTTPRequestSpec describe the request of a job and how its response is checked
+type HTTPRequestSpec struct {
+	// Method default to GET
+	Method string
+	URL    string
+	Header http.Header
+	// Body is a text/template executed with HTTPRequestData on every attempt
+	Body string
+	// Timeout stop the request once elapsed, zero means no timeout
+	Timeout time.Duration
+	// ExpectStatus are the accepted status codes, empty means any 2xx
+	ExpectStatus []int
+	// Assert check a value of the JSON response
+	Assert *JSONAssertion
+}
+
+// JSONAssertion check the value at Path of a JSON response, in form "$.data.items[0].status".
+// Strings are compared as is, other values as JSON. An empty Equals only requires the path to exist
+type JSONAssertion struct {
+	Path   string
+	Equals string
+}
+
+// HTTPRequestData is the data of the body template
+type HTTPRequestData struct {
+	Job         string
+	RunID       string
+	Attempt     int
+	ScheduledAt time.Time
+	Params      map[string]string
+}
+
+// HTTPCheckError is returned when the response does not pass the checks
+type HTTPCheckError struct {
+	StatusCode int
+	// Reason is the check which failed
+	Reason string
+	// Tail is the start of the response body
+	Tail string
+}
+
+func (e *HTTPCheckError) Error() string {
+	if e.Tail == "" {
+		return fmt.Sprintf("http status %d: %s", e.StatusCode, e.Reason)
+	}
+
+	return fmt.Sprintf("http status %d: %s: %s", e.StatusCode, e.Reason, e.Tail)
+}
+
+func (e *HTTPCheckError) Is(target error) bool {
+	return target == ErrHTTPCheckFailed
+}
+
+// HTTPRequestOption configure a http request job
+type HTTPRequestOption func(hj *httpJob)
+
+// WithHTTPClient set the client sending the requests, default to http.DefaultClient
+func WithHTTPClient(client *http.Client) HTTPRequestOption {
+	return func(hj *httpJob) {
+		hj.client = client
+	}
+}
+
+// WithHTTPResponseArchive upload the response of every attempt to bucket, under prefix/job/run_id/attempt-N
+func WithHTTPResponseArchive(st storage.Storage, bucket, prefix string) HTTPRequestOption {
+	return func(hj *httpJob) {
+		hj.st = st
+		hj.bucket = bucket
+		hj.prefix = prefix
+	}
+}
+
+// WithHTTPMaxBody set how many bytes of the response are read, default to DefaultHTTPMaxBody
+func WithHTTPMaxBody(n int64) HTTPRequestOption {
+	return func(hj *httpJob) {
+		hj.maxBody = n
+	}
+}
+
+type httpJob struct {
+	spec    HTTPRequestSpec
+	body    *template.Template
+	path    []jsonPathStep
+	client  *http.Client
+	maxBody int64
+
+	st     storage.Storage
+	bucket string
+	prefix string
+}
+
+// NewHTTPRequestJob returns job function sending the request. The run is passed in the
+// X-Cron-Job, X-Cron-Run-Id and X-Cron-Attempt headers. A response failing the checks
+// fails the run with HTTPCheckError
+func NewHTTPRequestJob(spec HTTPRequestSpec, opts ...HTTPRequestOption) (JobFunc, error) {
+	hj := &httpJob{spec: spec, client: http.DefaultClient, maxBody: DefaultHTTPMaxBody}
+	for _, opt := range opts {
+		opt(hj)
+	}
+
+	if hj.spec.Method == "" {
+		hj.spec.Method = http.MethodGet
+	}
+	hj.spec.Method = strings.ToUpper(hj.spec.Method)
+
+	u, err := url.Parse(hj.spec.URL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidHTTPSpec, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("%w: unsupported url %q", ErrInvalidHTTPSpec, hj.spec.URL)
+	}
+
+	if hj.body, err = template.New("body").Option("missingkey=zero").Parse(hj.spec.Body); err != nil {
+		return nil, fmt.Errorf("%w: body: %v", ErrInvalidHTTPSpec, err)
+	}
+
+	if hj.spec.Assert != nil {
+		if hj.path, err = parseJSONPath(hj.spec.Assert.Path); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidHTTPSpec, err)
+		}
+	}
+
+	return hj.run, nil
+}
+
+func (hj *httpJob) run(ctx context.Context) error {
+	run, _ := RunFromContext(ctx)
+
+	if hj.spec.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, hj.spec.Timeout)
+		defer cancel()
+	}
+
+	req, err := hj.request(ctx, run)
+	if err != nil {
+		return err
+	}
+
+	resp, err := hj.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("http %s %s: %w", req.Method, hj.spec.URL, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, hj.maxBody))
+	if err != nil {
+		return fmt.Errorf("http %s %s: read response: %w", req.Method, hj.spec.URL, err)
+	}
+
+	hj.archive(run, resp, body)
+
+	return hj.check(resp.StatusCode, body)
+}
+
+// request build the request of the attempt, the body template is executed with the run and its params
+func (hj *httpJob) request(ctx context.Context, run RunInfo) (*http.Request, error) {
+	var body io.Reader
+	if hj.spec.Body != "" {
+		var b bytes.Buffer
+		if err := hj.body.Execute(&b, HTTPRequestData{
+			Job:         run.Job,
+			RunID:       run.ID,
+			Attempt:     run.Attempt,
+			ScheduledAt: run.ScheduledAt,
+			Params:      Params(ctx),
+		}); err != nil {
+			return nil, fmt.Errorf("http %s %s: body: %w", hj.spec.Method, hj.spec.URL, err)
+		}
+		body = &b
+	}
+
+	req, err := http.NewRequestWithContext(ctx, hj.spec.Method, hj.spec.URL, body)
+	if err != nil {
+		return nil, fmt.Errorf("http %s %s: %w", hj.spec.Method, hj.spec.URL, err)
+	}
+
+	for k, values := range hj.spec.Header {
+		for _, v := range values {
+			req.Header.Add(k, v)
+		}
+	}
+	req.Header.Set("X-Cron-Job", run.Job)
+	req.Header.Set("X-Cron-Run-Id", run.ID)
+	req.Header.Set("X-Cron-Attempt", strconv.Itoa(run.Attempt))
+
+	return req, nil
+}
+
+// check the status code then the JSON assertion
+func (hj *httpJob) check(status int, body []byte) error {
+	fail := func(reason string) error {
+		return &HTTPCheckError{StatusCode: status, Reason: reason, Tail: bodyTail(body)}
+	}
+
+	if !hj.expectStatus(status) {
+		if len(hj.spec.ExpectStatus) == 0 {
+			return fail("expected 2xx status")
+		}
+		return fail(fmt.Sprintf("expected status in %v", hj.spec.ExpectStatus))
+	}
+
+	if hj.spec.Assert == nil {
+		return nil
+	}
+
+	var doc interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return fail(fmt.Sprintf("expected JSON response, got %v", err))
+	}
+
+	v, ok := lookupJSONPath(doc, hj.path)
+	if !ok {
+		return fail(fmt.Sprintf("expected %s to exist", hj.spec.Assert.Path))
+	}
+	if hj.spec.Assert.Equals == "" {
+		return nil
+	}
+
+	if got := jsonString(v); got != hj.spec.Assert.Equals {
+		return fail(fmt.Sprintf("expected %s to be %s, got %s", hj.spec.Assert.Path, hj.spec.Assert.Equals, got))
+	}
+
+	return nil
+}
+
+func (hj *httpJob) expectStatus(status int) bool {
+	if len(hj.spec.ExpectStatus) == 0 {
+		return status >= 200 && status < 300
+	}
+
+	for _, s := range hj.spec.ExpectStatus {
+		if s == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (hj *httpJob) archive(run RunInfo, resp *http.Response, body []byte) {
+	if hj.st == nil {
+		return
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+
+	name := path.Join(hj.prefix, run.Job, run.ID, fmt.Sprintf("attempt-%d%s", run.Attempt, extensionOf(contentType)))
+
+	// job context may already be cancelled, the response should still be archived
+	if err := hj.st.Put(context.Background(), hj.bucket, name, body, false, contentType); err != nil {
+		log.Printf("cron: failed to archive response of run %s of job %s: %v\n", run.ID, run.Job, err)
+	}
+}
+
+// extensionOf returns the file extension of the content type, empty when unknown
+func extensionOf(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+
+	exts, err := mime.ExtensionsByType(mediaType)
+	if err != nil || len(exts) == 0 {
+		return ""
+	}
+
+	return exts[0]
+}
+
+// bodyTail returns the start of the response body reported in HTTPCheckError
+func bodyTail(body []byte) string {
+	if len(body) > commandTailSize {
+		body = body[:commandTailSize]
+	}
+
+	return strings.TrimSpace(string(body))
+}
+
+// jsonPathStep is either an object key or an array index
+type jsonPathStep struct {
+	key   string
+	index int
+}
+
+// parseJSONPath parse a dotted path with array indexes, the leading "$" is optional
+func parseJSONPath(p string) ([]jsonPathStep, error) {
+	s := strings.TrimPrefix(strings.TrimSpace(p), "$")
+	if s == "" {
+		return nil, nil
+	}
+
+	var steps []jsonPathStep
+	for s != "" {
+		switch s[0] {
+		case '.':
+			s = s[1:]
+			end := strings.IndexAny(s, ".[")
+			if end < 0 {
+				end = len(s)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("json path %q: empty key", p)
+			}
+			steps = append(steps, jsonPathStep{key: s[:end], index: -1})
+			s = s[end:]
+		case '[':
+			end := strings.IndexByte(s, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("json path %q: missing ]", p)
+			}
+			i, err := strconv.Atoi(s[1:end])
+			if err != nil || i < 0 {
+				return nil, fmt.Errorf("json path %q: invalid index %q", p, s[1:end])
+			}
+			steps = append(steps, jsonPathStep{index: i})
+			s = s[end+1:]
+		default:
+			// path without leading "$.", e.g. "data.status"
+			s = "." + s
+		}
+	}
+
+	return steps, nil
+}
+
+// lookupJSONPath returns the value of doc at path, false when it does not exist
+func lookupJSONPath(doc interface{}, steps []jsonPathStep) (interface{}, bool) {
+	v := doc
+	for _, step := range steps {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			if step.index >= 0 {
+				return nil, false
+			}
+			next, ok := node[step.key]
+			if !ok {
+				return nil, false
+			}
+			v = next
+		case []interface{}:
+			if step.index < 0 || step.index >= len(node) {
+				return nil, false
+			}
+			v = node[step.index]
+		default:
+			return nil, false
+		}
+	}
+
+	return v, true
+}
+
+// jsonString returns strings as is and other values as JSON
+func jsonString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return string(b)
+}
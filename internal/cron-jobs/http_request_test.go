@@ -0,0 +1,169 @@
+package cron_jobs_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	cron_jobs "github.com/vldcreation/sample-cron-go/internal/cron-jobs"
+)
+
+func TestHTTPRequestJob(t *testing.T) {
+	type request struct {
+		method, path, body, runID, token string
+	}
+	requests := make(chan request, 10)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- request{r.Method, r.URL.Path, string(body), r.Header.Get("X-Cron-Run-Id"), r.Header.Get("Authorization")}
+
+		switch r.URL.Path {
+		case "/unavailable":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("maintenance"))
+		case "/text":
+			w.Write([]byte("ok"))
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"data": {"status": "synced", "items": [{"count": 3}]}}`))
+		}
+	}))
+	defer srv.Close()
+
+	st := &memStorage{objects: make(map[string][]byte)}
+
+	// trigger the job once with the given params, returns the run id and its error
+	run := func(t *testing.T, spec cron_jobs.HTTPRequestSpec, params map[string]string) (string, error) {
+		t.Helper()
+
+		fn, err := cron_jobs.NewHTTPRequestJob(spec, cron_jobs.WithHTTPResponseArchive(st, "bucket", "responses"))
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		c := cron_jobs.NewCron()
+		defer c.Stop()
+
+		if _, err := c.AddJobWithCron("0 0 1 1 *", fn, cron_jobs.WithName("sync")); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		runID, err := c.Trigger("sync", params)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		var records []cron_jobs.RunRecord
+		for deadline := time.Now().Add(5 * time.Second); len(records) == 0 && time.Now().Before(deadline); {
+			time.Sleep(5 * time.Millisecond)
+			records, _ = c.History().Query(context.Background(), cron_jobs.HistoryQuery{RunID: runID})
+		}
+		if len(records) != 1 {
+			t.Fatalf("expected 1 record, got %+v", records)
+		}
+
+		if records[0].Error != "" {
+			return runID, errors.New(records[0].Error)
+		}
+		return runID, nil
+	}
+
+	t.Run("should send templated body and archive the response", func(t *testing.T) {
+		runID, err := run(t, cron_jobs.HTTPRequestSpec{
+			Method: "post",
+			URL:    srv.URL + "/sync",
+			Header: http.Header{"Authorization": {"Bearer secret"}},
+			Body:   `{"job": "{{.Job}}", "object": "{{.Params.object}}"}`,
+			Assert: &cron_jobs.JSONAssertion{Path: "$.data.items[0].count", Equals: "3"},
+		}, map[string]string{"object": "a.jpeg"})
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		expected := request{http.MethodPost, "/sync", `{"job": "sync", "object": "a.jpeg"}`, runID, "Bearer secret"}
+		if got := <-requests; got != expected {
+			t.Errorf("expected %+v, got %+v", expected, got)
+		}
+
+		st.mu.Lock()
+		defer st.mu.Unlock()
+		if got := string(st.objects["bucket/responses/sync/"+runID+"/attempt-1.json"]); !strings.Contains(got, `"synced"`) {
+			t.Errorf("expected archived response, got %q", got)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		spec     cron_jobs.HTTPRequestSpec
+		expected string
+	}{
+		{
+			name:     "should fail on unexpected status",
+			spec:     cron_jobs.HTTPRequestSpec{URL: srv.URL + "/unavailable"},
+			expected: "http status 503: expected 2xx status: maintenance",
+		},
+		{
+			name:     "should accept expected status",
+			spec:     cron_jobs.HTTPRequestSpec{URL: srv.URL + "/unavailable", ExpectStatus: []int{200, 503}},
+			expected: "",
+		},
+		{
+			name:     "should fail on assertion mismatch",
+			spec:     cron_jobs.HTTPRequestSpec{URL: srv.URL, Assert: &cron_jobs.JSONAssertion{Path: "data.status", Equals: "pending"}},
+			expected: "http status 200: expected data.status to be pending, got synced",
+		},
+		{
+			name:     "should fail on missing path",
+			spec:     cron_jobs.HTTPRequestSpec{URL: srv.URL, Assert: &cron_jobs.JSONAssertion{Path: "$.data.items[1]"}},
+			expected: "http status 200: expected $.data.items[1] to exist",
+		},
+		{
+			name:     "should fail on non JSON response",
+			spec:     cron_jobs.HTTPRequestSpec{URL: srv.URL + "/text", Assert: &cron_jobs.JSONAssertion{Path: "$.status"}},
+			expected: "expected JSON response",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := run(t, tt.spec, nil)
+			<-requests
+
+			if tt.expected == "" {
+				if err != nil {
+					t.Errorf("expected nil, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected %q, got %v", tt.expected, err)
+			}
+		})
+	}
+
+	t.Run("should reject invalid spec", func(t *testing.T) {
+		for _, spec := range []cron_jobs.HTTPRequestSpec{
+			{URL: "ftp://example.com"},
+			{URL: srv.URL, Body: "{{.Job"},
+			{URL: srv.URL, Assert: &cron_jobs.JSONAssertion{Path: "$.items[x]"}},
+		} {
+			if _, err := cron_jobs.NewHTTPRequestJob(spec); !errors.Is(err, cron_jobs.ErrInvalidHTTPSpec) {
+				t.Errorf("expected %v, got %v", cron_jobs.ErrInvalidHTTPSpec, err)
+			}
+		}
+	})
+
+	t.Run("should report check failure", func(t *testing.T) {
+		fn, _ := cron_jobs.NewHTTPRequestJob(cron_jobs.HTTPRequestSpec{URL: srv.URL + "/unavailable"})
+		if err := fn(context.Background()); !errors.Is(err, cron_jobs.ErrHTTPCheckFailed) {
+			t.Errorf("expected %v, got %v", cron_jobs.ErrHTTPCheckFailed, err)
+		}
+		<-requests
+	})
+}
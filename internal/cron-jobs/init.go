@@ -1,8 +1,19 @@
 package cron_jobs
 
-import "github.com/go-co-op/gocron"
+import (
+	"context"
+
+	"github.com/go-co-op/gocron"
+)
 
 type CronJob interface {
 	SetupJob(sch *gocron.Scheduler) *Cron
-	AddJob(cmd func()) error
+	AddJob(cmd JobFunc) error
+	AddJobWithInterval(interval any, cmd JobFunc, opts ...JobOption) (*Job, error)
+	AddJobWithCron(spec string, cmd JobFunc, opts ...JobOption) (*Job, error)
+	Job(name string) (*Job, error)
+	List() []JobInfo
+	UpdateSchedule(name, spec string, opts ...JobOption) error
+	RemoveJob(name string) error
+	Shutdown(ctx context.Context) error
 }
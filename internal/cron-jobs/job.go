@@ -0,0 +1,304 @@
+package cron_jobs
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-co-op/gocron"
+	"github.com/vldcreation/sample-cron-go/internal/clock"
+)
+
+// Job is a handle of job registered to Cron
+type Job struct {
+	name string
+	tags []string
+	cmd  JobFunc
+	cfg  jobConfig
+	// handler is the handler run by an unscheduled job, e.g. an at job, empty for a registered job
+	handler string
+
+	overlap *overlapGuard
+	// breaker suspend the job after consecutive failures, nil when disabled
+	breaker *breaker
+	// loc is the time zone limits are evaluated in
+	loc   *time.Location
+	clock clock.Clock
+
+	mu       sync.RWMutex
+	schedule Schedule
+	job      *gocron.Job
+	lastRun  time.Time
+	lastErr  error
+	running  int
+	runCount int
+	retired  bool
+	paused   bool
+	// deferred are the runs shifted to a business day, keyed by unix time
+	deferred map[int64]bool
+}
+
+// JobInfo is a snapshot of the job state, used for listing
+type JobInfo struct {
+	Name      string    `json:"name"`
+	Tags      []string  `json:"tags,omitempty"`
+	Schedule  string    `json:"schedule"`
+	NextRun   time.Time `json:"next_run"`
+	LastRun   time.Time `json:"last_run"`
+	LastError string    `json:"last_error,omitempty"`
+	Running   bool      `json:"running"`
+	RunCount  int       `json:"run_count"`
+	Retired   bool      `json:"retired"`
+	Paused    bool      `json:"paused"`
+	Calendar  string    `json:"calendar,omitempty"`
+	// Breaker is the state of the circuit breaker, nil when the job has none
+	Breaker *BreakerInfo `json:"breaker,omitempty"`
+}
+
+// Name returns the unique name of the job
+func (j *Job) Name() string {
+	return j.name
+}
+
+// Tags returns the tags of the job
+func (j *Job) Tags() []string {
+	return append([]string(nil), j.tags...)
+}
+
+// HasTag reports whether the job has the given tag
+func (j *Job) HasTag(tag string) bool {
+	for _, t := range j.tags {
+		if t == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Schedule returns the schedule of the job
+func (j *Job) Schedule() Schedule {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	return j.schedule
+}
+
+// location returns the time zone limits and calendars of the job are evaluated in
+func (j *Job) location() *time.Location {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	return j.loc
+}
+
+// NextRun returns the next time the scheduler will run the job
+func (j *Job) NextRun() time.Time {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	if j.retired {
+		return time.Time{}
+	}
+
+	return j.job.NextRun()
+}
+
+// LastRun returns the last time the job was started
+func (j *Job) LastRun() time.Time {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	return j.lastRun
+}
+
+// RunCount returns how many times the job was started
+func (j *Job) RunCount() int {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	return j.runCount
+}
+
+// NextRuns preview the next n fire times of the job, starting from the next run
+func (j *Job) NextRuns(n int) []time.Time {
+	if n <= 0 {
+		return nil
+	}
+
+	sch := j.Schedule()
+
+	next := j.NextRun()
+	if next.IsZero() {
+		// scheduler not started yet
+		return NextRuns(sch, j.clock.Now(), n)
+	}
+
+	return append([]time.Time{next}, NextRuns(sch, next, n-1)...)
+}
+
+// Info returns snapshot of the job state
+func (j *Job) Info() JobInfo {
+	info := JobInfo{
+		Name:     j.name,
+		Tags:     j.Tags(),
+		NextRun:  j.NextRun(),
+		Calendar: j.cfg.Calendar,
+		Breaker:  j.breaker.info(),
+	}
+
+	j.mu.RLock()
+	info.Schedule = scheduleString(j.schedule)
+	info.LastRun = j.lastRun
+	info.Running = j.running > 0
+	info.RunCount = j.runCount
+	info.Retired = j.retired
+	info.Paused = j.paused
+	if j.lastErr != nil {
+		info.LastError = j.lastErr.Error()
+	}
+	j.mu.RUnlock()
+
+	return info
+}
+
+// Retired reports whether the job reached one of its limits and will not run anymore
+func (j *Job) Retired() bool {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	return j.retired
+}
+
+// Paused reports whether scheduled runs of the job are skipped
+func (j *Job) Paused() bool {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	return j.paused
+}
+
+func (j *Job) setPaused(paused bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.paused = paused
+}
+
+// LastError returns the error of the last finished run
+func (j *Job) LastError() error {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	return j.lastErr
+}
+
+// scheduledAt returns the time the current run was scheduled for.
+// gocron does not guard the last run it sets, so it is derived from the schedule:
+// the latest occurrence not after now, or now for interval schedules
+func (j *Job) scheduledAt(now time.Time) time.Time {
+	sch, ok := j.Schedule().(*cronSchedule)
+	if !ok {
+		return now
+	}
+
+	var at time.Time
+	for next := sch.Next(now.Add(-scheduleLookback)); !next.IsZero() && !next.After(now); next = sch.Next(next) {
+		at = next
+	}
+
+	if at.IsZero() {
+		return now
+	}
+
+	return at
+}
+
+// lockedAt returns the time identifying the scheduled run across instances.
+// Every instance fires an interval schedule from its own start time, so the run is
+// identified by the interval it falls in instead
+func (j *Job) lockedAt(scheduledAt time.Time) time.Time {
+	sch, ok := j.Schedule().(*intervalSchedule)
+	if !ok {
+		return scheduledAt
+	}
+
+	return sch.slot(scheduledAt)
+}
+
+// scheduledOn reports whether the schedule of the job has a run at t
+func (j *Job) scheduledOn(t time.Time) bool {
+	sch, ok := j.Schedule().(*cronSchedule)
+	if !ok {
+		// interval schedule keep running on business days by itself
+		return true
+	}
+
+	return sch.Next(t.Add(-time.Nanosecond)).Equal(t)
+}
+
+// deferRun returns false when a run is already shifted to t
+func (j *Job) deferRun(t time.Time) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.deferred[t.Unix()] {
+		return false
+	}
+
+	if j.deferred == nil {
+		j.deferred = make(map[int64]bool)
+	}
+	j.deferred[t.Unix()] = true
+
+	return true
+}
+
+func (j *Job) undeferRun(t time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	delete(j.deferred, t.Unix())
+}
+
+// markStarted returns false when the job already reached its max runs
+func (j *Job) markStarted() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if max := j.cfg.Limits.MaxRuns; max > 0 && j.runCount >= max {
+		return false
+	}
+
+	j.lastRun = j.clock.Now()
+	j.running++
+	j.runCount++
+
+	return true
+}
+
+func (j *Job) markFinished(err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.running--
+	j.lastErr = err
+}
+
+// exhausted reports whether the job started its max runs
+func (j *Job) exhausted() bool {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	return j.cfg.Limits.MaxRuns > 0 && j.runCount >= j.cfg.Limits.MaxRuns
+}
+
+// scheduleLookback is how late a run may start after its scheduled time
+const scheduleLookback = time.Minute
+
+func scheduleString(sch Schedule) string {
+	if s, ok := sch.(interface{ String() string }); ok {
+		return s.String()
+	}
+
+	return ""
+}
@@ -0,0 +1,144 @@
+package cron_jobs
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+var (
+	ErrJobRetired    = errors.New("cron: job retired")
+	ErrInvalidWindow = errors.New("cron: invalid window")
+)
+
+// Limits bound how often and when a job may run, zero values mean no limit
+type Limits struct {
+	// MaxRuns retire the job after the given number of started runs
+	MaxRuns int
+	// StartAfter skip runs scheduled before the given time
+	StartAfter time.Time
+	// StopAfter retire the job once the given time has passed
+	StopAfter time.Time
+	// Windows are the daily periods the job is allowed to run in, empty means any time
+	Windows []Window
+	// Blackouts are the daily periods the job must not run in, e.g. a maintenance window
+	Blackouts []Window
+}
+
+// Window is a daily time range evaluated in the job time zone.
+// End before Start means the window cross midnight, e.g. 22:00-02:00
+type Window struct {
+	// Start and End are offsets from midnight, End is exclusive
+	Start time.Duration
+	End   time.Duration
+}
+
+// ParseWindow parse daily window in form "HH:MM-HH:MM", e.g. "01:00-03:30"
+func ParseWindow(s string) (Window, error) {
+	from, to, ok := strings.Cut(strings.TrimSpace(s), "-")
+	if !ok {
+		return Window{}, fmt.Errorf("%w: %q", ErrInvalidWindow, s)
+	}
+
+	start, err := parseClock(from)
+	if err != nil {
+		return Window{}, fmt.Errorf("%w: %q: %v", ErrInvalidWindow, s, err)
+	}
+
+	end, err := parseClock(to)
+	if err != nil {
+		return Window{}, fmt.Errorf("%w: %q: %v", ErrInvalidWindow, s, err)
+	}
+
+	if start == end {
+		return Window{}, fmt.Errorf("%w: %q is empty", ErrInvalidWindow, s)
+	}
+
+	return Window{Start: start, End: end}, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Contains reports whether the wall clock of t is within the window
+func (w Window) Contains(t time.Time) bool {
+	offset := time.Duration(t.Hour())*time.Hour +
+		time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second
+
+	if w.Start < w.End {
+		return offset >= w.Start && offset < w.End
+	}
+
+	// cross midnight
+	return offset >= w.Start || offset < w.End
+}
+
+func (w Window) String() string {
+	clock := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+	}
+
+	return clock(w.Start) + "-" + clock(w.End)
+}
+
+// check decide whether a run scheduled at t may start.
+// It returns the reason when the run must be skipped and whether the job must be retired.
+func (l Limits) check(t time.Time, runCount int) (reason string, retire bool) {
+	if l.MaxRuns > 0 && runCount >= l.MaxRuns {
+		return fmt.Sprintf("max runs %d reached", l.MaxRuns), true
+	}
+
+	if !l.StopAfter.IsZero() && t.After(l.StopAfter) {
+		return fmt.Sprintf("stopped after %v", l.StopAfter), true
+	}
+
+	if !l.StartAfter.IsZero() && t.Before(l.StartAfter) {
+		return fmt.Sprintf("not started until %v", l.StartAfter), false
+	}
+
+	for _, w := range l.Blackouts {
+		if w.Contains(t) {
+			return fmt.Sprintf("blackout %v", w), false
+		}
+	}
+
+	if len(l.Windows) == 0 {
+		return "", false
+	}
+
+	for _, w := range l.Windows {
+		if w.Contains(t) {
+			return "", false
+		}
+	}
+
+	return "outside of run windows", false
+}
+
+// retire remove the job from the scheduler, it stays registered so its state can be inspected
+func (c *Cron) retire(j *Job, reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	j.mu.Lock()
+	if j.retired {
+		j.mu.Unlock()
+		return
+	}
+	j.retired = true
+	gj := j.job
+	j.mu.Unlock()
+
+	c.s.RemoveByReference(gj)
+
+	log.Printf("cron: job %s retired: %s\n", j.name, reason)
+}
@@ -0,0 +1,131 @@
+package cron_jobs_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cron_jobs "github.com/vldcreation/sample-cron-go/internal/cron-jobs"
+)
+
+func TestWindow(t *testing.T) {
+	tests := []struct {
+		name     string
+		window   string
+		at       time.Time
+		expected bool
+	}{
+		{
+			name:     "should contain time within window",
+			window:   "09:00-17:00",
+			at:       time.Date(2023, 6, 19, 12, 30, 0, 0, time.UTC),
+			expected: true,
+		},
+		{
+			name:     "should exclude window end",
+			window:   "09:00-17:00",
+			at:       time.Date(2023, 6, 19, 17, 0, 0, 0, time.UTC),
+			expected: false,
+		},
+		{
+			name:     "should contain time after midnight when window cross midnight",
+			window:   "22:00-02:00",
+			at:       time.Date(2023, 6, 19, 1, 59, 59, 0, time.UTC),
+			expected: true,
+		},
+		{
+			name:     "should exclude time outside window crossing midnight",
+			window:   "22:00-02:00",
+			at:       time.Date(2023, 6, 19, 12, 0, 0, 0, time.UTC),
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := cron_jobs.ParseWindow(tt.window)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			if got := w.Contains(tt.at); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	t.Run("should return error when window is invalid", func(t *testing.T) {
+		for _, s := range []string{"09:00", "9-17", "25:00-26:00", "10:00-10:00"} {
+			if _, err := cron_jobs.ParseWindow(s); err == nil {
+				t.Errorf("%s: expected error, got nil", s)
+			}
+		}
+	})
+}
+
+func TestJobLimits(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should retire job after max runs", func(t *testing.T) {
+		c := cron_jobs.NewCron()
+		j, err := c.AddJobWithInterval(10*time.Millisecond, func(ctx context.Context) error { return nil },
+			cron_jobs.WithName("resign"), cron_jobs.WithMaxRuns(2))
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		c.StartAsync()
+		defer c.Stop()
+
+		deadline := time.Now().Add(time.Second)
+		for !j.Retired() && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		time.Sleep(50 * time.Millisecond)
+
+		if !j.Retired() {
+			t.Fatalf("expected job to be retired")
+		}
+		if got := j.RunCount(); got != 2 {
+			t.Errorf("expected 2 runs, got %d", got)
+		}
+		if !j.NextRun().IsZero() {
+			t.Errorf("expected no next run, got %v", j.NextRun())
+		}
+		if err := c.UpdateSchedule("resign", "1s"); err == nil {
+			t.Errorf("expected error, got nil")
+		}
+	})
+
+	t.Run("should skip runs before start after", func(t *testing.T) {
+		c := cron_jobs.NewCron()
+		ran := make(chan struct{}, 1)
+		j, err := c.AddJobWithInterval(time.Hour, func(ctx context.Context) error {
+			ran <- struct{}{}
+			return nil
+		}, cron_jobs.WithName("resign"), cron_jobs.WithStartAfter(time.Now().Add(time.Hour)))
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		c.StartAsync()
+		defer c.Stop()
+
+		var got []cron_jobs.RunRecord
+		deadline := time.Now().Add(time.Second)
+		for len(got) == 0 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+			got, _ = c.History().Query(ctx, cron_jobs.HistoryQuery{Job: j.Name()})
+		}
+
+		if len(got) != 1 || got[0].Status != cron_jobs.RunSkipped {
+			t.Errorf("expected 1 skipped record, got %+v", got)
+		}
+
+		select {
+		case <-ran:
+			t.Errorf("expected run to be skipped")
+		default:
+		}
+	})
+}
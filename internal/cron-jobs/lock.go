@@ -0,0 +1,241 @@
+package cron_jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vldcreation/sample-cron-go/internal/clock"
+)
+
+var (
+	ErrLockHeld  = errors.New("cron: lock held by other instance")
+	ErrRunDone   = errors.New("cron: run already done by other instance")
+	ErrLeaseLost = errors.New("cron: lease lost")
+)
+
+// DefaultLockTTL is the lease duration when the scheduler has no lock ttl
+var DefaultLockTTL = time.Second * 30
+
+// Locker make sure a scheduled run is executed by only one instance
+type Locker interface {
+	// Acquire take the lease of key for the run scheduled at run.
+	// Returns ErrLockHeld if other instance holds the lease or
+	// ErrRunDone if the run (or a later one) was already executed.
+	// A zero run is a run on demand, it is never done and does not mark a run as done.
+	Acquire(ctx context.Context, key string, run time.Time, ttl time.Duration) (Lease, error)
+}
+
+// Lease is held by the instance executing a run
+type Lease interface {
+	// Token is the fencing token, it increases on every acquisition of the key
+	Token() int64
+	// Renew extend the lease by its ttl, returns ErrLeaseLost if the lease was taken over
+	Renew(ctx context.Context) error
+	// Release give the lease up and mark the run as done
+	Release(ctx context.Context) error
+}
+
+// InstanceID returns an id unique to this process, usable as lease owner
+func InstanceID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.NewString()[:8])
+}
+
+// leaseRecord is the persisted state of a lease
+type leaseRecord struct {
+	Owner     string    `json:"owner"`
+	Token     int64     `json:"token"`
+	Run       time.Time `json:"run"`
+	LastRun   time.Time `json:"last_run"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// leaseBackend atomically update lease record of key.
+// fn receives the current record (nil if none) and returns the record to store.
+type leaseBackend interface {
+	update(ctx context.Context, key string, fn func(cur *leaseRecord) (*leaseRecord, error)) error
+}
+
+// leaseLocker implements Locker on top of a lease backend
+type leaseLocker struct {
+	backend leaseBackend
+	owner   string
+	clock   clock.Clock
+}
+
+// LockerOption configure a locker
+type LockerOption func(l *leaseLocker)
+
+// WithLockerClock set the clock leases expire by, default to the system clock.
+// Instances sharing the leases must tell the same time.
+func WithLockerClock(clk clock.Clock) LockerOption {
+	return func(l *leaseLocker) {
+		l.clock = clk
+	}
+}
+
+func newLeaseLocker(backend leaseBackend, owner string, opts ...LockerOption) *leaseLocker {
+	l := &leaseLocker{backend: backend, owner: owner, clock: clock.New()}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+func (l *leaseLocker) Acquire(ctx context.Context, key string, run time.Time, ttl time.Duration) (Lease, error) {
+	var token int64
+
+	err := l.backend.update(ctx, key, func(cur *leaseRecord) (*leaseRecord, error) {
+		now := l.clock.Now()
+		next := &leaseRecord{
+			Owner:     l.owner,
+			Token:     1,
+			Run:       run,
+			ExpiresAt: now.Add(ttl),
+		}
+
+		if cur != nil {
+			if !run.IsZero() && !cur.LastRun.Before(run) {
+				return nil, ErrRunDone
+			}
+
+			if cur.Owner != "" && cur.ExpiresAt.After(now) {
+				return nil, ErrLockHeld
+			}
+
+			next.Token = cur.Token + 1
+			next.LastRun = cur.LastRun
+		}
+
+		token = next.Token
+		return next, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &lease{
+		locker: l,
+		key:    key,
+		token:  token,
+		ttl:    ttl,
+	}, nil
+}
+
+type lease struct {
+	locker *leaseLocker
+	key    string
+	token  int64
+	ttl    time.Duration
+}
+
+func (l *lease) Token() int64 {
+	return l.token
+}
+
+func (l *lease) Renew(ctx context.Context) error {
+	return l.locker.backend.update(ctx, l.key, func(cur *leaseRecord) (*leaseRecord, error) {
+		if !l.owns(cur) {
+			return nil, ErrLeaseLost
+		}
+
+		next := *cur
+		next.ExpiresAt = l.locker.clock.Now().Add(l.ttl)
+		return &next, nil
+	})
+}
+
+func (l *lease) Release(ctx context.Context) error {
+	return l.locker.backend.update(ctx, l.key, func(cur *leaseRecord) (*leaseRecord, error) {
+		if !l.owns(cur) {
+			return nil, ErrLeaseLost
+		}
+
+		next := *cur
+		next.Owner = ""
+		next.ExpiresAt = time.Time{}
+		if next.Run.After(next.LastRun) {
+			next.LastRun = next.Run
+		}
+		return &next, nil
+	})
+}
+
+func (l *lease) owns(cur *leaseRecord) bool {
+	return cur != nil && cur.Owner == l.locker.owner && cur.Token == l.token
+}
+
+// lock acquire the lease of the run scheduled at when the scheduler has a locker,
+// returns nil lease when locking is disabled
+func (c *Cron) lock(ctx context.Context, run *RunInfo, at time.Time) (Lease, error) {
+	if c.opts.Locker == nil {
+		return nil, nil
+	}
+
+	lease, err := c.opts.Locker.Acquire(ctx, run.Job, at, c.lockTTL())
+	if err != nil {
+		return nil, fmt.Errorf("lock: %w", err)
+	}
+
+	return lease, nil
+}
+
+func (c *Cron) lockTTL() time.Duration {
+	if c.opts.LockTTL > 0 {
+		return c.opts.LockTTL
+	}
+
+	return DefaultLockTTL
+}
+
+// keepAlive renew the lease while the run is running and cancel the run when the lease is lost.
+// The returned stop function release the lease.
+func (c *Cron) keepAlive(ctx context.Context, run *RunInfo, lease Lease) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		for {
+			timer := c.clock.NewTimer(c.lockTTL() / 3)
+			select {
+			case <-done:
+				timer.Stop()
+				return
+			case <-timer.C():
+			}
+
+			err := lease.Renew(ctx)
+			if errors.Is(err, ErrLeaseLost) {
+				log.Printf("cron: job %s lost lease of run %s, cancelling\n", run.Job, run.ID)
+				cancel()
+				return
+			}
+			if err != nil {
+				log.Printf("cron: job %s failed to renew lease: %v\n", run.Job, err)
+			}
+		}
+	}()
+
+	return ctx, func() {
+		close(done)
+		<-stopped
+		cancel()
+
+		if err := lease.Release(context.Background()); err != nil {
+			log.Printf("cron: job %s failed to release lease: %v\n", run.Job, err)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package cron_jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// fileLockPoll is how often a busy lock file is retried
+	fileLockPoll = time.Millisecond * 10
+	// fileLockStale is the age after which a lock file is considered left by a crashed process
+	fileLockStale = time.Second * 10
+)
+
+// fileLeases store lease records as JSON files in a local directory,
+// a lock file created exclusively guards each update
+type fileLeases struct {
+	dir string
+}
+
+// NewFileLocker returns a locker storing leases in dir, only usable by instances on the same host.
+// owner identify this instance and must be unique among instances.
+func NewFileLocker(dir, owner string, opts ...LockerOption) (Locker, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("lease: %w", err)
+	}
+
+	return newLeaseLocker(&fileLeases{dir: dir}, owner, opts...), nil
+}
+
+func (f *fileLeases) update(ctx context.Context, key string, fn func(cur *leaseRecord) (*leaseRecord, error)) error {
+	name := filepath.Join(f.dir, url.PathEscape(key)+".lease.json")
+
+	unlock, err := f.lock(ctx, name+".lock")
+	if err != nil {
+		return fmt.Errorf("lease %s: %w", key, err)
+	}
+	defer unlock()
+
+	var cur *leaseRecord
+
+	b, err := os.ReadFile(name)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return fmt.Errorf("lease %s: %w", key, err)
+	default:
+		cur = &leaseRecord{}
+		if err := json.Unmarshal(b, cur); err != nil {
+			return fmt.Errorf("lease %s: %w", key, err)
+		}
+	}
+
+	next, err := fn(cur)
+	if err != nil {
+		return err
+	}
+
+	b, err = json.Marshal(next)
+	if err != nil {
+		return fmt.Errorf("lease %s: %w", key, err)
+	}
+
+	if err := writeFileAtomic(name, b); err != nil {
+		return fmt.Errorf("lease %s: %w", key, err)
+	}
+
+	return nil
+}
+
+// lock create the lock file exclusively, waiting until it is free or ctx is done
+func (f *fileLeases) lock(ctx context.Context, name string) (func(), error) {
+	for {
+		lf, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			lf.Close()
+			return func() { os.Remove(name) }, nil
+		}
+
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		if info, err := os.Stat(name); err == nil && time.Since(info.ModTime()) > fileLockStale {
+			os.Remove(name)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(fileLockPoll):
+		}
+	}
+}
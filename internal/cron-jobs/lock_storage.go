@@ -0,0 +1,75 @@
+package cron_jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/vldcreation/sample-cron-go/internal/storage"
+)
+
+// storageCASRetries is how many times a conflicting write is retried
+const storageCASRetries = 3
+
+// storageLeases store lease records as JSON objects using conditional writes
+type storageLeases struct {
+	st     storage.Storage
+	bucket string
+	prefix string
+}
+
+// NewStorageLocker returns a locker storing leases under prefix in bucket,
+// instances sharing the bucket never run the same scheduled run twice.
+// owner identify this instance and must be unique among instances.
+func NewStorageLocker(st storage.Storage, bucket, prefix, owner string, opts ...LockerOption) Locker {
+	return newLeaseLocker(&storageLeases{st: st, bucket: bucket, prefix: prefix}, owner, opts...)
+}
+
+func (s *storageLeases) update(ctx context.Context, key string, fn func(cur *leaseRecord) (*leaseRecord, error)) error {
+	name := path.Join(s.prefix, key+".lease.json")
+
+	for i := 0; i < storageCASRetries; i++ {
+		var (
+			cur     *leaseRecord
+			version string
+		)
+
+		data, info, err := s.st.Read(ctx, s.bucket, name)
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+		case err != nil:
+			return fmt.Errorf("lease %s: %w", key, err)
+		default:
+			cur = &leaseRecord{}
+			if err := json.Unmarshal(data, cur); err != nil {
+				return fmt.Errorf("lease %s: %w", key, err)
+			}
+			version = info.Version
+		}
+
+		next, err := fn(cur)
+		if err != nil {
+			return err
+		}
+
+		b, err := json.Marshal(next)
+		if err != nil {
+			return fmt.Errorf("lease %s: %w", key, err)
+		}
+
+		_, err = s.st.PutIfMatch(ctx, s.bucket, name, b, "application/json", version)
+		if errors.Is(err, storage.ErrPreconditionFailed) {
+			// changed by other instance, evaluate again
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("lease %s: %w", key, err)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("lease %s: %w", key, ErrLockHeld)
+}
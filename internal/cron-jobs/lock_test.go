@@ -0,0 +1,398 @@
+package cron_jobs_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vldcreation/sample-cron-go/internal/clock"
+	cron_jobs "github.com/vldcreation/sample-cron-go/internal/cron-jobs"
+	"github.com/vldcreation/sample-cron-go/internal/storage"
+)
+
+func TestFileLocker(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	replicaA, err := cron_jobs.NewFileLocker(dir, "replica-a")
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	replicaB, _ := cron_jobs.NewFileLocker(dir, "replica-b")
+
+	run := time.Date(2023, 6, 18, 15, 0, 0, 0, time.UTC)
+
+	t.Run("should run scheduled run only once", func(t *testing.T) {
+		lease, err := replicaA.Acquire(ctx, "resign/url", run, time.Minute)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		if _, err := replicaB.Acquire(ctx, "resign/url", run, time.Minute); !errors.Is(err, cron_jobs.ErrLockHeld) {
+			t.Errorf("expected %v, got %v", cron_jobs.ErrLockHeld, err)
+		}
+
+		if err := lease.Renew(ctx); err != nil {
+			t.Errorf("expected nil, got %v", err)
+		}
+
+		if err := lease.Release(ctx); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		if _, err := replicaB.Acquire(ctx, "resign/url", run, time.Minute); !errors.Is(err, cron_jobs.ErrRunDone) {
+			t.Errorf("expected %v, got %v", cron_jobs.ErrRunDone, err)
+		}
+	})
+
+	t.Run("should take over expired lease with greater token", func(t *testing.T) {
+		next := run.Add(time.Hour)
+
+		stale, err := replicaA.Acquire(ctx, "resign/url", next, time.Millisecond)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		lease, err := replicaB.Acquire(ctx, "resign/url", next, time.Minute)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		if lease.Token() <= stale.Token() {
+			t.Errorf("expected token greater than %d, got %d", stale.Token(), lease.Token())
+		}
+
+		if err := stale.Renew(ctx); !errors.Is(err, cron_jobs.ErrLeaseLost) {
+			t.Errorf("expected %v, got %v", cron_jobs.ErrLeaseLost, err)
+		}
+
+		if err := stale.Release(ctx); !errors.Is(err, cron_jobs.ErrLeaseLost) {
+			t.Errorf("expected %v, got %v", cron_jobs.ErrLeaseLost, err)
+		}
+	})
+}
+
+// racyStorage run race before the next conditional writes, e.g. to let other instance write first
+type racyStorage struct {
+	*memStorage
+
+	mu    sync.Mutex
+	races int
+	race  func()
+}
+
+func (s *racyStorage) PutIfMatch(ctx context.Context, parent, name string, contents []byte, contentType, version string) (*storage.ObjectInfo, error) {
+	s.mu.Lock()
+	race := s.race
+	if s.races > 0 {
+		s.races--
+	} else {
+		race = nil
+	}
+	s.mu.Unlock()
+
+	if race != nil {
+		race()
+	}
+
+	return s.memStorage.PutIfMatch(ctx, parent, name, contents, contentType, version)
+}
+
+// racing make the next n conditional writes lose against race
+func (s *racyStorage) racing(n int, race func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.races = n
+	s.race = race
+}
+
+// waitReleased wait for the lease stored at name to be released,
+// the lease is released once the succeeded event is emitted
+func waitReleased(t *testing.T, st *memStorage, name string) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		data, _, err := st.Read(context.Background(), "bucket", name)
+		if err == nil && strings.Contains(string(data), `"owner":""`) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected lease released, got %s (%v)", data, err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestStorageLocker(t *testing.T) {
+	ctx := context.Background()
+	run := time.Date(2023, 6, 18, 15, 0, 0, 0, time.UTC)
+
+	t.Run("should run scheduled run only once", func(t *testing.T) {
+		st := &racyStorage{memStorage: &memStorage{objects: make(map[string][]byte)}}
+		replicaA := cron_jobs.NewStorageLocker(st, "bucket", "cron-locks", "replica-a")
+		replicaB := cron_jobs.NewStorageLocker(st, "bucket", "cron-locks", "replica-b")
+
+		lease, err := replicaA.Acquire(ctx, "resign", run, time.Minute)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if _, err := replicaB.Acquire(ctx, "resign", run, time.Minute); !errors.Is(err, cron_jobs.ErrLockHeld) {
+			t.Errorf("expected %v, got %v", cron_jobs.ErrLockHeld, err)
+		}
+		if err := lease.Release(ctx); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if _, err := replicaB.Acquire(ctx, "resign", run, time.Minute); !errors.Is(err, cron_jobs.ErrRunDone) {
+			t.Errorf("expected %v, got %v", cron_jobs.ErrRunDone, err)
+		}
+	})
+
+	t.Run("should expire lease by the locker clock", func(t *testing.T) {
+		st := &memStorage{objects: make(map[string][]byte)}
+		clk := clock.NewFake(run)
+		replicaA := cron_jobs.NewStorageLocker(st, "bucket", "cron-locks", "replica-a", cron_jobs.WithLockerClock(clk))
+		replicaB := cron_jobs.NewStorageLocker(st, "bucket", "cron-locks", "replica-b", cron_jobs.WithLockerClock(clk))
+
+		stale, err := replicaA.Acquire(ctx, "resign", run, time.Minute)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		clk.Advance(30 * time.Second)
+		if err := stale.Renew(ctx); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		// renewed until 15:01:30
+		clk.Advance(45 * time.Second)
+		if _, err := replicaB.Acquire(ctx, "resign", run, time.Minute); !errors.Is(err, cron_jobs.ErrLockHeld) {
+			t.Errorf("expected %v, got %v", cron_jobs.ErrLockHeld, err)
+		}
+
+		clk.Advance(30 * time.Second)
+		if _, err := replicaB.Acquire(ctx, "resign", run, time.Minute); err != nil {
+			t.Errorf("expected expired lease taken over, got %v", err)
+		}
+	})
+
+	t.Run("should evaluate lease again when taken concurrently", func(t *testing.T) {
+		st := &racyStorage{memStorage: &memStorage{objects: make(map[string][]byte)}}
+		replicaA := cron_jobs.NewStorageLocker(st, "bucket", "cron-locks", "replica-a")
+		replicaB := cron_jobs.NewStorageLocker(st, "bucket", "cron-locks", "replica-b")
+
+		// replica b read the free lease, then replica a takes it before b writes
+		var leaseA cron_jobs.Lease
+		st.racing(1, func() {
+			var err error
+			if leaseA, err = replicaA.Acquire(ctx, "resign", run, time.Minute); err != nil {
+				t.Errorf("expected nil, got %v", err)
+			}
+		})
+
+		if _, err := replicaB.Acquire(ctx, "resign", run, time.Minute); !errors.Is(err, cron_jobs.ErrLockHeld) {
+			t.Errorf("expected %v, got %v", cron_jobs.ErrLockHeld, err)
+		}
+
+		if err := leaseA.Release(ctx); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		// replica a runs an earlier run in between, the lease is still free for the next one
+		next := run.Add(time.Hour)
+		st.racing(1, func() {
+			lease, err := replicaA.Acquire(ctx, "resign", run.Add(time.Minute), time.Minute)
+			if err != nil {
+				t.Errorf("expected nil, got %v", err)
+				return
+			}
+			leaseA = lease
+			if err := lease.Release(ctx); err != nil {
+				t.Errorf("expected nil, got %v", err)
+			}
+		})
+		leaseB, err := replicaB.Acquire(ctx, "resign", next, time.Minute)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if leaseB.Token() <= leaseA.Token() {
+			t.Errorf("expected token greater than %d, got %d", leaseA.Token(), leaseB.Token())
+		}
+	})
+
+	t.Run("should give up after too many conflicting writes", func(t *testing.T) {
+		st := &racyStorage{memStorage: &memStorage{objects: make(map[string][]byte)}}
+		replicaA := cron_jobs.NewStorageLocker(st, "bucket", "cron-locks", "replica-a")
+
+		// every write of the lease loses against other instance touching it
+		st.racing(100, func() {
+			st.Put(ctx, "bucket", "cron-locks/resign.lease.json", []byte(`{}`), false, "application/json")
+		})
+
+		if _, err := replicaA.Acquire(ctx, "resign", run, time.Minute); !errors.Is(err, cron_jobs.ErrLockHeld) {
+			t.Errorf("expected %v, got %v", cron_jobs.ErrLockHeld, err)
+		}
+	})
+
+	t.Run("should run interval job once per interval across unaligned instances", func(t *testing.T) {
+		st := &memStorage{objects: make(map[string][]byte)}
+
+		var (
+			mu   sync.Mutex
+			runs []string
+		)
+		replica := func(name string, start time.Time) (*cron_jobs.Cron, *clock.Fake, chan cron_jobs.Event) {
+			clk := clock.NewFake(start)
+			events := make(chan cron_jobs.Event, 10)
+			c := cron_jobs.NewCron(
+				cron_jobs.WithClock(clk),
+				cron_jobs.WithLocker(cron_jobs.NewStorageLocker(st, "bucket", "cron-locks", name), time.Minute),
+				cron_jobs.WithListener(func(ev cron_jobs.Event) {
+					events <- ev
+				}, cron_jobs.EventSucceeded, cron_jobs.EventSkipped),
+			)
+			if _, err := c.AddJobWithInterval(10*time.Second, func(ctx context.Context) error {
+				run, _ := cron_jobs.RunFromContext(ctx)
+				mu.Lock()
+				defer mu.Unlock()
+				runs = append(runs, name+" "+run.ScheduledAt.Format("15:04:05"))
+				return nil
+			}, cron_jobs.WithName("resign")); err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+			return c, clk, events
+		}
+
+		released := func(t *testing.T) {
+			t.Helper()
+			waitReleased(t, st, "cron-locks/resign.lease.json")
+		}
+
+		// replicas started 4s apart, each fire the job every 10s from its own start
+		replicaA, clkA, eventsA := replica("replica-a", time.Date(2023, 6, 19, 9, 0, 3, 0, time.UTC))
+		replicaB, clkB, eventsB := replica("replica-b", time.Date(2023, 6, 19, 9, 0, 7, 0, time.UTC))
+		defer replicaA.Stop()
+		defer replicaB.Stop()
+
+		replicaA.StartAsync()
+		if ev := waitEvent(t, eventsA); ev.Type != cron_jobs.EventSucceeded {
+			t.Fatalf("expected first run of replica a, got %+v", ev)
+		}
+		released(t)
+		replicaB.StartAsync()
+		if ev := waitEvent(t, eventsB); ev.Type != cron_jobs.EventSkipped {
+			t.Fatalf("expected first run of replica b skipped, got %+v", ev)
+		}
+
+		// a bit late like a real clock, see nextMinute
+		for i := 0; i < 3; i++ {
+			clkA.BlockUntil(1)
+			clkA.Advance(10*time.Second + time.Millisecond)
+			if ev := waitEvent(t, eventsA); ev.Type != cron_jobs.EventSucceeded {
+				t.Fatalf("expected replica a to run, got %+v", ev)
+			}
+			released(t)
+
+			clkB.BlockUntil(1)
+			clkB.Advance(10*time.Second + time.Millisecond)
+			if ev := waitEvent(t, eventsB); ev.Type != cron_jobs.EventSkipped || !strings.Contains(ev.Error, cron_jobs.ErrRunDone.Error()) {
+				t.Fatalf("expected replica b skipped, got %+v", ev)
+			}
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		expected := "[replica-a 09:00:03 replica-a 09:00:13 replica-a 09:00:23 replica-a 09:00:33]"
+		if got := fmt.Sprint(runs); got != expected {
+			t.Errorf("expected %s, got %s", expected, got)
+		}
+	})
+
+	t.Run("should renew lease by the cron clock", func(t *testing.T) {
+		st := &memStorage{objects: make(map[string][]byte)}
+		clk := clock.NewFake(run)
+		c := cron_jobs.NewCron(
+			cron_jobs.WithClock(clk),
+			cron_jobs.WithLocker(cron_jobs.NewStorageLocker(st, "bucket", "cron-locks", "replica-a", cron_jobs.WithLockerClock(clk)), 30*time.Second),
+		)
+		defer c.Stop()
+
+		release := make(chan struct{})
+		if _, err := c.AddJobWithCron("0 0 1 1 *", func(ctx context.Context) error {
+			<-release
+			return nil
+		}, cron_jobs.WithName("resign")); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		defer close(release)
+
+		if _, err := c.Trigger("resign", nil); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		// renewed every third of the ttl
+		clk.BlockUntil(1)
+		clk.Advance(10 * time.Second)
+
+		expected := fmt.Sprintf(`"expires_at":"%s"`, run.Add(40*time.Second).Format(time.RFC3339))
+		deadline := time.Now().Add(time.Second)
+		for {
+			data, _, _ := st.Read(ctx, "bucket", "cron-locks/resign.lease.json")
+			if strings.Contains(string(data), expected) {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("expected lease renewed with %s, got %s", expected, data)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	})
+
+	t.Run("should not take scheduled run by a manual run", func(t *testing.T) {
+		st := &memStorage{objects: make(map[string][]byte)}
+		clk := clock.NewFake(time.Date(2023, 6, 19, 9, 0, 3, 0, time.UTC))
+		events := make(chan cron_jobs.Event, 10)
+		c := cron_jobs.NewCron(
+			cron_jobs.WithClock(clk),
+			cron_jobs.WithLocker(cron_jobs.NewStorageLocker(st, "bucket", "cron-locks", "replica-a", cron_jobs.WithLockerClock(clk)), time.Minute),
+			cron_jobs.WithListener(func(ev cron_jobs.Event) {
+				events <- ev
+			}, cron_jobs.EventSucceeded, cron_jobs.EventSkipped),
+		)
+		defer c.Stop()
+
+		if _, err := c.AddJobWithInterval(10*time.Second, func(ctx context.Context) error {
+			return nil
+		}, cron_jobs.WithName("resign")); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		c.StartAsync()
+		if ev := waitEvent(t, events); ev.Type != cron_jobs.EventSucceeded {
+			t.Fatalf("expected first run, got %+v", ev)
+		}
+		waitReleased(t, st, "cron-locks/resign.lease.json")
+
+		// triggered at 09:00:12, in the slot of the run scheduled at 09:00:13
+		clk.BlockUntil(1)
+		clk.Advance(9 * time.Second)
+		if _, err := c.Trigger("resign", nil); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if ev := waitEvent(t, events); ev.Type != cron_jobs.EventSucceeded || !ev.Manual {
+			t.Fatalf("expected manual run, got %+v", ev)
+		}
+		waitReleased(t, st, "cron-locks/resign.lease.json")
+
+		clk.Advance(time.Second + time.Millisecond)
+		if ev := waitEvent(t, events); ev.Type != cron_jobs.EventSucceeded || ev.Manual {
+			t.Errorf("expected scheduled run, got %+v", ev)
+		}
+	})
+}
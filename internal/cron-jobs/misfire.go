@@ -0,0 +1,120 @@
+package cron_jobs
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// MisfirePolicy decide what to do with runs missed while the scheduler was down
+type MisfirePolicy int
+
+const (
+	// MisfireSkip ignore missed runs
+	MisfireSkip MisfirePolicy = iota
+	// MisfireRunOnce run once immediately if at least one run was missed
+	MisfireRunOnce
+	// MisfireRunAll run every missed occurrence, up to the job misfire limit
+	MisfireRunAll
+)
+
+// DefaultMisfireLimit is the max missed runs replayed by MisfireRunAll when the job has no limit
+const DefaultMisfireLimit = 10
+
+var misfirePolicyNames = map[MisfirePolicy]string{
+	MisfireSkip:    "skip",
+	MisfireRunOnce: "run_once",
+	MisfireRunAll:  "run_all",
+}
+
+func (p MisfirePolicy) String() string {
+	if name, ok := misfirePolicyNames[p]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("MisfirePolicy(%d)", int(p))
+}
+
+// ParseMisfirePolicy parse policy name: skip, run_once or run_all
+func ParseMisfirePolicy(s string) (MisfirePolicy, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return MisfireSkip, nil
+	}
+
+	for p, name := range misfirePolicyNames {
+		if name == s {
+			return p, nil
+		}
+	}
+
+	return MisfireSkip, fmt.Errorf("cron: unknown misfire policy %q", s)
+}
+
+// missedRuns returns the occurrences of sch after last and not after now, at most limit
+// the most recent occurrences are kept when there are more than limit
+func missedRuns(sch Schedule, last, now time.Time, limit int) []time.Time {
+	var missed []time.Time
+
+	for next := sch.Next(last); !next.IsZero() && !next.After(now); next = sch.Next(next) {
+		missed = append(missed, next)
+		if len(missed) > limit {
+			missed = missed[1:]
+		}
+	}
+
+	return missed
+}
+
+// catchUp apply the misfire policy of the job using its persisted last successful run
+func (c *Cron) catchUp(j *Job) {
+	if c.opts.State == nil || j.cfg.Misfire == MisfireSkip {
+		return
+	}
+
+	last, err := c.opts.State.LastSuccess(c.ctx, j.name)
+	if err != nil {
+		log.Printf("cron: failed to load state of job %s: %v\n", j.name, err)
+		return
+	}
+
+	// never succeeded before, nothing was missed
+	if last.IsZero() {
+		return
+	}
+
+	// interval jobs run as soon as they are scheduled, which is already the catch up
+	if _, ok := j.Schedule().(*intervalSchedule); ok {
+		return
+	}
+
+	limit := 1
+	if j.cfg.Misfire == MisfireRunAll {
+		limit = j.cfg.MisfireLimit
+		if limit <= 0 {
+			limit = DefaultMisfireLimit
+		}
+	}
+
+	missed := missedRuns(j.Schedule(), last, c.clock.Now(), limit)
+	if len(missed) == 0 {
+		return
+	}
+
+	log.Printf("cron: job %s missed runs since %v, catching up %d run(s)\n", j.name, last, len(missed))
+
+	go func() {
+		for _, t := range missed {
+			c.execute(j, runRequest{ScheduledAt: t})
+		}
+	}()
+}
+
+// catchUpAll apply misfire policy of every registered job and resume their shifted runs
+func (c *Cron) catchUpAll() {
+	for _, j := range c.Jobs() {
+		c.catchUp(j)
+		c.resumeDeferred(j)
+	}
+}
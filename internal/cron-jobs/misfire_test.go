@@ -0,0 +1,242 @@
+package cron_jobs_test
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	cron_jobs "github.com/vldcreation/sample-cron-go/internal/cron-jobs"
+)
+
+func TestCatchUpMissedRuns(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	// yearly job, scheduler never runs it during the test
+	spec := "0 0 1 1 *"
+	lastSuccess := time.Date(now.Year()-5, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		policy   cron_jobs.MisfirePolicy
+		limit    int
+		expected []time.Time
+	}{
+		{
+			name:   "should skip missed runs",
+			policy: cron_jobs.MisfireSkip,
+		},
+		{
+			name:     "should run once for the latest missed run",
+			policy:   cron_jobs.MisfireRunOnce,
+			expected: []time.Time{time.Date(now.Year(), 1, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:   "should run every missed run up to the limit",
+			policy: cron_jobs.MisfireRunAll,
+			limit:  2,
+			expected: []time.Time{
+				time.Date(now.Year()-1, 1, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(now.Year(), 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, err := cron_jobs.NewFileState(filepath.Join(t.TempDir(), "state.json"))
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+			state.SetLastSuccess(ctx, "yearly", lastSuccess)
+
+			c := cron_jobs.NewCron(cron_jobs.WithState(state), cron_jobs.WithLocation(time.UTC))
+			defer c.Stop()
+
+			var (
+				mu   sync.Mutex
+				runs []time.Time
+				done = make(chan struct{}, 10)
+			)
+			_, err = c.AddJobWithCron(spec, func(ctx context.Context) error {
+				run, _ := cron_jobs.RunFromContext(ctx)
+				mu.Lock()
+				runs = append(runs, run.ScheduledAt)
+				mu.Unlock()
+				done <- struct{}{}
+				return nil
+			}, cron_jobs.WithName("yearly"), cron_jobs.WithMisfire(tt.policy, tt.limit))
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			c.StartAsync()
+
+			for range tt.expected {
+				select {
+				case <-done:
+				case <-time.After(time.Second):
+					t.Fatalf("timeout waiting for catch up run")
+				}
+			}
+
+			// wait for running jobs so the state is saved
+			c.Stop()
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if len(runs) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, runs)
+			}
+			for i := range runs {
+				if !runs[i].Equal(tt.expected[i]) {
+					t.Errorf("run %d: expected %v, got %v", i, tt.expected[i], runs[i])
+				}
+			}
+
+			// last success advanced to the latest caught up run
+			if len(tt.expected) > 0 {
+				last, _ := state.LastSuccess(ctx, "yearly")
+				if !last.Equal(tt.expected[len(tt.expected)-1]) {
+					t.Errorf("expected last success %v, got %v", tt.expected[len(tt.expected)-1], last)
+				}
+			}
+		})
+	}
+}
+
+func TestCatchUpIntervalJob(t *testing.T) {
+	t.Run("should not catch up interval job already running on start", func(t *testing.T) {
+		state := cron_jobs.NewMemoryState()
+		state.SetLastSuccess(context.Background(), "resign", time.Now().Add(-time.Hour))
+
+		c := cron_jobs.NewCron(cron_jobs.WithState(state))
+
+		runs := make(chan time.Time, 10)
+		if _, err := c.AddJobWithInterval(time.Hour, func(ctx context.Context) error {
+			run, _ := cron_jobs.RunFromContext(ctx)
+			runs <- run.ScheduledAt
+			return nil
+		}, cron_jobs.WithName("resign"), cron_jobs.WithMisfire(cron_jobs.MisfireRunAll, 0)); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		c.StartAsync()
+		select {
+		case <-runs:
+		case <-time.After(time.Second):
+			t.Fatalf("expected interval job to run on start")
+		}
+
+		time.Sleep(50 * time.Millisecond)
+		c.Stop()
+
+		if n := len(runs); n != 0 {
+			t.Errorf("expected a single run on start, got %d more", n)
+		}
+	})
+}
+
+// slowState blocks LastSuccess until released
+type slowState struct {
+	cron_jobs.StateStore
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (s *slowState) LastSuccess(ctx context.Context, job string) (time.Time, error) {
+	close(s.entered)
+	<-s.release
+	return s.StateStore.LastSuccess(ctx, job)
+}
+
+func TestCatchUpAddedJob(t *testing.T) {
+	t.Run("should not hold the scheduler while catching up a job added to a running scheduler", func(t *testing.T) {
+		state := &slowState{StateStore: cron_jobs.NewMemoryState(), entered: make(chan struct{}), release: make(chan struct{})}
+
+		c := cron_jobs.NewCron(cron_jobs.WithState(state))
+		c.StartAsync()
+		defer c.Stop()
+
+		added := make(chan error, 1)
+		go func() {
+			_, err := c.AddJobWithCron("0 0 1 1 *", func(ctx context.Context) error { return nil },
+				cron_jobs.WithName("yearly"), cron_jobs.WithMisfire(cron_jobs.MisfireRunOnce, 0))
+			added <- err
+		}()
+
+		select {
+		case <-state.entered:
+		case <-time.After(time.Second):
+			t.Fatalf("expected added job to be caught up")
+		}
+
+		listed := make(chan int, 1)
+		go func() { listed <- len(c.Jobs()) }()
+		select {
+		case n := <-listed:
+			if n != 1 {
+				t.Errorf("expected 1 job, got %d", n)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("expected jobs to be listed while the state store is read")
+		}
+
+		close(state.release)
+		if err := <-added; err != nil {
+			t.Errorf("expected nil, got %v", err)
+		}
+	})
+}
+
+func TestStorageState(t *testing.T) {
+	ctx := context.Background()
+	st := &memStorage{objects: make(map[string][]byte)}
+	replicaA := cron_jobs.NewStorageState(st, "bucket", "cron-state")
+	replicaB := cron_jobs.NewStorageState(st, "bucket", "cron-state")
+
+	t.Run("should share last success between instances", func(t *testing.T) {
+		if last, err := replicaA.LastSuccess(ctx, "resign"); err != nil || !last.IsZero() {
+			t.Fatalf("expected zero time, got %v (%v)", last, err)
+		}
+
+		run := time.Date(2023, 6, 19, 9, 0, 0, 0, time.UTC)
+		if err := replicaA.SetLastSuccess(ctx, "resign", run); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		// an older run finishing late does not move the state back
+		if err := replicaB.SetLastSuccess(ctx, "resign", run.Add(-time.Hour)); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		if last, err := replicaB.LastSuccess(ctx, "resign"); err != nil || !last.Equal(run) {
+			t.Errorf("expected %v, got %v (%v)", run, last, err)
+		}
+	})
+
+	t.Run("should share shifted runs between instances", func(t *testing.T) {
+		monday := time.Date(2023, 6, 19, 9, 0, 0, 0, time.UTC)
+		tuesday := monday.AddDate(0, 0, 1)
+
+		for _, run := range []time.Time{tuesday, monday, tuesday} {
+			if err := replicaA.AddDeferredRun(ctx, "resign", run); err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+		}
+		if err := replicaB.RemoveDeferredRun(ctx, "resign", monday); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		runs, err := replicaA.DeferredRuns(ctx, "resign")
+		if err != nil || len(runs) != 1 || !runs[0].Equal(tuesday) {
+			t.Errorf("expected [%v], got %v (%v)", tuesday, runs, err)
+		}
+		// the last success is kept along the shifted runs
+		if last, err := replicaA.LastSuccess(ctx, "resign"); err != nil || last.IsZero() {
+			t.Errorf("expected last success kept, got %v (%v)", last, err)
+		}
+	})
+}
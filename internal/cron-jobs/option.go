@@ -0,0 +1,230 @@
+package cron_jobs
+
+import (
+	"time"
+
+	"github.com/vldcreation/sample-cron-go/internal/clock"
+)
+
+// Option configure the Cron scheduler
+type Option func(opts *CronOptions)
+
+// JobOption configure a single job
+type JobOption func(cfg *jobConfig)
+
+type jobConfig struct {
+	// Name unique name of the job, generated when empty
+	Name string
+	// Tags used to group jobs
+	Tags []string
+	// Params passed to the job function, see Params
+	Params map[string]string
+	// Retry policy applied within a single run, default no retry
+	Retry RetryPolicy
+	// Overlap decide what to do when the previous run is still running, default allow
+	Overlap OverlapPolicy
+	// Misfire decide what to do with runs missed while the scheduler was down, default skip
+	Misfire MisfirePolicy
+	// MisfireLimit is the max missed runs replayed by MisfireRunAll
+	MisfireLimit int
+	// Limits bound how often and when the job runs, default no limit
+	Limits Limits
+	// Timezone IANA name used to evaluate cron spec and limits, e.g. "Asia/Jakarta"
+	// empty means use the scheduler location
+	Timezone string
+	// Calendar is the name of the registered calendar telling business days, empty means every day
+	Calendar string
+	// CalendarPolicy decide what to do with runs falling on a non business day, default skip
+	CalendarPolicy CalendarPolicy
+	// Breaker suspend the job after consecutive failed runs, default disabled
+	Breaker BreakerPolicy
+}
+
+func newJobConfig(opts ...JobOption) jobConfig {
+	cfg := jobConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return cfg
+}
+
+func WithLocation(loc *time.Location) Option {
+	return func(o *CronOptions) {
+		o.Location = loc
+	}
+}
+
+func WithDrainTimeout(d time.Duration) Option {
+	return func(o *CronOptions) {
+		o.DrainTimeout = d
+	}
+}
+
+// WithMaxConcurrentJobs limit how many jobs run at the same time,
+// a run waits for a free slot when the limit is reached
+func WithMaxConcurrentJobs(n int) Option {
+	return func(o *CronOptions) {
+		o.MaxConcurrentJobs = n
+	}
+}
+
+// WithHistory set the store recording job executions, nil disable recording
+func WithHistory(store HistoryStore) Option {
+	return func(o *CronOptions) {
+		o.History = store
+	}
+}
+
+// WithState set the store persisting job state, required to catch up missed runs
+func WithState(store StateStore) Option {
+	return func(o *CronOptions) {
+		o.State = store
+	}
+}
+
+// WithLocker make sure only one instance execute a scheduled run
+func WithLocker(locker Locker, ttl time.Duration) Option {
+	return func(o *CronOptions) {
+		o.Locker = locker
+		o.LockTTL = ttl
+	}
+}
+
+// WithClock set the clock used to schedule and record runs, e.g. clock.NewFake in tests
+func WithClock(clk clock.Clock) Option {
+	return func(o *CronOptions) {
+		o.Clock = clk
+	}
+}
+
+// WithDeadLetters send runs failing after their retries to queue
+func WithDeadLetters(queue DeadLetterQueue) Option {
+	return func(o *CronOptions) {
+		o.DeadLetters = queue
+	}
+}
+
+// WithAtStore persist the one-shot jobs scheduled by At in store, instances sharing it run every job once
+func WithAtStore(store AtStore) Option {
+	return func(o *CronOptions) {
+		o.AtStore = store
+	}
+}
+
+// WithAtPollInterval set how often the at store is checked for jobs added by other instances
+func WithAtPollInterval(d time.Duration) Option {
+	return func(o *CronOptions) {
+		o.AtPollInterval = d
+	}
+}
+
+// WithListener register listener for the given event types, none means every event
+func WithListener(fn Listener, types ...EventType) Option {
+	return func(o *CronOptions) {
+		o.Listeners = append(o.Listeners, only(fn, types))
+	}
+}
+
+// WithMiddleware wrap every attempt of every job, e.g. for tracing
+func WithMiddleware(mw ...Middleware) Option {
+	return func(o *CronOptions) {
+		o.Middlewares = append(o.Middlewares, mw...)
+	}
+}
+
+func WithTimezone(tz string) JobOption {
+	return func(c *jobConfig) {
+		c.Timezone = tz
+	}
+}
+
+func WithName(name string) JobOption {
+	return func(c *jobConfig) {
+		c.Name = name
+	}
+}
+
+func WithTags(tags ...string) JobOption {
+	return func(c *jobConfig) {
+		c.Tags = append(c.Tags, tags...)
+	}
+}
+
+func WithRetry(policy RetryPolicy) JobOption {
+	return func(c *jobConfig) {
+		c.Retry = policy
+	}
+}
+
+// WithBreaker skip scheduled runs for the policy cooldown after the policy threshold of consecutive failures,
+// then let a single run probe whether the job recovered
+func WithBreaker(policy BreakerPolicy) JobOption {
+	return func(c *jobConfig) {
+		c.Breaker = policy
+	}
+}
+
+func WithOverlap(policy OverlapPolicy) JobOption {
+	return func(c *jobConfig) {
+		c.Overlap = policy
+	}
+}
+
+// WithMisfire set the misfire policy, limit is only used by MisfireRunAll
+func WithMisfire(policy MisfirePolicy, limit int) JobOption {
+	return func(c *jobConfig) {
+		c.Misfire = policy
+		c.MisfireLimit = limit
+	}
+}
+
+// WithParams set parameters available to the job function through Params
+func WithParams(params map[string]string) JobOption {
+	return func(c *jobConfig) {
+		c.Params = params
+	}
+}
+
+// WithMaxRuns retire the job after n started runs
+func WithMaxRuns(n int) JobOption {
+	return func(c *jobConfig) {
+		c.Limits.MaxRuns = n
+	}
+}
+
+// WithStartAfter skip runs scheduled before t
+func WithStartAfter(t time.Time) JobOption {
+	return func(c *jobConfig) {
+		c.Limits.StartAfter = t
+	}
+}
+
+// WithStopAfter retire the job once t has passed
+func WithStopAfter(t time.Time) JobOption {
+	return func(c *jobConfig) {
+		c.Limits.StopAfter = t
+	}
+}
+
+// WithWindows only allow runs within the given daily windows
+func WithWindows(windows ...Window) JobOption {
+	return func(c *jobConfig) {
+		c.Limits.Windows = append(c.Limits.Windows, windows...)
+	}
+}
+
+// WithBlackouts skip runs within the given daily windows
+func WithBlackouts(windows ...Window) JobOption {
+	return func(c *jobConfig) {
+		c.Limits.Blackouts = append(c.Limits.Blackouts, windows...)
+	}
+}
+
+// WithCalendar only run the job on business days of the registered calendar
+func WithCalendar(name string, policy CalendarPolicy) JobOption {
+	return func(c *jobConfig) {
+		c.Calendar = name
+		c.CalendarPolicy = policy
+	}
+}
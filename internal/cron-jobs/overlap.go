@@ -0,0 +1,170 @@
+package cron_jobs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// OverlapPolicy decide what to do when a job is triggered while the previous run is still running
+type OverlapPolicy int
+
+const (
+	// OverlapAllow run concurrently with the running one
+	OverlapAllow OverlapPolicy = iota
+	// OverlapSkip skip the new run if the job is still running (singleton)
+	OverlapSkip
+	// OverlapQueue keep at most one pending run, started when the running one finishes
+	OverlapQueue
+	// OverlapReplace cancel the running one and start the new run once it returns
+	OverlapReplace
+)
+
+var overlapPolicyNames = map[OverlapPolicy]string{
+	OverlapAllow:   "allow",
+	OverlapSkip:    "skip",
+	OverlapQueue:   "queue",
+	OverlapReplace: "replace",
+}
+
+func (p OverlapPolicy) String() string {
+	if name, ok := overlapPolicyNames[p]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("OverlapPolicy(%d)", int(p))
+}
+
+// ParseOverlapPolicy parse policy name: allow, skip (or singleton), queue or replace
+func ParseOverlapPolicy(s string) (OverlapPolicy, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "":
+		return OverlapAllow, nil
+	case "singleton":
+		return OverlapSkip, nil
+	}
+
+	for p, name := range overlapPolicyNames {
+		if name == s {
+			return p, nil
+		}
+	}
+
+	return OverlapAllow, fmt.Errorf("cron: unknown overlap policy %q", s)
+}
+
+// overlapGuard serialize runs of a single job according to its overlap policy
+type overlapGuard struct {
+	policy OverlapPolicy
+
+	// sem is held by the running run, unused for OverlapAllow
+	sem chan struct{}
+
+	// pending reports whether a queued run is waiting, protected by the job mutex
+	pending bool
+	// cancel the running run, used by OverlapReplace
+	cancel context.CancelFunc
+}
+
+func newOverlapGuard(policy OverlapPolicy) *overlapGuard {
+	return &overlapGuard{
+		policy: policy,
+		sem:    make(chan struct{}, 1),
+	}
+}
+
+// acquire wait until the run may start according to the overlap policy.
+// It returns the context of the run, or false when the run should be skipped.
+func (j *Job) acquire(parent context.Context) (context.Context, bool) {
+	g := j.overlap
+
+	if g.policy == OverlapAllow {
+		return parent, true
+	}
+
+	select {
+	case g.sem <- struct{}{}:
+		return j.startRun(parent), true
+	default:
+	}
+
+	switch g.policy {
+	case OverlapSkip:
+		return nil, false
+	case OverlapQueue:
+		j.mu.Lock()
+		if g.pending {
+			j.mu.Unlock()
+			return nil, false
+		}
+		g.pending = true
+		j.mu.Unlock()
+
+		defer func() {
+			j.mu.Lock()
+			g.pending = false
+			j.mu.Unlock()
+		}()
+	case OverlapReplace:
+		j.mu.Lock()
+		if g.cancel != nil {
+			g.cancel()
+		}
+		j.mu.Unlock()
+	}
+
+	select {
+	case g.sem <- struct{}{}:
+		return j.startRun(parent), true
+	case <-parent.Done():
+		return nil, false
+	}
+}
+
+func (j *Job) startRun(parent context.Context) context.Context {
+	ctx, cancel := context.WithCancel(parent)
+
+	j.mu.Lock()
+	j.overlap.cancel = cancel
+	j.mu.Unlock()
+
+	return ctx
+}
+
+// release must be called once the run acquired returns
+func (j *Job) release() {
+	g := j.overlap
+	if g.policy == OverlapAllow {
+		return
+	}
+
+	j.mu.Lock()
+	if g.cancel != nil {
+		g.cancel()
+		g.cancel = nil
+	}
+	j.mu.Unlock()
+
+	<-g.sem
+}
+
+// acquireSlot wait for a free slot when the scheduler limit concurrent jobs
+func (c *Cron) acquireSlot(ctx context.Context) bool {
+	if c.slots == nil {
+		return true
+	}
+
+	select {
+	case c.slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (c *Cron) releaseSlot() {
+	if c.slots != nil {
+		<-c.slots
+	}
+}
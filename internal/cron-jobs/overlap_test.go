@@ -0,0 +1,203 @@
+package cron_jobs_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	cron_jobs "github.com/vldcreation/sample-cron-go/internal/cron-jobs"
+)
+
+// blockingJob returns job function reporting every start on started and returning once released
+// or cancelled, the run id is sent on started and the result on done
+func blockingJob(started chan<- string, release <-chan struct{}, done chan<- error) cron_jobs.JobFunc {
+	return func(ctx context.Context) error {
+		run, _ := cron_jobs.RunFromContext(ctx)
+		started <- run.ID
+
+		var err error
+		select {
+		case <-release:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		done <- err
+		return err
+	}
+}
+
+func waitString(t *testing.T, ch <-chan string) string {
+	t.Helper()
+
+	select {
+	case s := <-ch:
+		return s
+	case <-time.After(time.Second):
+		t.Fatalf("expected value")
+		return ""
+	}
+}
+
+func expectNothing(t *testing.T, ch <-chan string) {
+	t.Helper()
+
+	select {
+	case s := <-ch:
+		t.Fatalf("expected nothing, got %s", s)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestOverlapPolicy(t *testing.T) {
+	setup := func(t *testing.T, policy cron_jobs.OverlapPolicy) (*cron_jobs.Cron, chan string, chan struct{}, chan error, chan cron_jobs.Event) {
+		t.Helper()
+
+		skipped := make(chan cron_jobs.Event, 10)
+		c := cron_jobs.NewCron(cron_jobs.WithListener(func(ev cron_jobs.Event) {
+			skipped <- ev
+		}, cron_jobs.EventSkipped))
+		t.Cleanup(c.Stop)
+
+		started := make(chan string, 10)
+		release := make(chan struct{})
+		done := make(chan error, 10)
+		if _, err := c.AddJobWithCron("0 0 1 1 *", blockingJob(started, release, done), cron_jobs.WithName("resign"), cron_jobs.WithOverlap(policy)); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		return c, started, release, done, skipped
+	}
+
+	t.Run("should run concurrently when allowed", func(t *testing.T) {
+		c, started, release, _, _ := setup(t, cron_jobs.OverlapAllow)
+
+		first, _ := c.Trigger("resign", nil)
+		second, _ := c.Trigger("resign", nil)
+
+		got := map[string]bool{waitString(t, started): true, waitString(t, started): true}
+		if !got[first] || !got[second] {
+			t.Errorf("expected runs %s and %s running together, got %v", first, second, got)
+		}
+		close(release)
+	})
+
+	t.Run("should skip run while previous one is running", func(t *testing.T) {
+		c, started, release, _, skipped := setup(t, cron_jobs.OverlapSkip)
+
+		c.Trigger("resign", nil)
+		waitString(t, started)
+
+		second, _ := c.Trigger("resign", nil)
+		if ev := waitEvent(t, skipped); ev.RunID != second || ev.Error != "previous run still running" {
+			t.Errorf("expected run %s skipped, got %+v", second, ev)
+		}
+		expectNothing(t, started)
+
+		close(release)
+	})
+
+	t.Run("should queue one run until previous one finishes", func(t *testing.T) {
+		c, started, release, _, skipped := setup(t, cron_jobs.OverlapQueue)
+
+		c.Trigger("resign", nil)
+		waitString(t, started)
+
+		queued, _ := c.Trigger("resign", nil)
+		expectNothing(t, started)
+
+		// only one run is kept pending
+		third, _ := c.Trigger("resign", nil)
+		if ev := waitEvent(t, skipped); ev.RunID != third {
+			t.Errorf("expected run %s skipped, got %+v", third, ev)
+		}
+
+		release <- struct{}{}
+		if got := waitString(t, started); got != queued {
+			t.Errorf("expected queued run %s started, got %s", queued, got)
+		}
+		close(release)
+	})
+
+	t.Run("should cancel running run and start the new one", func(t *testing.T) {
+		c, started, release, done, _ := setup(t, cron_jobs.OverlapReplace)
+
+		first, _ := c.Trigger("resign", nil)
+		waitString(t, started)
+
+		second, _ := c.Trigger("resign", nil)
+		select {
+		case err := <-done:
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("expected %v, got %v", context.Canceled, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected run %s cancelled", first)
+		}
+
+		if got := waitString(t, started); got != second {
+			t.Errorf("expected run %s started, got %s", second, got)
+		}
+		close(release)
+	})
+}
+
+func TestMaxConcurrentJobs(t *testing.T) {
+	t.Run("should wait for a free slot", func(t *testing.T) {
+		c := cron_jobs.NewCron(cron_jobs.WithMaxConcurrentJobs(1))
+		defer c.Stop()
+
+		started := make(chan string, 10)
+		release := make(chan struct{})
+		done := make(chan error, 10)
+		for _, name := range []string{"resign", "archive"} {
+			if _, err := c.AddJobWithCron("0 0 1 1 *", blockingJob(started, release, done), cron_jobs.WithName(name)); err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+		}
+
+		first, _ := c.Trigger("resign", nil)
+		if got := waitString(t, started); got != first {
+			t.Fatalf("expected run %s started, got %s", first, got)
+		}
+
+		second, _ := c.Trigger("archive", nil)
+		expectNothing(t, started)
+
+		release <- struct{}{}
+		if got := waitString(t, started); got != second {
+			t.Errorf("expected run %s started once the slot is free, got %s", second, got)
+		}
+		close(release)
+	})
+
+	t.Run("should record run cancelled while waiting for a slot", func(t *testing.T) {
+		skipped := make(chan cron_jobs.Event, 10)
+		c := cron_jobs.NewCron(cron_jobs.WithMaxConcurrentJobs(1), cron_jobs.WithListener(func(ev cron_jobs.Event) {
+			skipped <- ev
+		}, cron_jobs.EventSkipped))
+
+		started := make(chan string, 10)
+		done := make(chan error, 10)
+		for _, name := range []string{"resign", "archive"} {
+			if _, err := c.AddJobWithCron("0 0 1 1 *", blockingJob(started, nil, done), cron_jobs.WithName(name)); err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+		}
+
+		c.Trigger("resign", nil)
+		waitString(t, started)
+		waiting, _ := c.Trigger("archive", nil)
+		expectNothing(t, started)
+
+		c.Stop()
+
+		if ev := waitEvent(t, skipped); ev.RunID != waiting || ev.Error != "cancelled while waiting for a free slot" {
+			t.Errorf("expected run %s skipped, got %+v", waiting, ev)
+		}
+		records, _ := c.History().Query(context.Background(), cron_jobs.HistoryQuery{RunID: waiting})
+		if len(records) != 1 || records[0].Status != cron_jobs.RunSkipped {
+			t.Errorf("expected skipped record of run %s, got %+v", waiting, records)
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package cron_jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/vldcreation/sample-cron-go/internal/clock"
+)
+
+var ErrRetriesExhausted = errors.New("cron: retries exhausted")
+
+// RetryPolicy describe how a failed job run is retried within the same run
+type RetryPolicy struct {
+	// MaxAttempts is the total attempts including the first one, <= 1 means no retry
+	MaxAttempts int
+	// InitialBackoff is the wait before the second attempt
+	InitialBackoff time.Duration
+	// MaxBackoff cap the wait between attempts, zero means no cap
+	MaxBackoff time.Duration
+	// Multiplier grow the backoff after each attempt, default to 2
+	Multiplier float64
+	// Jitter randomize the backoff by +/- the given fraction, e.g. 0.2 means +/- 20%
+	Jitter float64
+	// Retryable reports whether the error should be retried,
+	// nil means every error except context cancellation and Permanent error
+	Retryable func(err error) bool
+	// Clock measure the backoff, nil means the system clock
+	Clock clock.Clock
+}
+
+// DefaultRetryPolicy retry 3 times with exponential backoff starting from 1 second
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Second * 30,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// RetryError is returned when a job still fails after all attempts
+type RetryError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("%v after %d attempts: %v", ErrRetriesExhausted, e.Attempts, e.Err)
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+func (e *RetryError) Is(target error) bool {
+	return target == ErrRetriesExhausted
+}
+
+// permanentError mark an error as not retryable
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent wrap err so the job is not retried
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &permanentError{err: err}
+}
+
+// Backoff returns the wait before the given attempt, attempt start from 1
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	if attempt <= 1 || p.InitialBackoff <= 0 {
+		return 0
+	}
+
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+
+	backoff := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-2))
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+
+	if p.Jitter > 0 {
+		backoff += backoff * p.Jitter * (rand.Float64()*2 - 1)
+	}
+
+	return time.Duration(backoff)
+}
+
+func (p RetryPolicy) shouldRetry(err error) bool {
+	var permanent *permanentError
+	if errors.As(err, &permanent) || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+
+	return true
+}
+
+// Do call fn until it succeeds, the error is not retryable or attempts are exhausted.
+// onRetry is called before each retry, it may be nil
+func (p RetryPolicy) Do(ctx context.Context, fn JobFunc, onRetry func(attempt int, err error)) error {
+	maxAttempts := p.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if attempt > 1 {
+			if onRetry != nil {
+				onRetry(attempt, err)
+			}
+
+			timer := clock.OrReal(p.Clock).NewTimer(p.Backoff(attempt))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C():
+			}
+		}
+
+		err = invoke(ctx, fn)
+		if err == nil {
+			return nil
+		}
+
+		if !p.shouldRetry(err) {
+			return err
+		}
+	}
+
+	if maxAttempts == 1 {
+		return err
+	}
+
+	return &RetryError{Attempts: maxAttempts, Err: err}
+}
+
+// retryPolicy returns p measuring its backoff with the cron clock, unless it has its own
+func (c *Cron) retryPolicy(p RetryPolicy) RetryPolicy {
+	if p.Clock == nil {
+		p.Clock = c.clock
+	}
+
+	return p
+}
@@ -0,0 +1,121 @@
+package cron_jobs_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	cron_jobs "github.com/vldcreation/sample-cron-go/internal/cron-jobs"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := cron_jobs.RetryPolicy{
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Second * 5,
+		Multiplier:     2,
+	}
+
+	tests := []struct {
+		attempt  int
+		expected time.Duration
+	}{
+		{attempt: 1, expected: 0},
+		{attempt: 2, expected: time.Second},
+		{attempt: 3, expected: time.Second * 2},
+		{attempt: 4, expected: time.Second * 4},
+		{attempt: 5, expected: time.Second * 5},
+	}
+
+	for _, tt := range tests {
+		if got := policy.Backoff(tt.attempt); got != tt.expected {
+			t.Errorf("attempt %d: expected %v, got %v", tt.attempt, tt.expected, got)
+		}
+	}
+
+	t.Run("should keep jitter within range", func(t *testing.T) {
+		policy.Jitter = 0.5
+		for i := 0; i < 100; i++ {
+			got := policy.Backoff(2)
+			if got < time.Second/2 || got > time.Second*3/2 {
+				t.Fatalf("expected backoff between 500ms and 1.5s, got %v", got)
+			}
+		}
+	})
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	errTransient := errors.New("transient")
+	policy := cron_jobs.RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+	}
+
+	t.Run("should succeed after transient errors", func(t *testing.T) {
+		calls := 0
+		err := policy.Do(context.Background(), func(ctx context.Context) error {
+			calls++
+			if calls < 3 {
+				return errTransient
+			}
+			return nil
+		}, nil)
+
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+		}
+		if calls != 3 {
+			t.Errorf("expected 3 calls, got %d", calls)
+		}
+	})
+
+	t.Run("should report exhausted retries", func(t *testing.T) {
+		retries := 0
+		err := policy.Do(context.Background(), func(ctx context.Context) error {
+			return errTransient
+		}, func(attempt int, err error) {
+			retries++
+		})
+
+		if !errors.Is(err, cron_jobs.ErrRetriesExhausted) || !errors.Is(err, errTransient) {
+			t.Errorf("expected exhausted %v, got %v", errTransient, err)
+		}
+		if retries != 2 {
+			t.Errorf("expected 2 retries, got %d", retries)
+		}
+	})
+
+	t.Run("should not retry permanent or non retryable error", func(t *testing.T) {
+		calls := 0
+		err := policy.Do(context.Background(), func(ctx context.Context) error {
+			calls++
+			return cron_jobs.Permanent(errTransient)
+		}, nil)
+
+		if !errors.Is(err, errTransient) || calls != 1 {
+			t.Errorf("expected single call with %v, got %d calls with %v", errTransient, calls, err)
+		}
+
+		calls = 0
+		p := policy
+		p.Retryable = func(err error) bool { return false }
+		_ = p.Do(context.Background(), func(ctx context.Context) error {
+			calls++
+			return errTransient
+		}, nil)
+
+		if calls != 1 {
+			t.Errorf("expected 1 call, got %d", calls)
+		}
+	})
+
+	t.Run("should convert panic into error", func(t *testing.T) {
+		err := cron_jobs.RetryPolicy{}.Do(context.Background(), func(ctx context.Context) error {
+			panic("boom")
+		}, nil)
+
+		if !errors.Is(err, cron_jobs.ErrJobPanic) {
+			t.Errorf("expected %v, got %v", cron_jobs.ErrJobPanic, err)
+		}
+	})
+}
@@ -0,0 +1,328 @@
+package cron_jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrJobPanic     = errors.New("cron: job panic")
+	ErrDrainTimeout = errors.New("cron: drain timeout exceeded")
+	ErrCronStopped  = errors.New("cron: scheduler stopped")
+)
+
+// JobFunc is the function executed by the scheduler.
+// ctx is cancelled when the scheduler is stopped, a job should return as soon as possible.
+type JobFunc func(ctx context.Context) error
+
+// Middleware wrap a job function, RunFromContext is available to the wrapper
+type Middleware func(next JobFunc) JobFunc
+
+// RunInfo describe the current run of a job, available from the job context
+type RunInfo struct {
+	ID          string
+	Job         string
+	ScheduledAt time.Time
+	Attempt     int
+	// Token is the fencing token of the run lease, zero when locking is disabled.
+	// Pass it to external systems so they can reject writes from a stale run.
+	Token int64
+	// Manual reports whether the run was triggered on demand instead of by the schedule
+	Manual bool
+}
+
+type runKey struct{}
+
+// RunFromContext returns the run info of the job being executed
+func RunFromContext(ctx context.Context) (RunInfo, bool) {
+	run, ok := ctx.Value(runKey{}).(RunInfo)
+	return run, ok
+}
+
+func withRun(ctx context.Context, run RunInfo) context.Context {
+	return context.WithValue(ctx, runKey{}, run)
+}
+
+// track register a running job so shutdown can wait for it,
+// returns false when the scheduler is stopping
+func (c *Cron) track() bool {
+	c.runMu.Lock()
+	defer c.runMu.Unlock()
+
+	if c.closing {
+		return false
+	}
+
+	c.wg.Add(1)
+	return true
+}
+
+// runRequest describe a single run to execute
+type runRequest struct {
+	// ID of the run, generated when empty
+	ID          string
+	ScheduledAt time.Time
+	// Params override the job params for this run
+	Params map[string]string
+	// Manual run ignore pause and run windows
+	Manual bool
+	// ReplayOf is the run id of the replayed dead letter
+	ReplayOf string
+	// Claimed run is already exclusive to this instance, e.g. an at job, it is not locked
+	Claimed bool
+	// Deferred run was shifted to a business day, its persisted entry is removed once it starts
+	Deferred bool
+}
+
+// runJob is registered to the scheduler for every job
+func (c *Cron) runJob(j *Job) {
+	c.execute(j, runRequest{ScheduledAt: j.scheduledAt(c.clock.Now())})
+}
+
+// execute run the job as requested, blocking until the run finishes
+func (c *Cron) execute(j *Job, req runRequest) {
+	if !c.track() {
+		return
+	}
+	defer c.wg.Done()
+
+	if req.ID == "" {
+		req.ID = uuid.NewString()
+	}
+
+	run := &RunInfo{
+		ID:          req.ID,
+		Job:         j.name,
+		ScheduledAt: req.ScheduledAt,
+		Manual:      req.Manual,
+	}
+
+	if j.Retired() {
+		return
+	}
+
+	c.emit(runEvent(EventScheduled, run))
+
+	if !req.Manual {
+		if j.Paused() {
+			c.skip(run, "paused")
+			return
+		}
+
+		if reason, retire := j.cfg.Limits.check(req.ScheduledAt.In(j.location()), j.RunCount()); reason != "" {
+			c.skip(run, reason)
+			if retire {
+				c.retire(j, reason)
+			}
+			return
+		}
+
+		if c.offDay(j, run) {
+			return
+		}
+
+		probe, reason := j.breaker.allow(c.clock.Now())
+		if reason != "" {
+			c.skip(run, reason)
+			return
+		}
+		if probe {
+			// the probe may be skipped below, let the next run probe then
+			defer j.breaker.release()
+		}
+	}
+
+	ctx, ok := j.acquire(c.ctx)
+	if !ok {
+		log.Printf("cron: job %s skipped, previous run still running\n", j.name)
+		c.skip(run, "previous run still running")
+		return
+	}
+	defer j.release()
+
+	if !c.acquireSlot(ctx) {
+		log.Printf("cron: job %s skipped, cancelled while waiting for a free slot\n", j.name)
+		c.skip(run, "cancelled while waiting for a free slot")
+		return
+	}
+	defer c.releaseSlot()
+
+	var (
+		lease Lease
+		err   error
+	)
+	if !req.Claimed {
+		// a manual run holds the lease without taking the scheduled run of its slot
+		var at time.Time
+		if !req.Manual {
+			at = j.lockedAt(run.ScheduledAt)
+		}
+		if lease, err = c.lock(ctx, run, at); err != nil {
+			if req.Deferred && errors.Is(err, ErrRunDone) {
+				c.removeDeferred(j, run.ScheduledAt)
+			}
+			c.skip(run, err.Error())
+			return
+		}
+	}
+	if lease != nil {
+		run.Token = lease.Token()
+
+		var unlock func()
+		ctx, unlock = c.keepAlive(ctx, run, lease)
+		defer unlock()
+	}
+
+	if !j.markStarted() {
+		// a concurrent run took the last one
+		c.skip(run, fmt.Sprintf("max runs %d reached", j.cfg.Limits.MaxRuns))
+		return
+	}
+
+	if req.Deferred {
+		c.removeDeferred(j, run.ScheduledAt)
+	}
+
+	params := mergeParams(j.cfg.Params, req.Params)
+
+	started := runEvent(EventStarted, run)
+	started.StartedAt = c.clock.Now()
+	c.emit(started)
+
+	var attempts []RunRecord
+	err = c.retryPolicy(j.cfg.Retry).Do(ctx, func(ctx context.Context) error {
+		rec, err := c.attempt(withParams(ctx, params), j, run)
+		attempts = append(attempts, rec)
+		return err
+	}, func(attempt int, err error) {
+		log.Printf("cron: job %s failed, retrying attempt %d: %v\n", j.name, attempt, err)
+
+		retried := runEvent(EventRetried, run)
+		retried.StartedAt = started.StartedAt
+		retried.Error = err.Error()
+		c.emit(retried)
+	})
+	j.markFinished(err)
+	c.breakerDone(j, run, err)
+
+	finished := runEvent(EventSucceeded, run)
+	finished.StartedAt = started.StartedAt
+	finished.FinishedAt = c.clock.Now()
+	finished.Duration = finished.FinishedAt.Sub(finished.StartedAt)
+	if err != nil {
+		finished.Type = EventFailed
+		finished.Error = err.Error()
+	}
+	c.emit(finished)
+
+	if j.exhausted() {
+		c.retire(j, fmt.Sprintf("max runs %d reached", j.cfg.Limits.MaxRuns))
+	}
+
+	if err != nil {
+		log.Printf("cron: job %s failed: %v\n", j.name, err)
+		c.deadLetter(j, run, req, params, attempts, err)
+		return
+	}
+
+	if c.opts.State != nil {
+		if err := c.opts.State.SetLastSuccess(context.Background(), j.name, run.ScheduledAt); err != nil {
+			log.Printf("cron: failed to save state of job %s: %v\n", j.name, err)
+		}
+	}
+}
+
+// attempt execute a single attempt of the run and record it
+func (c *Cron) attempt(ctx context.Context, j *Job, run *RunInfo) (RunRecord, error) {
+	run.Attempt++
+
+	rec := RunRecord{
+		RunID:       run.ID,
+		Job:         run.Job,
+		ScheduledAt: run.ScheduledAt,
+		StartedAt:   c.clock.Now(),
+		Attempt:     run.Attempt,
+	}
+
+	err := invoke(withRun(ctx, *run), c.wrap(j.cmd))
+
+	rec.FinishedAt = c.clock.Now()
+	rec.Duration = rec.FinishedAt.Sub(rec.StartedAt)
+	rec.Status = statusOf(err)
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	c.record(rec)
+
+	return rec, err
+}
+
+// skip record a run that did not start
+func (c *Cron) skip(run *RunInfo, reason string) {
+	now := c.clock.Now()
+	c.record(RunRecord{
+		RunID:       run.ID,
+		Job:         run.Job,
+		ScheduledAt: run.ScheduledAt,
+		StartedAt:   now,
+		FinishedAt:  now,
+		Status:      RunSkipped,
+		Error:       reason,
+	})
+
+	ev := runEvent(EventSkipped, run)
+	ev.Error = reason
+	c.emit(ev)
+}
+
+func (c *Cron) record(rec RunRecord) {
+	if c.opts.History == nil {
+		return
+	}
+
+	// job context may already be cancelled, the record should still be stored
+	if err := c.opts.History.Record(context.Background(), rec); err != nil {
+		log.Printf("cron: failed to record run %s of job %s: %v\n", rec.RunID, rec.Job, err)
+	}
+}
+
+func statusOf(err error) RunStatus {
+	switch {
+	case err == nil:
+		return RunSuccess
+	case errors.Is(err, context.Canceled):
+		return RunCancelled
+	default:
+		return RunFailed
+	}
+}
+
+// wrap apply the scheduler middlewares to cmd, panic of cmd is converted into error
+// before reaching the middlewares
+func (c *Cron) wrap(cmd JobFunc) JobFunc {
+	fn := func(ctx context.Context) error {
+		return invoke(ctx, cmd)
+	}
+
+	for i := len(c.opts.Middlewares) - 1; i >= 0; i-- {
+		fn = c.opts.Middlewares[i](fn)
+	}
+
+	return fn
+}
+
+// invoke call the job function, converting panic into error
+func invoke(ctx context.Context, fn JobFunc) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrJobPanic, r)
+		}
+	}()
+
+	return fn(ctx)
+}
@@ -0,0 +1,184 @@
+package cron_jobs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+var (
+	ErrInvalidSpec     = errors.New("cron: invalid schedule spec")
+	ErrInvalidInterval = errors.New("cron: invalid interval")
+)
+
+// cronParser accept both standard 5 fields and 6 fields (with seconds) spec,
+// including descriptor like @daily, @hourly or @every 15m
+var cronParser = cron.NewParser(
+	cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// Schedule describe when a job should run
+type Schedule interface {
+	// Next returns the next activation time, later than the given time.
+	Next(time.Time) time.Time
+}
+
+// cronSchedule wrap parsed cron spec, keep the raw spec so it can be
+// registered to the scheduler as is
+type cronSchedule struct {
+	spec        string
+	withSeconds bool
+	schedule    cron.Schedule
+}
+
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	return s.schedule.Next(t)
+}
+
+func (s *cronSchedule) String() string {
+	return s.spec
+}
+
+// intervalSchedule run every fixed duration
+type intervalSchedule struct {
+	every time.Duration
+}
+
+func (s *intervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(s.every)
+}
+
+func (s *intervalSchedule) String() string {
+	return "@every " + s.every.String()
+}
+
+// slot returns the start of the interval t falls in, intervals are aligned on the zero time
+// so instances started at different times agree on them
+func (s *intervalSchedule) slot(t time.Time) time.Time {
+	return t.Truncate(s.every)
+}
+
+// ParseCronSpec parse standard cron expression (5 or 6 fields) in the given time zone.
+// @spec: e.g. "*/5 * * * *", "0 30 9 * * 1-5", "@daily", "@every 15m"
+// @tz: IANA time zone name, e.g. "Asia/Jakarta"
+//
+//	empty tz means the spec is evaluated in time.Local
+func ParseCronSpec(spec, tz string) (Schedule, error) {
+	return parseCronSpec(spec, tz, nil)
+}
+
+func parseCronSpec(spec, tz string, loc *time.Location) (*cronSchedule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("%w: empty spec", ErrInvalidSpec)
+	}
+
+	// spec may already carry its own time zone
+	if strings.HasPrefix(spec, "TZ=") || strings.HasPrefix(spec, "CRON_TZ=") {
+		if tz != "" {
+			return nil, fmt.Errorf("%w: time zone given twice in %q", ErrInvalidSpec, spec)
+		}
+	} else {
+		if tz == "" {
+			if loc == nil {
+				loc = time.Local
+			}
+			tz = loc.String()
+		}
+
+		if _, err := time.LoadLocation(tz); err != nil {
+			return nil, fmt.Errorf("%w: unknown time zone %q: %v", ErrInvalidSpec, tz, err)
+		}
+
+		spec = fmt.Sprintf("CRON_TZ=%s %s", tz, spec)
+	}
+
+	sch, err := cronParser.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSpec, err)
+	}
+
+	return &cronSchedule{
+		spec:        spec,
+		withSeconds: countFields(spec) == 6,
+		schedule:    sch,
+	}, nil
+}
+
+// parseInterval convert interval accepted by AddJobWithInterval into duration
+// int value is treated as seconds
+func parseInterval(interval any) (*intervalSchedule, error) {
+	var every time.Duration
+
+	switch v := interval.(type) {
+	case time.Duration:
+		every = v
+	case int:
+		every = time.Duration(v) * time.Second
+	case string:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidInterval, err)
+		}
+		every = d
+	default:
+		return nil, fmt.Errorf("%w: unsupported type %T", ErrInvalidInterval, interval)
+	}
+
+	if every <= 0 {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInterval, every)
+	}
+
+	return &intervalSchedule{every: every}, nil
+}
+
+// parseSchedule accept either a duration, e.g. "10s" or "1h30m", as interval schedule
+// or a cron expression as cron schedule
+func parseSchedule(spec, tz string, loc *time.Location) (Schedule, error) {
+	if d, err := time.ParseDuration(strings.TrimSpace(spec)); err == nil {
+		return parseInterval(d)
+	}
+
+	return parseCronSpec(spec, tz, loc)
+}
+
+// NextRuns returns the next n activation times of the schedule after from.
+func NextRuns(sch Schedule, from time.Time, n int) []time.Time {
+	runs := make([]time.Time, 0, n)
+
+	next := from
+	for i := 0; i < n; i++ {
+		next = sch.Next(next)
+		if next.IsZero() {
+			// schedule never fires again
+			break
+		}
+		runs = append(runs, next)
+	}
+
+	return runs
+}
+
+// PreviewSpec returns the next n fire times of a cron spec without registering it,
+// useful to verify a schedule before deploying
+func PreviewSpec(spec, tz string, from time.Time, n int) ([]time.Time, error) {
+	sch, err := ParseCronSpec(spec, tz)
+	if err != nil {
+		return nil, err
+	}
+
+	return NextRuns(sch, from, n), nil
+}
+
+// countFields count schedule fields, ignoring time zone prefix
+func countFields(spec string) int {
+	fields := strings.Fields(spec)
+	if len(fields) > 0 && (strings.HasPrefix(fields[0], "TZ=") || strings.HasPrefix(fields[0], "CRON_TZ=")) {
+		fields = fields[1:]
+	}
+
+	return len(fields)
+}
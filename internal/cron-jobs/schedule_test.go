@@ -0,0 +1,111 @@
+package cron_jobs_test
+
+import (
+	"testing"
+	"time"
+
+	cron_jobs "github.com/vldcreation/sample-cron-go/internal/cron-jobs"
+)
+
+func TestPreviewSpec(t *testing.T) {
+	parseLocation := func(name string) *time.Location {
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		return loc
+	}
+
+	jakarta := parseLocation("Asia/Jakarta")
+	newYork := parseLocation("America/New_York")
+
+	tests := []struct {
+		name     string
+		spec     string
+		tz       string
+		from     time.Time
+		expected []time.Time
+	}{
+		{
+			name: "should run daily at midnight in given time zone",
+			spec: "@daily",
+			tz:   "Asia/Jakarta",
+			from: time.Date(2023, 6, 18, 15, 0, 0, 0, jakarta),
+			expected: []time.Time{
+				time.Date(2023, 6, 19, 0, 0, 0, 0, jakarta),
+				time.Date(2023, 6, 20, 0, 0, 0, 0, jakarta),
+			},
+		},
+		{
+			name: "should support range and step",
+			spec: "0 9-17/4 * * 1-5",
+			tz:   "Asia/Jakarta",
+			from: time.Date(2023, 6, 16, 12, 0, 0, 0, jakarta), // friday
+			expected: []time.Time{
+				time.Date(2023, 6, 16, 13, 0, 0, 0, jakarta),
+				time.Date(2023, 6, 16, 17, 0, 0, 0, jakarta),
+				time.Date(2023, 6, 19, 9, 0, 0, 0, jakarta),
+			},
+		},
+		{
+			name: "should support seconds field",
+			spec: "30 0 12 * * *",
+			tz:   "UTC",
+			from: time.Date(2023, 6, 18, 0, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2023, 6, 18, 12, 0, 30, 0, time.UTC),
+				time.Date(2023, 6, 19, 12, 0, 30, 0, time.UTC),
+			},
+		},
+		{
+			name: "should support @every",
+			spec: "@every 15m",
+			tz:   "UTC",
+			from: time.Date(2023, 6, 18, 0, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2023, 6, 18, 0, 15, 0, 0, time.UTC),
+				time.Date(2023, 6, 18, 0, 30, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "should skip missing hour when DST starts",
+			spec: "30 2 * * *",
+			tz:   "America/New_York",
+			from: time.Date(2023, 3, 11, 12, 0, 0, 0, newYork),
+			expected: []time.Time{
+				time.Date(2023, 3, 13, 2, 30, 0, 0, newYork),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs, err := cron_jobs.PreviewSpec(tt.spec, tt.tz, tt.from, len(tt.expected))
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			if len(runs) != len(tt.expected) {
+				t.Fatalf("expected %d runs, got %d", len(tt.expected), len(runs))
+			}
+
+			for i := range runs {
+				if !runs[i].Equal(tt.expected[i]) {
+					t.Errorf("run %d: expected %v, got %v", i, tt.expected[i], runs[i])
+				}
+			}
+		})
+	}
+
+	t.Run("should return error when spec is invalid", func(t *testing.T) {
+		if _, err := cron_jobs.PreviewSpec("61 * * * *", "UTC", time.Now(), 1); err == nil {
+			t.Errorf("expected error, got nil")
+		}
+	})
+
+	t.Run("should return error when time zone is unknown", func(t *testing.T) {
+		if _, err := cron_jobs.PreviewSpec("@daily", "Mars/Olympus", time.Now(), 1); err == nil {
+			t.Errorf("expected error, got nil")
+		}
+	})
+}
@@ -0,0 +1,236 @@
+package cron_jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// StateStore persist job state that must survive restart
+type StateStore interface {
+	// LastSuccess returns the scheduled time of the last successful run, zero if the job never succeeded
+	LastSuccess(ctx context.Context, job string) (time.Time, error)
+	// SetLastSuccess store the scheduled time of the last successful run,
+	// an older time than the stored one is ignored
+	SetLastSuccess(ctx context.Context, job string, t time.Time) error
+	// DeferredRuns returns the runs of the job shifted to a business day and not started yet
+	DeferredRuns(ctx context.Context, job string) ([]time.Time, error)
+	// AddDeferredRun store a run shifted to t, a run already stored is ignored
+	AddDeferredRun(ctx context.Context, job string, t time.Time) error
+	// RemoveDeferredRun remove the run shifted to t once it is started
+	RemoveDeferredRun(ctx context.Context, job string, t time.Time) error
+}
+
+var (
+	// compile-time interface check
+	_ StateStore = (*MemoryState)(nil)
+	_ StateStore = (*FileState)(nil)
+)
+
+// MemoryState keep job state in memory, mostly useful for testing
+type MemoryState struct {
+	mu          sync.RWMutex
+	lastSuccess map[string]time.Time
+	deferred    map[string][]time.Time
+}
+
+func NewMemoryState() *MemoryState {
+	return &MemoryState{lastSuccess: make(map[string]time.Time), deferred: make(map[string][]time.Time)}
+}
+
+func (s *MemoryState) LastSuccess(ctx context.Context, job string) (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.lastSuccess[job], nil
+}
+
+func (s *MemoryState) SetLastSuccess(ctx context.Context, job string, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t.After(s.lastSuccess[job]) {
+		s.lastSuccess[job] = t
+	}
+	return nil
+}
+
+func (s *MemoryState) DeferredRuns(ctx context.Context, job string) ([]time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]time.Time(nil), s.deferred[job]...), nil
+}
+
+func (s *MemoryState) AddDeferredRun(ctx context.Context, job string, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deferred[job], _ = addRun(s.deferred[job], t)
+	return nil
+}
+
+func (s *MemoryState) RemoveDeferredRun(ctx context.Context, job string, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deferred[job], _ = removeRun(s.deferred[job], t)
+	if len(s.deferred[job]) == 0 {
+		delete(s.deferred, job)
+	}
+	return nil
+}
+
+// FileState keep job state in a local JSON file
+type FileState struct {
+	path string
+
+	mu    sync.Mutex
+	state fileStateData
+}
+
+type fileStateData struct {
+	LastSuccess map[string]time.Time   `json:"last_success"`
+	Deferred    map[string][]time.Time `json:"deferred,omitempty"`
+}
+
+// NewFileState open or create the state file at path
+func NewFileState(path string) (*FileState, error) {
+	s := &FileState{
+		path:  path,
+		state: fileStateData{LastSuccess: make(map[string]time.Time), Deferred: make(map[string][]time.Time)},
+	}
+
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("state: %w", err)
+	}
+
+	if len(b) > 0 {
+		if err := json.Unmarshal(b, &s.state); err != nil {
+			return nil, fmt.Errorf("state: %s: %w", path, err)
+		}
+	}
+
+	if s.state.LastSuccess == nil {
+		s.state.LastSuccess = make(map[string]time.Time)
+	}
+	if s.state.Deferred == nil {
+		s.state.Deferred = make(map[string][]time.Time)
+	}
+
+	return s, nil
+}
+
+func (s *FileState) LastSuccess(ctx context.Context, job string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.state.LastSuccess[job], nil
+}
+
+func (s *FileState) SetLastSuccess(ctx context.Context, job string, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !t.After(s.state.LastSuccess[job]) {
+		return nil
+	}
+	s.state.LastSuccess[job] = t
+
+	return s.save()
+}
+
+func (s *FileState) DeferredRuns(ctx context.Context, job string) ([]time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]time.Time(nil), s.state.Deferred[job]...), nil
+}
+
+func (s *FileState) AddDeferredRun(ctx context.Context, job string, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	runs, changed := addRun(s.state.Deferred[job], t)
+	if !changed {
+		return nil
+	}
+	s.state.Deferred[job] = runs
+
+	return s.save()
+}
+
+func (s *FileState) RemoveDeferredRun(ctx context.Context, job string, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	runs, changed := removeRun(s.state.Deferred[job], t)
+	if !changed {
+		return nil
+	}
+	if len(runs) == 0 {
+		delete(s.state.Deferred, job)
+	} else {
+		s.state.Deferred[job] = runs
+	}
+
+	return s.save()
+}
+
+// save write the state atomically, caller must hold s.mu
+func (s *FileState) save() error {
+	b, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("state: %w", err)
+	}
+
+	return writeFileAtomic(s.path, b)
+}
+
+// addRun returns runs with t added in time order, changed is false when t is already there
+func addRun(runs []time.Time, t time.Time) ([]time.Time, bool) {
+	i := sort.Search(len(runs), func(i int) bool { return !runs[i].Before(t) })
+	if i < len(runs) && runs[i].Equal(t) {
+		return runs, false
+	}
+
+	runs = append(runs, time.Time{})
+	copy(runs[i+1:], runs[i:])
+	runs[i] = t
+
+	return runs, true
+}
+
+// removeRun returns runs without t, changed is false when t is not there
+func removeRun(runs []time.Time, t time.Time) ([]time.Time, bool) {
+	for i, run := range runs {
+		if run.Equal(t) {
+			return append(runs[:i:i], runs[i+1:]...), true
+		}
+	}
+
+	return runs, false
+}
+
+// writeFileAtomic write to a temporary file then rename it, so a crash never leaves a partial file
+func writeFileAtomic(path string, b []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
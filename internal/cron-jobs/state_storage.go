@@ -0,0 +1,122 @@
+package cron_jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/vldcreation/sample-cron-go/internal/storage"
+)
+
+// compile-time interface check
+var _ StateStore = (*StorageState)(nil)
+
+// StorageState keep job state as JSON objects under prefix in bucket, one object per job,
+// so instances sharing the bucket catch up the same missed runs
+type StorageState struct {
+	st     storage.Storage
+	bucket string
+	prefix string
+}
+
+type storageStateData struct {
+	LastSuccess time.Time   `json:"last_success"`
+	Deferred    []time.Time `json:"deferred,omitempty"`
+}
+
+// NewStorageState returns store of the job state under prefix in bucket
+func NewStorageState(st storage.Storage, bucket, prefix string) *StorageState {
+	return &StorageState{st: st, bucket: bucket, prefix: prefix}
+}
+
+func (s *StorageState) LastSuccess(ctx context.Context, job string) (time.Time, error) {
+	data, _, err := s.read(ctx, job)
+	return data.LastSuccess, err
+}
+
+func (s *StorageState) SetLastSuccess(ctx context.Context, job string, t time.Time) error {
+	return s.update(ctx, job, func(data *storageStateData) bool {
+		if !t.After(data.LastSuccess) {
+			return false
+		}
+		data.LastSuccess = t
+		return true
+	})
+}
+
+func (s *StorageState) DeferredRuns(ctx context.Context, job string) ([]time.Time, error) {
+	data, _, err := s.read(ctx, job)
+	return data.Deferred, err
+}
+
+func (s *StorageState) AddDeferredRun(ctx context.Context, job string, t time.Time) error {
+	return s.update(ctx, job, func(data *storageStateData) (changed bool) {
+		data.Deferred, changed = addRun(data.Deferred, t)
+		return changed
+	})
+}
+
+func (s *StorageState) RemoveDeferredRun(ctx context.Context, job string, t time.Time) error {
+	return s.update(ctx, job, func(data *storageStateData) (changed bool) {
+		data.Deferred, changed = removeRun(data.Deferred, t)
+		return changed
+	})
+}
+
+// update apply fn to the state of the job and save it when fn reports a change,
+// fn is applied again when the state is saved concurrently by other instance
+func (s *StorageState) update(ctx context.Context, job string, fn func(data *storageStateData) bool) error {
+	for i := 0; i < storageCASRetries; i++ {
+		data, version, err := s.read(ctx, job)
+		if err != nil {
+			return err
+		}
+		if !fn(&data) {
+			return nil
+		}
+
+		b, err := json.Marshal(data)
+		if err != nil {
+			return fmt.Errorf("state %s: %w", job, err)
+		}
+
+		_, err = s.st.PutIfMatch(ctx, s.bucket, s.name(job), b, "application/json", version)
+		if errors.Is(err, storage.ErrPreconditionFailed) {
+			// saved by other instance, apply again
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("state %s: %w", job, err)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("state %s: too many concurrent updates", job)
+}
+
+// read returns the state of the job and the version of its object, empty when it does not exist yet
+func (s *StorageState) read(ctx context.Context, job string) (storageStateData, string, error) {
+	var data storageStateData
+
+	b, info, err := s.st.Read(ctx, s.bucket, s.name(job))
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return data, "", nil
+	case err != nil:
+		return data, "", fmt.Errorf("state %s: %w", job, err)
+	}
+
+	if err := json.Unmarshal(b, &data); err != nil {
+		return data, "", fmt.Errorf("state %s: %w", job, err)
+	}
+
+	return data, info.Version, nil
+}
+
+func (s *StorageState) name(job string) string {
+	return path.Join(s.prefix, job+".state.json")
+}
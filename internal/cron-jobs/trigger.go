@@ -0,0 +1,100 @@
+package cron_jobs
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+)
+
+// Trigger run the job now in background, regardless of its schedule, pause and run windows.
+// params override the job params for this run only. It returns the id of the run.
+func (c *Cron) Trigger(name string, params map[string]string) (string, error) {
+	return c.trigger(name, runRequest{Params: params})
+}
+
+// trigger run the job now in background as a manual run of req
+func (c *Cron) trigger(name string, req runRequest) (string, error) {
+	j, err := c.Job(name)
+	if err != nil {
+		return "", err
+	}
+	if j.Retired() {
+		return "", fmt.Errorf("%w: %s", ErrJobRetired, name)
+	}
+
+	return c.start(j, req)
+}
+
+// start execute the run of j on demand in background, returns its id
+func (c *Cron) start(j *Job, req runRequest) (string, error) {
+	// tracked until the run is tracked by execute, so shutdown waits for it
+	if !c.track() {
+		return "", ErrCronStopped
+	}
+
+	req.ID = uuid.NewString()
+	req.ScheduledAt = c.clock.Now()
+	req.Manual = true
+
+	log.Printf("cron: job %s triggered, run %s\n", j.name, req.ID)
+	go func() {
+		defer c.wg.Done()
+		c.execute(j, req)
+	}()
+
+	return req.ID, nil
+}
+
+// Pause skip scheduled runs of the job until it is resumed, a running run is not interrupted
+func (c *Cron) Pause(name string) error {
+	j, err := c.Job(name)
+	if err != nil {
+		return err
+	}
+
+	j.setPaused(true)
+	log.Printf("cron: job %s paused\n", name)
+
+	return nil
+}
+
+// Resume scheduled runs of a paused job
+func (c *Cron) Resume(name string) error {
+	j, err := c.Job(name)
+	if err != nil {
+		return err
+	}
+
+	j.setPaused(false)
+	log.Printf("cron: job %s resumed\n", name)
+
+	return nil
+}
+
+// PauseAll pause every registered job
+func (c *Cron) PauseAll() {
+	for _, j := range c.Jobs() {
+		j.setPaused(true)
+	}
+	log.Println("cron: all jobs paused")
+}
+
+// ResumeAll resume every registered job
+func (c *Cron) ResumeAll() {
+	for _, j := range c.Jobs() {
+		j.setPaused(false)
+	}
+	log.Println("cron: all jobs resumed")
+}
+
+// StopJob retire the job so it is never scheduled again, a running run is not interrupted
+func (c *Cron) StopJob(name string) error {
+	j, err := c.Job(name)
+	if err != nil {
+		return err
+	}
+
+	c.retire(j, "stopped on request")
+	return nil
+}
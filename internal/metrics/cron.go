@@ -0,0 +1,28 @@
+package metrics
+
+import (
+	"context"
+
+	cron_jobs "github.com/vldcreation/sample-cron-go/internal/cron-jobs"
+)
+
+type historyStore struct {
+	cron_jobs.HistoryStore
+	m *Metrics
+}
+
+// InstrumentHistory count every run recorded by the scheduler, pass it to cron_jobs.WithHistory.
+// Each attempt of a run is counted, skipped runs included.
+func (m *Metrics) InstrumentHistory(h cron_jobs.HistoryStore) cron_jobs.HistoryStore {
+	return &historyStore{HistoryStore: h, m: m}
+}
+
+func (h *historyStore) Record(ctx context.Context, rec cron_jobs.RunRecord) error {
+	status := string(rec.Status)
+	h.m.jobRuns.WithLabelValues(rec.Job, status).Inc()
+	if rec.Status != cron_jobs.RunSkipped {
+		h.m.jobDuration.WithLabelValues(rec.Job, status).Observe(rec.Duration.Seconds())
+	}
+
+	return h.HistoryStore.Record(ctx, rec)
+}
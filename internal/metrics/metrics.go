@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	cron_jobs "github.com/vldcreation/sample-cron-go/internal/cron-jobs"
+	"github.com/vldcreation/sample-cron-go/internal/storage"
+)
+
+const namespace = "sample_cron"
+
+// Metrics hold the collectors of the app, use the Instrument functions to wrap
+// the existing interfaces instead of updating every call site
+type Metrics struct {
+	registry *prometheus.Registry
+
+	jobRuns     *prometheus.CounterVec
+	jobDuration *prometheus.HistogramVec
+
+	storageOps      *prometheus.CounterVec
+	storageDuration *prometheus.HistogramVec
+
+	pubsubMessages        *prometheus.CounterVec
+	pubsubHandlerDuration *prometheus.HistogramVec
+}
+
+// New create metrics registered to a dedicated registry, including go runtime and process collectors
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		jobRuns: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cron",
+			Name:      "job_runs_total",
+			Help:      "Job run attempts by job and status.",
+		}, []string{"job", "status"}),
+		jobDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "cron",
+			Name:      "job_run_duration_seconds",
+			Help:      "Duration of job run attempts by job and status.",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 4, 8),
+		}, []string{"job", "status"}),
+		storageOps: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "storage",
+			Name:      "operations_total",
+			Help:      "Storage operations by backend, operation and error class.",
+		}, []string{"backend", "op", "error"}),
+		storageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "storage",
+			Name:      "operation_duration_seconds",
+			Help:      "Latency of storage operations by backend and operation.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"backend", "op"}),
+		pubsubMessages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "pubsub",
+			Name:      "messages_total",
+			Help:      "Pub/sub messages by topic, operation (publish, receive, ack) and error class.",
+		}, []string{"topic", "op", "error"}),
+		pubsubHandlerDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "pubsub",
+			Name:      "handler_duration_seconds",
+			Help:      "Latency of the subscriber handler by topic.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"topic"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.jobRuns,
+		m.jobDuration,
+		m.storageOps,
+		m.storageDuration,
+		m.pubsubMessages,
+		m.pubsubHandlerDuration,
+	)
+
+	return m
+}
+
+// Registry returns the registry collectors are registered to
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler serve the metrics in prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// errorClass group errors into a small set of label values
+func errorClass(err error) string {
+	switch {
+	case err == nil:
+		return "none"
+	case errors.Is(err, storage.ErrNotFound):
+		return "not_found"
+	case errors.Is(err, storage.ErrPreconditionFailed):
+		return "precondition_failed"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "deadline_exceeded"
+	case errors.Is(err, cron_jobs.ErrJobPanic):
+		return "panic"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,83 @@
+package metrics_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	cron_jobs "github.com/vldcreation/sample-cron-go/internal/cron-jobs"
+	"github.com/vldcreation/sample-cron-go/internal/metrics"
+	"github.com/vldcreation/sample-cron-go/internal/pubsubs"
+	"github.com/vldcreation/sample-cron-go/internal/storage"
+)
+
+type fakeStorage struct {
+	storage.Storage
+}
+
+func (f *fakeStorage) Stat(ctx context.Context, parent, name string) (*storage.ObjectInfo, error) {
+	return nil, fmt.Errorf("stat %s: %w", name, storage.ErrNotFound)
+}
+
+type fakeBroker struct{}
+
+func (f *fakeBroker) Publish(ctx context.Context, msg *pubsubs.Message) error {
+	return nil
+}
+
+func (f *fakeBroker) Subscribe(ctx context.Context, handler func(context.Context, *pubsubs.Message), opts ...pubsubs.Option) error {
+	handler(ctx, &pubsubs.Message{Topic: "cron-control"})
+	return nil
+}
+
+func TestMetrics(t *testing.T) {
+	ctx := context.Background()
+	m := metrics.New()
+
+	history := m.InstrumentHistory(cron_jobs.NewMemoryHistory(cron_jobs.HistoryRetention{}))
+	for _, status := range []cron_jobs.RunStatus{cron_jobs.RunSuccess, cron_jobs.RunFailed, cron_jobs.RunFailed} {
+		if err := history.Record(ctx, cron_jobs.RunRecord{Job: "resign", Status: status, Duration: time.Second}); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+	}
+
+	st := m.InstrumentStorage(&fakeStorage{}, "minio")
+	if _, err := st.Stat(ctx, "bucket", "a.jpeg"); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+
+	pub := m.InstrumentPublisher(&fakeBroker{})
+	if err := pub.Publish(ctx, &pubsubs.Message{Topic: "cron"}); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	sub := m.InstrumentSubscriber(&fakeBroker{})
+	if err := sub.Subscribe(ctx, func(ctx context.Context, msg *pubsubs.Message) {}); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+
+	expected := []string{
+		`sample_cron_cron_job_runs_total{job="resign",status="failed"} 2`,
+		`sample_cron_cron_job_runs_total{job="resign",status="success"} 1`,
+		`sample_cron_cron_job_run_duration_seconds_count{job="resign",status="failed"} 2`,
+		`sample_cron_storage_operations_total{backend="minio",error="not_found",op="stat"} 1`,
+		`sample_cron_storage_operation_duration_seconds_count{backend="minio",op="stat"} 1`,
+		`sample_cron_pubsub_messages_total{error="none",op="publish",topic="cron"} 1`,
+		`sample_cron_pubsub_messages_total{error="none",op="receive",topic="cron-control"} 1`,
+		`sample_cron_pubsub_messages_total{error="none",op="ack",topic="cron-control"} 1`,
+		`sample_cron_pubsub_handler_duration_seconds_count{topic="cron-control"} 1`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(string(body), line) {
+			t.Errorf("expected metrics to contain %s", line)
+		}
+	}
+}
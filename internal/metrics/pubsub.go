@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/vldcreation/sample-cron-go/internal/pubsubs"
+)
+
+type instrumentedPublisher struct {
+	next pubsubs.Publisher
+	m    *Metrics
+}
+
+// InstrumentPublisher count published messages by topic and error class
+func (m *Metrics) InstrumentPublisher(pub pubsubs.Publisher) pubsubs.Publisher {
+	return &instrumentedPublisher{next: pub, m: m}
+}
+
+func (p *instrumentedPublisher) Publish(ctx context.Context, msg *pubsubs.Message) error {
+	err := p.next.Publish(ctx, msg)
+	p.m.pubsubMessages.WithLabelValues(msg.Topic, "publish", errorClass(err)).Inc()
+
+	return err
+}
+
+type instrumentedSubscriber struct {
+	next pubsubs.Subscriberer
+	m    *Metrics
+}
+
+// InstrumentSubscriber count received and acked messages and time the handler.
+// The subscriber ack a message once the handler returns.
+func (m *Metrics) InstrumentSubscriber(sub pubsubs.Subscriberer) pubsubs.Subscriberer {
+	return &instrumentedSubscriber{next: sub, m: m}
+}
+
+func (s *instrumentedSubscriber) Subscribe(ctx context.Context, handler func(context.Context, *pubsubs.Message), opts ...pubsubs.Option) error {
+	return s.next.Subscribe(ctx, func(ctx context.Context, msg *pubsubs.Message) {
+		s.m.pubsubMessages.WithLabelValues(msg.Topic, "receive", "none").Inc()
+
+		start := time.Now()
+		handler(ctx, msg)
+		s.m.pubsubHandlerDuration.WithLabelValues(msg.Topic).Observe(time.Since(start).Seconds())
+
+		s.m.pubsubMessages.WithLabelValues(msg.Topic, "ack", "none").Inc()
+	}, opts...)
+}
@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/vldcreation/sample-cron-go/internal/storage"
+)
+
+// compile-time interface check
+var _ storage.Storage = (*instrumentedStorage)(nil)
+
+type instrumentedStorage struct {
+	next    storage.Storage
+	backend string
+	m       *Metrics
+}
+
+// InstrumentStorage count and time every operation of st, backend is e.g. "minio" or "gcs"
+func (m *Metrics) InstrumentStorage(st storage.Storage, backend string) storage.Storage {
+	return &instrumentedStorage{next: st, backend: backend, m: m}
+}
+
+func (s *instrumentedStorage) observe(op string, start time.Time, err error) {
+	s.m.storageOps.WithLabelValues(s.backend, op, errorClass(err)).Inc()
+	s.m.storageDuration.WithLabelValues(s.backend, op).Observe(time.Since(start).Seconds())
+}
+
+func (s *instrumentedStorage) Put(ctx context.Context, parent, name string, contents []byte, cacheAble bool, contentType string) (err error) {
+	defer func(start time.Time) { s.observe("put", start, err) }(time.Now())
+	return s.next.Put(ctx, parent, name, contents, cacheAble, contentType)
+}
+
+func (s *instrumentedStorage) FPut(ctx context.Context, parent, name, filePath string, cacheAble bool, contentType string) (err error) {
+	defer func(start time.Time) { s.observe("fput", start, err) }(time.Now())
+	return s.next.FPut(ctx, parent, name, filePath, cacheAble, contentType)
+}
+
+func (s *instrumentedStorage) Delete(ctx context.Context, parent, name string) (err error) {
+	defer func(start time.Time) { s.observe("delete", start, err) }(time.Now())
+	return s.next.Delete(ctx, parent, name)
+}
+
+func (s *instrumentedStorage) Get(ctx context.Context, parent, name string) (b []byte, err error) {
+	defer func(start time.Time) { s.observe("get", start, err) }(time.Now())
+	return s.next.Get(ctx, parent, name)
+}
+
+func (s *instrumentedStorage) ReSignedURLWithReplace(ctx context.Context, parent, object string) (url string, err error) {
+	defer func(start time.Time) { s.observe("resign_with_replace", start, err) }(time.Now())
+	return s.next.ReSignedURLWithReplace(ctx, parent, object)
+}
+
+func (s *instrumentedStorage) ReSignedURL(ctx context.Context, parent, object, existingUrl string) (url string, err error) {
+	defer func(start time.Time) { s.observe("resign", start, err) }(time.Now())
+	return s.next.ReSignedURL(ctx, parent, object, existingUrl)
+}
+
+func (s *instrumentedStorage) Read(ctx context.Context, parent, name string) (b []byte, info *storage.ObjectInfo, err error) {
+	defer func(start time.Time) { s.observe("read", start, err) }(time.Now())
+	return s.next.Read(ctx, parent, name)
+}
+
+func (s *instrumentedStorage) PutIfMatch(ctx context.Context, parent, name string, contents []byte, contentType, version string) (info *storage.ObjectInfo, err error) {
+	defer func(start time.Time) { s.observe("put_if_match", start, err) }(time.Now())
+	return s.next.PutIfMatch(ctx, parent, name, contents, contentType, version)
+}
+
+func (s *instrumentedStorage) Stat(ctx context.Context, parent, name string) (info *storage.ObjectInfo, err error) {
+	defer func(start time.Time) { s.observe("stat", start, err) }(time.Now())
+	return s.next.Stat(ctx, parent, name)
+}
+
+func (s *instrumentedStorage) PresignURL(ctx context.Context, parent, name string, expires time.Duration) (url string, err error) {
+	defer func(start time.Time) { s.observe("presign", start, err) }(time.Now())
+	return s.next.PresignURL(ctx, parent, name, expires)
+}
+
+func (s *instrumentedStorage) List(ctx context.Context, parent, prefix, startAfter string, limit int) (objects []*storage.ObjectInfo, err error) {
+	defer func(start time.Time) { s.observe("list", start, err) }(time.Now())
+	return s.next.List(ctx, parent, prefix, startAfter, limit)
+}
@@ -7,7 +7,6 @@ import (
 	"time"
 
 	"cloud.google.com/go/pubsub"
-	"cloud.google.com/go/pubsublite/pscompat"
 	"github.com/rs/zerolog/log"
 )
 
@@ -63,16 +62,23 @@ func NewGSubscriber(p *Pubsubs) *gSubscriber {
 }
 
 // Subscribe publish message to the topic
-func (p *gSubscriber) Subscribe(ctx context.Context, handler func(context.Context, *Message)) error {
-	defer p.client.Close()
-
+// The client is shared with the publisher, it is closed by its owner with Pubsubs.Close
+func (p *gSubscriber) Subscribe(ctx context.Context, handler func(context.Context, *Message), opts ...Option) error {
 	cfg := defaults()
+	for _, opt := range opts {
+		opt(cfg)
+	}
 
 	log.Printf("Subscribing to topic %s", cfg.Topic)
 
 	t := createTopicIfNotExists(p.client, cfg.Topic)
 
-	sub, err := createSubsIfNotExists(p.client, cfg.Topic, t)
+	name := cfg.Subscription
+	if name == "" {
+		name = cfg.Topic
+	}
+
+	sub, err := createSubsIfNotExists(p.client, name, t)
 	if err != nil {
 		log.Fatal().Msgf("Failed to create the topic: %v", err)
 		return err
@@ -89,20 +95,14 @@ func (p *gSubscriber) Subscribe(ctx context.Context, handler func(context.Contex
 
 	err = sub.Receive(ctx, func(xCtx context.Context, msg *pubsub.Message) {
 		handler(xCtx, &Message{
-			ID:   msg.ID,
-			Data: msg.Data,
+			ID:        msg.ID,
+			Topic:     cfg.Topic,
+			Data:      msg.Data,
+			Attribute: msg.Attributes,
 		})
 		// NOTE: May be called concurrently; synchronize access to shared memory.
 		atomic.AddInt32(&received, 1)
 
-		// Metadata decoded from the message ID contains the partition and offset.
-		metadata, err := pscompat.ParseMessageMetadata(msg.ID)
-		if err != nil {
-			log.Fatal().Msgf("Failed to parse %q: %v", msg.ID, err)
-		}
-
-		fmt.Printf("Received (partition=%d, offset=%d): %s\n", metadata.Partition, metadata.Offset, string(msg.Data))
-
 		msg.Ack()
 	})
 
@@ -28,3 +28,8 @@ func NewPubSubs(ctx context.Context, cfg *global_config.Config) *Pubsubs {
 		config: cfg,
 	}
 }
+
+// Close release the client shared by the publisher and the subscriber
+func (p *Pubsubs) Close() error {
+	return p.client.Close()
+}
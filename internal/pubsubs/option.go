@@ -10,6 +10,7 @@ type config struct {
 	MaxConcurrent  int
 	SubscribeAsync bool
 	Topic          string
+	Subscription   string
 }
 
 func defaults() *config {
@@ -27,6 +28,14 @@ func WithTopic(v string) Option {
 	}
 }
 
+// WithSubscription set the subscription receiving the messages of the topic, default to the topic name.
+// Subscribers sharing a subscription share its messages, each message reaches one of them
+func WithSubscription(v string) Option {
+	return func(c *config) {
+		c.Subscription = v
+	}
+}
+
 func WithMaxConcurrent(v int) Option {
 	return func(c *config) {
 		c.MaxConcurrent = v
@@ -3,7 +3,7 @@ package pubsubs
 import "context"
 
 type Subscriberer interface {
-	Subscribe(ctx context.Context, handler func(context.Context, *Message)) error
+	Subscribe(ctx context.Context, handler func(context.Context, *Message), opts ...Option) error
 }
 
 type Publisher interface {
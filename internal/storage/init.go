@@ -3,14 +3,15 @@ package storage
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/vldcreation/sample-cron-go/internal/clock"
 	"github.com/vldcreation/sample-cron-go/internal/config"
 )
 
 var (
 	ErrNotFound     = fmt.Errorf("storage object not found")
-	conf            = config.NewAppConfig()
 	Test5Seconds    = time.Second * 5    // 5 seconds, for testing
 	Test10Seconds   = time.Second * 10   // 10 seconds, for testing
 	Test20Seconds   = time.Second * 20   // 20 seconds, for testing
@@ -19,6 +20,57 @@ var (
 	MaxDuration     = time.Hour * 24 * 7 // 7 days, max expiry for presigned URLs
 )
 
+var (
+	ErrPreconditionFailed = fmt.Errorf("storage precondition failed")
+
+	confOnce sync.Once
+	conf     *config.Config
+)
+
+// Option configure a storage client
+type Option func(o *options)
+
+type options struct {
+	clock clock.Clock
+}
+
+// WithClock set the clock used to check url expiry, default to the system clock
+func WithClock(clk clock.Clock) Option {
+	return func(o *options) {
+		o.clock = clk
+	}
+}
+
+func newOptions(opts ...Option) options {
+	o := options{clock: clock.New()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// appConfig load the app config on first use,
+// so importing the package does not require the config file
+func appConfig() *config.Config {
+	confOnce.Do(func() {
+		conf = config.NewAppConfig()
+	})
+
+	return conf
+}
+
+// ObjectInfo describe a stored object
+type ObjectInfo struct {
+	Name         string    `json:"name"`
+	Size         int64     `json:"size"`
+	ContentType  string    `json:"content_type"`
+	LastModified time.Time `json:"last_modified"`
+	// Version identify the object content, etag for minio and generation for gcs
+	// used for conditional writes
+	Version string `json:"version"`
+}
+
 type PresignUrlInfoS3 struct {
 	X_AMZ_ALGORITHM     string `json:"X-Amz-Algorithm"`
 	X_AMZ_CREDENTIAL    string `json:"X-Amz-Credential"`
@@ -53,4 +105,21 @@ type Storage interface {
 
 	// PresignURL returns a presigned URL for the object.
 	ReSignedURL(ctx context.Context, parent, object, existingUrl string) (string, error)
+
+	// Read fetches the object's contents and info, returns ErrNotFound if the object doesn't exist.
+	Read(ctx context.Context, parent, name string) ([]byte, *ObjectInfo, error)
+
+	// PutIfMatch creates or overwrites an object only if its current version matches version.
+	// Empty version means the object must not exist. Returns ErrPreconditionFailed on mismatch.
+	PutIfMatch(ctx context.Context, parent, name string, contents []byte, contentType, version string) (*ObjectInfo, error)
+
+	// Stat returns the object info, returns ErrNotFound if the object doesn't exist.
+	Stat(ctx context.Context, parent, name string) (*ObjectInfo, error)
+
+	// PresignURL returns a presigned GET URL for the object valid for the given duration.
+	PresignURL(ctx context.Context, parent, name string, expires time.Duration) (string, error)
+
+	// List returns up to limit objects whose name starts with prefix, in lexical order of name.
+	// Objects up to startAfter are skipped so listing can resume, limit <= 0 returns every object.
+	List(ctx context.Context, parent, prefix, startAfter string, limit int) ([]*ObjectInfo, error)
 }
@@ -0,0 +1,35 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	cron_jobs "github.com/vldcreation/sample-cron-go/internal/cron-jobs"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// JobMiddleware start a span around every job attempt, pass it to cron_jobs.WithMiddleware.
+// Storage and pub/sub calls made by the job with its context become children of the span.
+func JobMiddleware() cron_jobs.Middleware {
+	return func(next cron_jobs.JobFunc) cron_jobs.JobFunc {
+		return func(ctx context.Context) (err error) {
+			run, _ := cron_jobs.RunFromContext(ctx)
+
+			ctx, span := tracer().Start(ctx, "cron.run "+run.Job,
+				trace.WithSpanKind(trace.SpanKindInternal),
+				trace.WithAttributes(
+					attribute.String("cron.job", run.Job),
+					attribute.String("cron.run_id", run.ID),
+					attribute.Int("cron.attempt", run.Attempt),
+					attribute.String("cron.scheduled_at", run.ScheduledAt.Format(time.RFC3339)),
+					attribute.Bool("cron.manual", run.Manual),
+					attribute.Int64("cron.lease_token", run.Token),
+				),
+			)
+			defer func() { endSpan(span, err) }()
+
+			return next(ctx)
+		}
+	}
+}
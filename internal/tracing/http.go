@@ -0,0 +1,61 @@
+package tracing
+
+import (
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type tracedTransport struct {
+	next http.RoundTripper
+}
+
+// InstrumentHTTPClient returns a copy of client starting a client span for every request
+// and injecting its trace context into the request headers
+func InstrumentHTTPClient(client *http.Client) *http.Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	next := client.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	out := *client
+	out.Transport = &tracedTransport{next: next}
+
+	return &out
+}
+
+func (t *tracedTransport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	ctx, span := tracer().Start(req.Context(), "http "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.method", req.Method),
+			attribute.String("http.url", req.URL.Redacted()),
+		),
+	)
+	defer func() { endSpan(span, err) }()
+
+	// a round tripper must not modify the request of the caller
+	out := req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(out.Header))
+
+	resp, err = t.next.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+	if resp.StatusCode >= 500 {
+		span.SetStatus(codes.Error, fmt.Sprintf("http status %d", resp.StatusCode))
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,68 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/vldcreation/sample-cron-go/internal/pubsubs"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type tracedPublisher struct {
+	next pubsubs.Publisher
+}
+
+// InstrumentPublisher start a producer span for every message and inject
+// its trace context into the message attributes
+func InstrumentPublisher(pub pubsubs.Publisher) pubsubs.Publisher {
+	return &tracedPublisher{next: pub}
+}
+
+func (p *tracedPublisher) Publish(ctx context.Context, msg *pubsubs.Message) (err error) {
+	ctx, span := tracer().Start(ctx, "pubsub.publish "+msg.Topic,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attribute.String("messaging.destination", msg.Topic)),
+	)
+	defer func() { endSpan(span, err) }()
+
+	// do not modify the attributes owned by the caller
+	attrs := make(map[string]string, len(msg.Attribute)+2)
+	for k, v := range msg.Attribute {
+		attrs[k] = v
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(attrs))
+
+	out := *msg
+	out.Attribute = attrs
+
+	return p.next.Publish(ctx, &out)
+}
+
+type tracedSubscriber struct {
+	next pubsubs.Subscriberer
+}
+
+// InstrumentSubscriber start a consumer span around the handler,
+// child of the publisher span found in the message attributes
+func InstrumentSubscriber(sub pubsubs.Subscriberer) pubsubs.Subscriberer {
+	return &tracedSubscriber{next: sub}
+}
+
+func (s *tracedSubscriber) Subscribe(ctx context.Context, handler func(context.Context, *pubsubs.Message), opts ...pubsubs.Option) error {
+	return s.next.Subscribe(ctx, func(ctx context.Context, msg *pubsubs.Message) {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(msg.Attribute))
+
+		ctx, span := tracer().Start(ctx, "pubsub.receive "+msg.Topic,
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(
+				attribute.String("messaging.source", msg.Topic),
+				attribute.String("messaging.message_id", msg.ID),
+			),
+		)
+		defer span.End()
+
+		handler(ctx, msg)
+	}, opts...)
+}
@@ -0,0 +1,100 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"github.com/vldcreation/sample-cron-go/internal/storage"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// compile-time interface check
+var _ storage.Storage = (*tracedStorage)(nil)
+
+type tracedStorage struct {
+	next    storage.Storage
+	backend string
+}
+
+// InstrumentStorage start a span around every operation of st, backend is e.g. "minio" or "gcs"
+func InstrumentStorage(st storage.Storage, backend string) storage.Storage {
+	return &tracedStorage{next: st, backend: backend}
+}
+
+func (s *tracedStorage) start(ctx context.Context, op, parent, name string) (context.Context, trace.Span) {
+	return tracer().Start(ctx, "storage."+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("storage.backend", s.backend),
+			attribute.String("storage.bucket", parent),
+			attribute.String("storage.object", name),
+		),
+	)
+}
+
+func (s *tracedStorage) Put(ctx context.Context, parent, name string, contents []byte, cacheAble bool, contentType string) (err error) {
+	ctx, span := s.start(ctx, "put", parent, name)
+	defer func() { endSpan(span, err) }()
+	return s.next.Put(ctx, parent, name, contents, cacheAble, contentType)
+}
+
+func (s *tracedStorage) FPut(ctx context.Context, parent, name, filePath string, cacheAble bool, contentType string) (err error) {
+	ctx, span := s.start(ctx, "fput", parent, name)
+	defer func() { endSpan(span, err) }()
+	return s.next.FPut(ctx, parent, name, filePath, cacheAble, contentType)
+}
+
+func (s *tracedStorage) Delete(ctx context.Context, parent, name string) (err error) {
+	ctx, span := s.start(ctx, "delete", parent, name)
+	defer func() { endSpan(span, err) }()
+	return s.next.Delete(ctx, parent, name)
+}
+
+func (s *tracedStorage) Get(ctx context.Context, parent, name string) (b []byte, err error) {
+	ctx, span := s.start(ctx, "get", parent, name)
+	defer func() { endSpan(span, err) }()
+	return s.next.Get(ctx, parent, name)
+}
+
+func (s *tracedStorage) ReSignedURLWithReplace(ctx context.Context, parent, object string) (url string, err error) {
+	ctx, span := s.start(ctx, "resign_with_replace", parent, object)
+	defer func() { endSpan(span, err) }()
+	return s.next.ReSignedURLWithReplace(ctx, parent, object)
+}
+
+func (s *tracedStorage) ReSignedURL(ctx context.Context, parent, object, existingUrl string) (url string, err error) {
+	ctx, span := s.start(ctx, "resign", parent, object)
+	defer func() { endSpan(span, err) }()
+	return s.next.ReSignedURL(ctx, parent, object, existingUrl)
+}
+
+func (s *tracedStorage) Read(ctx context.Context, parent, name string) (b []byte, info *storage.ObjectInfo, err error) {
+	ctx, span := s.start(ctx, "read", parent, name)
+	defer func() { endSpan(span, err) }()
+	return s.next.Read(ctx, parent, name)
+}
+
+func (s *tracedStorage) PutIfMatch(ctx context.Context, parent, name string, contents []byte, contentType, version string) (info *storage.ObjectInfo, err error) {
+	ctx, span := s.start(ctx, "put_if_match", parent, name)
+	defer func() { endSpan(span, err) }()
+	return s.next.PutIfMatch(ctx, parent, name, contents, contentType, version)
+}
+
+func (s *tracedStorage) Stat(ctx context.Context, parent, name string) (info *storage.ObjectInfo, err error) {
+	ctx, span := s.start(ctx, "stat", parent, name)
+	defer func() { endSpan(span, err) }()
+	return s.next.Stat(ctx, parent, name)
+}
+
+func (s *tracedStorage) PresignURL(ctx context.Context, parent, name string, expires time.Duration) (url string, err error) {
+	ctx, span := s.start(ctx, "presign", parent, name)
+	defer func() { endSpan(span, err) }()
+	return s.next.PresignURL(ctx, parent, name, expires)
+}
+
+func (s *tracedStorage) List(ctx context.Context, parent, prefix, startAfter string, limit int) (objects []*storage.ObjectInfo, err error) {
+	ctx, span := s.start(ctx, "list", parent, prefix)
+	defer func() { endSpan(span, err) }()
+	return s.next.List(ctx, parent, prefix, startAfter, limit)
+}
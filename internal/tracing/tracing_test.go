@@ -0,0 +1,169 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vldcreation/sample-cron-go/internal/config"
+	cron_jobs "github.com/vldcreation/sample-cron-go/internal/cron-jobs"
+	"github.com/vldcreation/sample-cron-go/internal/pubsubs"
+	"github.com/vldcreation/sample-cron-go/internal/storage"
+	"github.com/vldcreation/sample-cron-go/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// fakeBroker deliver every published message to the subscriber handler
+type fakeBroker struct {
+	messages chan *pubsubs.Message
+}
+
+func (b *fakeBroker) Publish(ctx context.Context, msg *pubsubs.Message) error {
+	b.messages <- msg
+	return nil
+}
+
+func (b *fakeBroker) Subscribe(ctx context.Context, handler func(context.Context, *pubsubs.Message), opts ...pubsubs.Option) error {
+	// a subscriber receive messages without the publisher context
+	handler(context.Background(), <-b.messages)
+	return nil
+}
+
+type fakeStorage struct {
+	storage.Storage
+}
+
+func (f *fakeStorage) Stat(ctx context.Context, parent, name string) (*storage.ObjectInfo, error) {
+	return nil, storage.ErrNotFound
+}
+
+func setup(t *testing.T) *tracetest.SpanRecorder {
+	if _, err := tracing.Setup(config.TracingConfig{}); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	recorder := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	return recorder
+}
+
+func TestTracing(t *testing.T) {
+	t.Run("should continue publisher trace in subscriber handler", func(t *testing.T) {
+		recorder := setup(t)
+
+		broker := &fakeBroker{messages: make(chan *pubsubs.Message, 1)}
+		pub := tracing.InstrumentPublisher(broker)
+		sub := tracing.InstrumentSubscriber(broker)
+
+		attrs := map[string]string{"kind": "test"}
+		if err := pub.Publish(context.Background(), &pubsubs.Message{Topic: "cron", Attribute: attrs}); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if len(attrs) != 1 {
+			t.Errorf("expected caller attributes to be left untouched, got %v", attrs)
+		}
+
+		if err := sub.Subscribe(context.Background(), func(ctx context.Context, msg *pubsubs.Message) {}); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		spans := recorder.Ended()
+		if len(spans) != 2 {
+			t.Fatalf("expected 2 spans, got %d", len(spans))
+		}
+
+		publish, receive := spans[0], spans[1]
+		if receive.Parent().SpanID() != publish.SpanContext().SpanID() || receive.SpanContext().TraceID() != publish.SpanContext().TraceID() {
+			t.Errorf("expected receive span to be child of publish span")
+		}
+	})
+
+	t.Run("should trace job run and its storage calls", func(t *testing.T) {
+		recorder := setup(t)
+
+		st := tracing.InstrumentStorage(&fakeStorage{}, "minio")
+		c := cron_jobs.NewCron(cron_jobs.WithMiddleware(tracing.JobMiddleware()))
+		defer c.Stop()
+
+		done := make(chan struct{})
+		if _, err := c.AddJobWithCron("0 0 1 1 *", func(ctx context.Context) error {
+			defer close(done)
+			_, err := st.Stat(ctx, "bucket", "a.jpeg")
+			return err
+		}, cron_jobs.WithName("resign")); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		if _, err := c.Trigger("resign", nil); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("expected job to run")
+		}
+
+		var spans []sdktrace.ReadOnlySpan
+		deadline := time.Now().Add(time.Second)
+		for len(spans) < 2 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+			spans = recorder.Ended()
+		}
+		if len(spans) != 2 {
+			t.Fatalf("expected 2 spans, got %d", len(spans))
+		}
+
+		stat, run := spans[0], spans[1]
+		if run.Name() != "cron.run resign" || stat.Name() != "storage.stat" {
+			t.Errorf("expected cron.run resign and storage.stat, got %s and %s", run.Name(), stat.Name())
+		}
+		if stat.Parent().SpanID() != run.SpanContext().SpanID() {
+			t.Errorf("expected storage span to be child of run span")
+		}
+		if run.Status().Code != codes.Error {
+			t.Errorf("expected run span to record the error, got %v", run.Status())
+		}
+	})
+	t.Run("should inject trace context into http request headers", func(t *testing.T) {
+		recorder := setup(t)
+
+		var traceparent string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			traceparent = r.Header.Get("Traceparent")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer srv.Close()
+
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/jobs/sync", nil)
+		resp, err := tracing.InstrumentHTTPClient(srv.Client()).Do(req)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		resp.Body.Close()
+
+		if req.Header.Get("Traceparent") != "" {
+			t.Errorf("expected caller request to be left untouched")
+		}
+
+		spans := recorder.Ended()
+		if len(spans) != 1 {
+			t.Fatalf("expected 1 span, got %d", len(spans))
+		}
+		if expected := spans[0].SpanContext().TraceID().String(); !strings.Contains(traceparent, expected) {
+			t.Errorf("expected traceparent of trace %s, got %q", expected, traceparent)
+		}
+		if spans[0].Name() != "http POST" || spans[0].Status().Code != codes.Error {
+			t.Errorf("expected failed http POST span, got %s %v", spans[0].Name(), spans[0].Status())
+		}
+	})
+}
@@ -2,11 +2,14 @@ package utils
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/url"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/vldcreation/sample-cron-go/internal/clock"
 )
 
 func ParseUrl(_url string, sc interface{}) error {
@@ -62,14 +65,58 @@ func ParseDate(strDate string) (time.Time, error) {
 	return date, err
 }
 
+// ParseUnix parse unix timestamp in seconds, e.g. "1687101570", returns time in UTC
 func ParseUnix(unixStr string) (time.Time, error) {
-	unixConv, err := strconv.Atoi(unixStr)
+	unixConv, err := strconv.ParseInt(unixStr, 10, 64)
 	if err != nil {
 		return time.Time{}, err
 	}
 
-	expiresInt, _ := time.Parse("2006-01-02 15:04:05", time.Unix(0, 0).String())
-	return expiresInt.Add(time.Second * time.Duration(unixConv)), nil
+	return time.Unix(unixConv, 0).UTC(), nil
+}
+
+// URLExpiry returns when a presigned url expires. It understands S3 urls (X-Amz-Date and X-Amz-Expires),
+// GCS V4 urls (X-Goog-Date and X-Goog-Expires) and GCS V2 urls (Expires unix timestamp)
+func URLExpiry(_url string) (time.Time, error) {
+	query := url.Values{}
+	if err := ParseUrl(_url, &query); err != nil {
+		return time.Time{}, err
+	}
+
+	for _, prefix := range []string{"X-Amz-", "X-Goog-"} {
+		date, expires := query.Get(prefix+"Date"), query.Get(prefix+"Expires")
+		if date == "" || expires == "" {
+			continue
+		}
+
+		signedAt, err := ParseDate(date)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		seconds, err := strconv.Atoi(expires)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		return signedAt.Add(time.Second * time.Duration(seconds)), nil
+	}
+
+	if expires := query.Get("Expires"); expires != "" {
+		return ParseUnix(expires)
+	}
+
+	return time.Time{}, errors.New("url is not presigned")
+}
+
+// URLExpired reports whether the presigned url is expired at the time of clk
+func URLExpired(clk clock.Clock, _url string) (bool, error) {
+	expiry, err := URLExpiry(_url)
+	if err != nil {
+		return false, err
+	}
+
+	return !clock.OrReal(clk).Now().Before(expiry), nil
 }
 
 // @strDate: date string to be parsed
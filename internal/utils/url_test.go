@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/vldcreation/sample-cron-go/internal/clock"
 	"github.com/vldcreation/sample-cron-go/internal/utils"
 )
 
@@ -156,3 +157,72 @@ func defaultTestParseUrlData() interface{} {
 		X_AMZ_SIGNATURE:     "xx",
 	}
 }
+
+func TestParseUnix(t *testing.T) {
+	t.Run("should parse unix timestamp in seconds", func(t *testing.T) {
+		got, err := utils.ParseUnix("1687101570")
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		expected := time.Date(2023, 6, 18, 15, 19, 30, 0, time.UTC)
+		if !got.Equal(expected) {
+			t.Errorf("expected %v, got %v", expected, got)
+		}
+	})
+
+	t.Run("should return error when timestamp is invalid", func(t *testing.T) {
+		if _, err := utils.ParseUnix("tomorrow"); err == nil {
+			t.Errorf("expected error, got nil")
+		}
+	})
+}
+
+func TestURLExpired(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		expires time.Time
+	}{
+		{
+			name:    "should read expiry of s3 url",
+			url:     "http://127.0.0.1:9000/dci-auth/sample.jpeg?X-Amz-Algorithm=AWS4-HMAC-SHA256&X-Amz-Date=20230618T151930Z&X-Amz-Expires=20&X-Amz-SignedHeaders=host&X-Amz-Signature=7ced",
+			expires: time.Date(2023, 6, 18, 15, 19, 50, 0, time.UTC),
+		},
+		{
+			name:    "should read expiry of gcs v4 url",
+			url:     "https://storage.googleapis.com/dci-auth/sample.jpeg?X-Goog-Algorithm=GOOG4-RSA-SHA256&X-Goog-Date=20230618T151930Z&X-Goog-Expires=60&X-Goog-Signature=7ced",
+			expires: time.Date(2023, 6, 18, 15, 20, 30, 0, time.UTC),
+		},
+		{
+			name:    "should read expiry of gcs v2 url",
+			url:     "https://storage.googleapis.com/dci-auth/sample.jpeg?GoogleAccessId=sample&Expires=1687101630&Signature=7ced",
+			expires: time.Date(2023, 6, 18, 15, 20, 30, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewFake(tt.expires.Add(-time.Second))
+
+			expired, err := utils.URLExpired(clk, tt.url)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+			if expired {
+				t.Errorf("expected url to be valid a second before %v", tt.expires)
+			}
+
+			clk.Advance(time.Second)
+			if expired, _ = utils.URLExpired(clk, tt.url); !expired {
+				t.Errorf("expected url to be expired at %v", tt.expires)
+			}
+		})
+	}
+
+	t.Run("should return error when url is not presigned", func(t *testing.T) {
+		if _, err := utils.URLExpired(clock.New(), "http://127.0.0.1:9000/dci-auth/sample.jpeg"); err == nil {
+			t.Errorf("expected error, got nil")
+		}
+	})
+}
@@ -2,19 +2,19 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"log"
 	"os"
-	"time"
+	"os/signal"
+	"syscall"
 
 	"github.com/vldcreation/sample-cron-go/internal/app"
-	"github.com/vldcreation/sample-cron-go/internal/pubsubs"
-	"github.com/vldcreation/sample-cron-go/internal/storage"
+	cron_jobs "github.com/vldcreation/sample-cron-go/internal/cron-jobs"
 	"github.com/vldcreation/sample-cron-go/internal/utils"
 )
 
 func main() {
-	ctx, cancel := context.WithCancel(context.Background())
+	// cancelled on SIGINT/SIGTERM or when stop message is received
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
 	initApp := app.App{}
@@ -56,8 +56,6 @@ func main() {
 		panic(err)
 	}
 
-	var URLGenerated string
-
 	// first generated url
 	bt, err := initApp.Storage.Get(ctx, initApp.Config.Storage.Bucket, fileName+"."+ext)
 	if err != nil {
@@ -65,78 +63,36 @@ func main() {
 		panic(err)
 	}
 
-	URLGenerated = string(bt)
-	log.Printf("first url: %v\n", URLGenerated)
+	log.Printf("first url: %v\n", string(bt))
 
 	//
-	// initialize adjustment to stop cron
+	// initialize control plane, commands are published as JSON to the control topic, e.g.
+	// {"id": "1", "action": "trigger", "job": "resign-url", "params": {"object": "sample.jpeg"}}
 	//
-	var (
-		maxCron int = 2
-		curCron int = 0
-		// use as flat channel to stop cron
-		isStop = make(chan bool)
-	)
-
-	fnIter := func() {
-		if curCron >= maxCron {
-			isStop <- true
-			// instead of using channel, we can publish message to pubsub (just for sample purpose)
-			// to stop cron
-			log.Printf("Send message to stop cron\n")
-			if err := initApp.Publisherer.Publish(ctx, &pubsubs.Message{
-				Topic: initApp.Config.PubSub.Topic,
-				Data:  []byte("stop cron"),
-			}); err != nil {
-				log.Fatalf("error publish message: %v\n", err)
-			}
-		} else {
-			log.Printf("current cron: %v\n", curCron)
-			log.Printf("remain cron: %d\n", maxCron-curCron)
-			curCron++
-		}
-	}
-
-	// declare function to stop cron
-	fnStop := func(ctx context.Context, message *pubsubs.Message) {
-		log.Print(fmt.Sprintf("%s received %s\n", message.Topic, string(message.Data)))
-		initApp.Cron.Stop()
-		os.Exit(0)
-	}
-
-	// declare function to reSigned url, then send to cron
-	ResignedURLFunc := func() {
-		go fnIter()
-		log.Println("reSigned url for file ", fileName)
-		resResignedUrl, err := initApp.Storage.ReSignedURL(ctx, initApp.Config.Storage.Bucket, fileName+"."+ext, URLGenerated)
-		if err != nil {
-			log.Fatalf("error reSigned url: %v\n", err)
-			panic(err)
-		}
-
-		URLGenerated = resResignedUrl
-		log.Printf("new reSigned url: %v\n", resResignedUrl)
-
-		//
-		// initialize subcriber to stop cron
-		//
+	// the control topic is not shared with the business messages, commands are disabled without it
+	if controlTopic := initApp.Config.PubSub.ControlTopic; controlTopic != "" {
+		controller := cron_jobs.NewController(initApp.Cron, initApp.Subscriberer, initApp.Publisherer, controlTopic,
+			cron_jobs.WithAckTopic(initApp.Config.PubSub.AckTopic),
+			// cancel the main context, cron is shutdown gracefully by Run below
+			cron_jobs.WithOnStop(cancel),
+		)
 		go func() {
-			select {
-			case <-isStop:
-				if err := initApp.Subscriberer.Subscribe(ctx, fnStop); err != nil {
-					log.Fatalf("error subscribe: %v\n", err)
-				}
+			if err := controller.Run(ctx); err != nil {
+				log.Printf("error subscribe: %v\n", err)
 			}
 		}()
+	} else {
+		log.Println("pubsub_control_topic is not set, control commands are disabled")
 	}
 
-	if err := initApp.Cron.AddJobWithInterval(storage.Test10Seconds, ResignedURLFunc); err != nil {
-		log.Fatalf("error add job: %v\n", err)
-		panic(err)
+	// jobs are declared in config.yaml, see jobs section
+	// block until ctx is cancelled, then wait for running jobs
+	if err := initApp.Cron.Run(ctx); err != nil {
+		log.Printf("error stop cron: %v\n", err)
 	}
 
-	time.Sleep(storage.Test10Seconds)
-	initApp.Cron.StartWithBlocking()
+	// wait for the admin api to finish in flight requests, then flush telemetry
+	initApp.Wait()
 
-	// defer initApp.Cron.Stop()
+	log.Println("app stopped")
 }